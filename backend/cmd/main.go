@@ -1,14 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"freelance-platform/internal/audit"
+	"freelance-platform/internal/auth"
 	"freelance-platform/internal/database"
 	"freelance-platform/internal/handlers"
-	"freelance-platform/internal/middleware"
+	"freelance-platform/internal/mailer"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/internal/payments"
+	"freelance-platform/internal/presence"
+	"freelance-platform/internal/realtime"
+	"freelance-platform/internal/server"
+	"freelance-platform/internal/storage"
 
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
@@ -22,56 +35,43 @@ func main() {
 	database.Connect()
 	database.Migrate()
 
-	// Setup Gin router
-	r := gin.Default()
-
-	// Add middleware
-	r.Use(middleware.CORS())
-	r.Use(middleware.Logger())
-
-	// Setup routes
-	api := r.Group("/api")
-	{
-		auth := api.Group("/auth")
-		{
-			auth.POST("/register", handlers.Register)
-			auth.POST("/login", handlers.Login)
-			auth.POST("/logout", handlers.Logout)
-			auth.GET("/me", middleware.RequireAuth(), handlers.GetCurrentUser)
-			auth.PUT("/profile", middleware.RequireAuth(), handlers.UpdateProfile)
-		}
+	// Write audit records in the background
+	audit.Connect(database.DB)
 
-		projects := api.Group("/projects")
-		{
-			projects.GET("", handlers.GetProjects)
-			projects.POST("", middleware.RequireAuth(), handlers.CreateProject)
-			projects.GET("/:id", handlers.GetProject)
-			projects.PUT("/:id", middleware.RequireAuth(), handlers.UpdateProject)
-			projects.PUT("/:id/status", middleware.RequireAuth(), handlers.UpdateProjectStatus)
-			projects.DELETE("/:id", middleware.RequireAuth(), handlers.DeleteProject)
-			projects.GET("/:id/bids", middleware.RequireAuth(), handlers.GetProjectBids)
-		}
+	// Initialize session revocation
+	auth.Connect()
 
-		bids := api.Group("/bids")
-		{
-			bids.POST("", middleware.RequireAuth(), handlers.CreateBid)
-		}
+	// Initialize outgoing email
+	mailer.Connect()
 
-		chats := api.Group("/chats")
-		{
-			chats.GET("", middleware.RequireAuth(), handlers.GetChats)
-			chats.POST("", middleware.RequireAuth(), handlers.CreateChat)
-			chats.GET("/:id/messages", middleware.RequireAuth(), handlers.GetChatMessages)
-			chats.PUT("/:id/read", middleware.RequireAuth(), handlers.MarkMessagesAsRead)
-			chats.DELETE("/:id", middleware.RequireAuth(), handlers.DeleteChat)
-		}
+	// Initialize payment provider
+	payments.Connect()
 
-		messages := api.Group("/messages")
-		{
-			messages.POST("", middleware.RequireAuth(), handlers.SendMessage)
-			messages.GET("/unread-count", middleware.RequireAuth(), handlers.GetUnreadCount)
-		}
-	}
+	// Initialize file storage
+	storage.Connect()
+
+	// Initialize realtime hub and presence tracking
+	presence.Connect()
+	realtime.Connect()
+	realtime.DefaultHub.SetHooks(handlers.RealtimeHooks())
+
+	// Wire repositories and services
+	svc := server.NewServices(database.DB, realtime.Publish)
+
+	// Events recorded by a request are published to the services right after it commits
+	outbox.Connect(database.DB, svc.Events)
+
+	// Background work (emails, webhooks, bid expiry, review publishing) runs in cmd/worker
+
+	// Build the handlers with their dependencies and register the routes
+	r := server.NewRouter(server.Handlers{
+		Projects:      handlers.NewProjectHandler(svc.Projects),
+		Chats:         handlers.NewChatHandler(svc.Chats, presence.DefaultStore),
+		Notifications: handlers.NewNotificationHandler(svc.Notifications),
+		Webhooks:      handlers.NewWebhookHandler(svc.Webhooks),
+		Contracts:     handlers.NewContractHandler(database.DB),
+		Payments:      handlers.NewPaymentHandler(database.DB),
+	})
 
 	// Start server
 	port := os.Getenv("API_PORT")
@@ -79,6 +79,32 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	r.Run(":" + port)
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	// Stop taking requests on shutdown and let the ones in flight finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown: %v", err)
+	}
+
+	// Write the audit records the last requests queued
+	audit.DefaultLogger.Close()
+	log.Println("Server stopped")
 } 
\ No newline at end of file
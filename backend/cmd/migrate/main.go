@@ -1,46 +1,108 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	"freelance-platform/internal/database"
-	"freelance-platform/internal/models"
+	"freelance-platform/internal/migrate"
 
 	"github.com/joho/godotenv"
 )
 
+const usage = `Usage: migrate [-dir migrations] <command>
+
+Commands:
+  up            apply all pending migrations
+  down N        revert the N most recent migrations
+  status        list migrations and whether they are applied
+  create NAME   write empty NNNN_NAME.up.sql / .down.sql files into -dir
+`
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
 	}
 
-	// Parse command line flags
-	reset := flag.Bool("reset", false, "Reset database (drop all tables)")
+	dir := flag.String("dir", "migrations", "Directory new migrations are created in")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
 	flag.Parse()
 
-	// Connect to database
-	database.Connect()
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
 
-	if *reset {
-		log.Println("Resetting database...")
-		
-		// Drop all tables
-		database.DB.Migrator().DropTable(
-			&models.Message{},
-			&models.Chat{},
-			&models.Bid{},
-			&models.Project{},
-			&models.User{},
-		)
-		
-		log.Println("All tables dropped successfully")
+	// create only touches files, no database needed
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		up, down, err := migrate.Create(*dir, args[1])
+		if err != nil {
+			log.Fatal("Failed to create migration:", err)
+		}
+		log.Printf("Created %s and %s", up, down)
+		return
 	}
 
-	// Run migrations
-	log.Println("Running database migrations...")
-	database.Migrate()
-	
-	log.Println("Migration completed successfully!")
-} 
\ No newline at end of file
+	database.Connect()
+	m, err := database.Migrator()
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+		log.Printf("Applied %d migration(s)", n)
+
+	case "down":
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		steps, err := strconv.Atoi(args[1])
+		if err != nil || steps < 1 {
+			log.Fatal("down expects a positive number of migrations")
+		}
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			log.Fatal("Rollback failed:", err)
+		}
+		log.Printf("Reverted %d migration(s)", n)
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			log.Fatal("Failed to read migration status:", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Missing:
+				state = "applied, file missing"
+			case s.Modified:
+				state = "applied, MODIFIED since"
+			case s.Applied:
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/ledger"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	// Connect to database
+	database.Connect()
+
+	log.Println("Reconciling ledger...")
+
+	issues, err := ledger.Reconcile(database.DB)
+	if err != nil {
+		log.Fatal("Failed to reconcile ledger:", err)
+	}
+
+	if len(issues) > 0 {
+		for _, issue := range issues {
+			log.Println(issue)
+		}
+		log.Printf("Ledger reconciliation found %d issue(s)", len(issues))
+		os.Exit(1)
+	}
+
+	log.Println("Ledger is balanced")
+}
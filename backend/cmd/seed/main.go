@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"freelance-platform/internal/database"
 	"freelance-platform/internal/models"
@@ -32,29 +33,35 @@ func createSampleUsers() {
 	// Hash password for sample users
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 
+	// Sample accounts skip email verification
+	verifiedAt := time.Now()
+
 	users := []models.User{
 		{
-			Email:    "client@example.com",
-			Password: string(hashedPassword),
-			Name:     "John Client",
-			Role:     "client",
-			Bio:      "I'm a business owner looking for talented freelancers.",
+			Email:           "client@example.com",
+			Password:        string(hashedPassword),
+			Name:            "John Client",
+			EmailVerifiedAt: &verifiedAt,
+			Role:            "client",
+			Bio:             "I'm a business owner looking for talented freelancers.",
 		},
 		{
-			Email:    "freelancer@example.com",
-			Password: string(hashedPassword),
-			Name:     "Jane Freelancer",
-			Role:     "freelancer",
-			Bio:      "Full-stack developer with 5 years of experience.",
-			Skills:   `["React", "Node.js", "PostgreSQL", "TypeScript"]`,
-			Rating:   4.8,
+			Email:           "freelancer@example.com",
+			Password:        string(hashedPassword),
+			Name:            "Jane Freelancer",
+			EmailVerifiedAt: &verifiedAt,
+			Role:            "freelancer",
+			Bio:             "Full-stack developer with 5 years of experience.",
+			Skills:          `["React", "Node.js", "PostgreSQL", "TypeScript"]`,
+			Rating:          4.8,
 		},
 		{
-			Email:    "admin@example.com",
-			Password: string(hashedPassword),
-			Name:     "Admin User",
-			Role:     "admin",
-			Bio:      "Platform administrator.",
+			Email:           "admin@example.com",
+			Password:        string(hashedPassword),
+			Name:            "Admin User",
+			EmailVerifiedAt: &verifiedAt,
+			Role:            "admin",
+			Bio:             "Platform administrator.",
 		},
 	}
 
@@ -85,9 +92,10 @@ func createSampleProjects() {
 		{
 			Title:       "E-commerce Website Development",
 			Description: "Need a modern e-commerce website built with React and Node.js. Should include user authentication, product catalog, shopping cart, and payment integration.",
-			Budget:      2500.00,
-			Currency:    "USD",
+			BudgetMin:   60000,
+			BudgetMax:   80000,
 			Category:    "Web Development",
+			Location:    "Remote",
 			Skills:      `["React", "Node.js", "PostgreSQL", "Stripe"]`,
 			ClientID:    client.ID,
 			Status:      "open",
@@ -95,9 +103,10 @@ func createSampleProjects() {
 		{
 			Title:       "Mobile App UI/UX Design",
 			Description: "Looking for a talented designer to create modern and intuitive UI/UX for our mobile application. Need wireframes, mockups, and prototypes.",
-			Budget:      1500.00,
-			Currency:    "USD",
+			BudgetMin:   30000,
+			BudgetMax:   50000,
 			Category:    "Design",
+			Location:    "台北市",
 			Skills:      `["Figma", "UI/UX Design", "Mobile Design", "Prototyping"]`,
 			ClientID:    client.ID,
 			Status:      "open",
@@ -105,9 +114,10 @@ func createSampleProjects() {
 		{
 			Title:       "API Development and Documentation",
 			Description: "Need to develop RESTful APIs for our platform and create comprehensive documentation. Should include authentication, rate limiting, and proper error handling.",
-			Budget:      3000.00,
-			Currency:    "USD",
+			BudgetMin:   80000,
+			BudgetMax:   100000,
 			Category:    "Backend Development",
+			Location:    "Remote",
 			Skills:      `["Go", "REST API", "PostgreSQL", "Docker"]`,
 			ClientID:    client.ID,
 			Status:      "open",
@@ -127,4 +137,4 @@ func createSampleProjects() {
 			log.Printf("Project already exists: %s", project.Title)
 		}
 	}
-} 
\ No newline at end of file
+}
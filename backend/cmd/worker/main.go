@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"freelance-platform/internal/audit"
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/mailer"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/internal/payments"
+	"freelance-platform/internal/realtime"
+	"freelance-platform/internal/server"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	// Connect to database; the API applies migrations
+	database.Connect()
+
+	// Write audit records in the background
+	audit.Connect(database.DB)
+
+	// Initialize outgoing email
+	mailer.Connect()
+
+	// Refunds and payouts are sent to the payment provider from here
+	payments.Connect()
+
+	// Realtime pushes reach the API's connections through the shared broker
+	realtime.Connect()
+
+	// Wire repositories and services
+	svc := server.NewServices(database.DB, realtime.Publish)
+
+	// The worker relays whatever events the API left in the outbox
+	outbox.Connect(database.DB, svc.Events)
+
+	worker := server.NewWorker(database.DB, svc, outbox.DefaultRelay)
+	if value := os.Getenv("WORKER_CONCURRENCY"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			worker.Concurrency = n
+		}
+	}
+
+	// Finish the jobs in hand on shutdown; anything left is picked up by the next worker
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	worker.Run(ctx)
+
+	// Write the audit records the last jobs queued
+	audit.DefaultLogger.Close()
+	log.Println("Worker stopped")
+}
@@ -0,0 +1,25 @@
+// Package appenv tells which environment the binaries run in, from APP_ENV:
+// production, development (the default) or test. Settings that are only safe
+// for local work check it and refuse to start in production.
+package appenv
+
+import "os"
+
+const (
+	Production  = "production"
+	Development = "development"
+	Test        = "test"
+)
+
+// Name returns APP_ENV, or Development when it is unset
+func Name() string {
+	if env := os.Getenv("APP_ENV"); env != "" {
+		return env
+	}
+	return Development
+}
+
+// IsProduction reports whether APP_ENV is production
+func IsProduction() bool {
+	return Name() == Production
+}
@@ -0,0 +1,208 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Entry describes one audited change. Before and After are usually the same
+// model loaded before and after the change; only differing top-level fields are kept.
+type Entry struct {
+	ActorID    *uint
+	Action     string
+	TargetType string
+	TargetID   uint
+	Before     interface{}
+	After      interface{}
+	IP         string
+	UserAgent  string
+}
+
+// Logger writes audit records in the background through a bounded buffer.
+// When the buffer is full, Record writes synchronously instead of dropping the
+// record, so a burst slows requests down rather than losing history.
+type Logger struct {
+	db      *gorm.DB
+	entries chan models.AuditLog
+	pending atomic.Int64
+	done    chan struct{}
+	once    sync.Once
+}
+
+var DefaultLogger *Logger
+
+// Connect starts the default logger. AUDIT_BUFFER_SIZE bounds the number of pending records.
+func Connect(db *gorm.DB) {
+	size := 1024
+	if value := os.Getenv("AUDIT_BUFFER_SIZE"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			size = n
+		}
+	}
+
+	DefaultLogger = NewLogger(db, size)
+	log.Println("Audit logger ready")
+}
+
+func NewLogger(db *gorm.DB, size int) *Logger {
+	l := &Logger{
+		db:      db,
+		entries: make(chan models.AuditLog, size),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Record queues an audit entry. Entries whose Before and After are identical are skipped.
+func Record(entry Entry) {
+	if DefaultLogger == nil {
+		return
+	}
+	DefaultLogger.Record(entry)
+}
+
+func (l *Logger) Record(entry Entry) {
+	changes, changed := Diff(entry.Before, entry.After)
+	if !changed {
+		return
+	}
+
+	record := models.AuditLog{
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Changes:    changes,
+		IP:         entry.IP,
+		UserAgent:  entry.UserAgent,
+		CreatedAt:  time.Now(),
+	}
+
+	l.pending.Add(1)
+	select {
+	case l.entries <- record:
+	default:
+		l.write([]models.AuditLog{record})
+	}
+}
+
+// Close stops accepting records and waits until everything queued is written
+func (l *Logger) Close() {
+	l.once.Do(func() { close(l.entries) })
+	<-l.done
+}
+
+// Flush waits until every record queued so far has been written
+func (l *Logger) Flush() {
+	for l.pending.Load() > 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+
+	batch := make([]models.AuditLog, 0, 100)
+	for record := range l.entries {
+		batch = append(batch, record)
+
+		// Drain whatever else is already waiting into the same insert
+	drain:
+		for len(batch) < cap(batch) {
+			select {
+			case next, ok := <-l.entries:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+
+		l.write(batch)
+		batch = batch[:0]
+	}
+}
+
+func (l *Logger) write(records []models.AuditLog) {
+	defer l.pending.Add(-int64(len(records)))
+
+	if err := l.db.Create(&records).Error; err != nil {
+		for _, record := range records {
+			log.Printf("Failed to write audit record %s %s:%d: %v", record.Action, record.TargetType, record.TargetID, err)
+		}
+	}
+}
+
+// Diff compares the top-level JSON fields of before and after and returns the
+// changed ones as {"field": {"from": x, "to": y}}. Nested objects and arrays
+// (preloaded associations) and updated_at are ignored. A nil before or after
+// records every field as created or removed.
+func Diff(before, after interface{}) (string, bool) {
+	beforeFields := flatten(before)
+	afterFields := flatten(after)
+
+	changes := make(map[string]map[string]interface{})
+	for key, to := range afterFields {
+		from, ok := beforeFields[key]
+		if !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = map[string]interface{}{"from": from, "to": to}
+		}
+	}
+	for key, from := range beforeFields {
+		if _, ok := afterFields[key]; !ok {
+			changes[key] = map[string]interface{}{"from": from, "to": nil}
+		}
+	}
+
+	if len(changes) == 0 {
+		return "{}", false
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return "{}", false
+	}
+	return string(data), true
+}
+
+func flatten(value interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if value == nil {
+		return fields
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fields
+	}
+
+	for key, v := range raw {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		}
+		if key == "updated_at" {
+			continue
+		}
+		fields[key] = v
+	}
+	return fields
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"freelance-platform/internal/database"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Denylist remembers revoked session and token IDs until the access tokens
+// carrying them would have expired anyway
+type Denylist interface {
+	Add(ctx context.Context, id string, ttl time.Duration) error
+	// Contains reports whether any of the IDs has been revoked
+	Contains(ctx context.Context, ids ...string) (bool, error)
+}
+
+var DefaultDenylist Denylist
+
+// Connect selects the denylist through TOKEN_DENYLIST (memory or redis)
+func Connect() {
+	switch os.Getenv("TOKEN_DENYLIST") {
+	case "redis":
+		DefaultDenylist = NewRedisDenylist(database.ConnectRedis())
+	default:
+		DefaultDenylist = NewMemoryDenylist()
+	}
+
+	log.Println("Token denylist ready")
+}
+
+// Revoke denylists the IDs for the lifetime of an access token
+func Revoke(ctx context.Context, ids ...string) {
+	if DefaultDenylist == nil {
+		return
+	}
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if err := DefaultDenylist.Add(ctx, id, AccessTTL()); err != nil {
+			log.Printf("Failed to denylist %s: %v", id, err)
+		}
+	}
+}
+
+// MemoryDenylist is a process-local denylist for tests and single-instance setups
+type MemoryDenylist struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{entries: make(map[string]time.Time)}
+}
+
+func (d *MemoryDenylist) Add(ctx context.Context, id string, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries[id] = time.Now().Add(ttl)
+	return nil
+}
+
+func (d *MemoryDenylist) Contains(ctx context.Context, ids ...string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for _, id := range ids {
+		expiresAt, ok := d.entries[id]
+		if !ok {
+			continue
+		}
+		if expiresAt.After(now) {
+			return true, nil
+		}
+		delete(d.entries, id)
+	}
+	return false, nil
+}
+
+// RedisDenylist shares revocations between API instances
+type RedisDenylist struct {
+	client *redis.Client
+}
+
+func NewRedisDenylist(client *redis.Client) *RedisDenylist {
+	return &RedisDenylist{client: client}
+}
+
+func denylistKey(id string) string {
+	return "auth:denylist:" + id
+}
+
+func (d *RedisDenylist) Add(ctx context.Context, id string, ttl time.Duration) error {
+	return d.client.Set(ctx, denylistKey(id), 1, ttl).Err()
+}
+
+func (d *RedisDenylist) Contains(ctx context.Context, ids ...string) (bool, error) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != "" {
+			keys = append(keys, denylistKey(id))
+		}
+	}
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	count, err := d.client.Exists(ctx, keys...).Result()
+	return count > 0, err
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+var ErrInvalidOneTimeToken = errors.New("invalid or expired token")
+
+// IssueOneTimeToken creates a signed token for a single use of purpose by the
+// user. Earlier unused tokens for the same purpose stop working.
+func IssueOneTimeToken(db *gorm.DB, userID uint, purpose string, ttl time.Duration) (string, error) {
+	nonce := make([]byte, 24)
+	rand.Read(nonce)
+
+	expiresAt := time.Now().Add(ttl)
+	payload := fmt.Sprintf("%s:%d:%d:%s", purpose, userID, expiresAt.Unix(), base64.RawURLEncoding.EncodeToString(nonce))
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + sign(payload)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: HashToken(token),
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ConsumeOneTimeToken checks the signature and expiry of a token issued for
+// purpose and marks it used, returning the user it was issued to. Run it inside
+// the transaction that performs the action so a failed action leaves the token usable.
+func ConsumeOneTimeToken(tx *gorm.DB, token, purpose string) (uint, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, ErrInvalidOneTimeToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, ErrInvalidOneTimeToken
+	}
+	payload := string(raw)
+	if !hmac.Equal([]byte(signature), []byte(sign(payload))) {
+		return 0, ErrInvalidOneTimeToken
+	}
+
+	parts := strings.Split(payload, ":")
+	if len(parts) != 4 || parts[0] != purpose {
+		return 0, ErrInvalidOneTimeToken
+	}
+	userID, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, ErrInvalidOneTimeToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return 0, ErrInvalidOneTimeToken
+	}
+
+	// Claim the token; only one caller can flip used_at
+	result := tx.Model(&models.UserToken{}).
+		Where("token_hash = ? AND user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", HashToken(token), userID, purpose, time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrInvalidOneTimeToken
+	}
+
+	return uint(userID), nil
+}
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, JWTSecret())
+	mac.Write([]byte("one-time:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
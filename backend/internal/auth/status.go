@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"freelance-platform/internal/models"
+)
+
+var (
+	ErrAccountSuspended = errors.New("Account is suspended")
+	ErrAccountBanned    = errors.New("Account is banned")
+)
+
+// CheckAccountStatus reports whether a moderated account may sign in or use
+// the API. Suspensions with an end date lapse on their own.
+func CheckAccountStatus(user models.User) error {
+	switch user.Status {
+	case "banned":
+		return ErrAccountBanned
+	case "suspended":
+		if user.SuspendedUntil == nil || time.Now().Before(*user.SuspendedUntil) {
+			return ErrAccountSuspended
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	tokenTypeAccess     = "access"
+	tokenTypeMFAPending = "mfa_pending"
+)
+
+// MFATokenTTL is how long a user has to enter their second factor after the password
+const MFATokenTTL = 5 * time.Minute
+
+// JWTSecret returns the HMAC key used to sign access tokens
+func JWTSecret() []byte {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "your_jwt_secret_key"
+	}
+	return []byte(secret)
+}
+
+// AccessTTL is the lifetime of access tokens, configured via JWT_ACCESS_TTL_MINUTES
+func AccessTTL() time.Duration {
+	minutes := 15
+	if value := os.Getenv("JWT_ACCESS_TTL_MINUTES"); value != "" {
+		if m, err := strconv.Atoi(value); err == nil && m > 0 {
+			minutes = m
+		}
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// RefreshTTL is the lifetime of refresh tokens, configured via JWT_REFRESH_TTL_DAYS
+func RefreshTTL() time.Duration {
+	days := 30
+	if value := os.Getenv("JWT_REFRESH_TTL_DAYS"); value != "" {
+		if d, err := strconv.Atoi(value); err == nil && d > 0 {
+			days = d
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// IssueAccessToken signs a short-lived access token for a session. Every token
+// gets its own jti; sid ties it to the refresh token family it came from.
+func IssueAccessToken(userID uint, sessionID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(AccessTTL())
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":     tokenTypeAccess,
+		"user_id": userID,
+		"sid":     sessionID,
+		"jti":     RandomID(),
+		"exp":     expiresAt.Unix(),
+	})
+
+	signed, err := token.SignedString(JWTSecret())
+	return signed, expiresAt, err
+}
+
+// NewRefreshToken returns a random refresh token and the hash to store for it
+func NewRefreshToken() (token string, hash string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token)
+}
+
+// HashToken hashes an opaque token for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomID returns a random 128-bit identifier in hex
+func RandomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessClaims is the subset of access token claims the API relies on
+type AccessClaims struct {
+	UserID    uint
+	SessionID string
+	TokenID   string
+}
+
+// ParseAccessToken verifies an access token's signature and expiry and extracts its claims
+func ParseAccessToken(tokenString string) (AccessClaims, bool) {
+	return parseToken(tokenString, tokenTypeAccess)
+}
+
+// IssueMFAToken signs the short-lived token a password login yields when the
+// account has two-factor authentication enabled. It is not an access token.
+func IssueMFAToken(userID uint) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":     tokenTypeMFAPending,
+		"user_id": userID,
+		"jti":     RandomID(),
+		"exp":     time.Now().Add(MFATokenTTL).Unix(),
+	})
+
+	return token.SignedString(JWTSecret())
+}
+
+// ParseMFAToken verifies a token issued by IssueMFAToken
+func ParseMFAToken(tokenString string) (AccessClaims, bool) {
+	return parseToken(tokenString, tokenTypeMFAPending)
+}
+
+func parseToken(tokenString, tokenType string) (AccessClaims, bool) {
+	var claims AccessClaims
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return JWTSecret(), nil
+	})
+	if err != nil || !token.Valid {
+		return claims, false
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return claims, false
+	}
+
+	if typ, _ := mapClaims["typ"].(string); typ != tokenType {
+		return claims, false
+	}
+
+	userID, _ := mapClaims["user_id"].(float64)
+	claims.UserID = uint(userID)
+	claims.SessionID, _ = mapClaims["sid"].(string)
+	claims.TokenID, _ = mapClaims["jti"].(string)
+	return claims, true
+}
@@ -1,11 +1,13 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
-	"freelance-platform/internal/models"
+	"freelance-platform/internal/migrate"
+	sqlmigrations "freelance-platform/migrations"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -48,17 +50,33 @@ func Connect() {
 	log.Println("Database connected successfully")
 }
 
+// Migrate applies pending SQL migrations from the migrations directory
 func Migrate() {
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.Project{},
-		&models.Bid{},
-		&models.Chat{},
-		&models.Message{},
-	)
+	m, err := Migrator()
 	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	if _, err := m.Up(context.Background()); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
 	log.Println("Database migration completed")
-} 
\ No newline at end of file
+}
+
+// Migrator returns a migrator over the embedded migrations for DB
+func Migrator() (*migrate.Migrator, error) {
+	migrations, err := migrate.Load(sqlmigrations.FS)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	m := migrate.New(sqlDB, migrations)
+	m.Log = log.Printf
+	return m, nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var Redis *redis.Client
+
+// ConnectRedis connects to the Redis instance configured through REDIS_* and returns the shared client
+func ConnectRedis() *redis.Client {
+	if Redis != nil {
+		return Redis
+	}
+
+	host := os.Getenv("REDIS_HOST")
+	port := os.Getenv("REDIS_PORT")
+	password := os.Getenv("REDIS_PASSWORD")
+
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "6379"
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", host, port),
+		Password: password,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Fatal("Failed to connect to redis:", err)
+	}
+
+	log.Println("Redis connected successfully")
+
+	Redis = client
+	return Redis
+}
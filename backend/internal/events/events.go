@@ -0,0 +1,144 @@
+// Package events is an in-process bus for domain events. Handlers record an
+// event in the outbox with the change it describes, and the outbox relay
+// publishes it here once committed; subscribers such as the notification
+// center react to it without the handlers knowing about them.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	BidCreated           = "bid.created"
+	BidAccepted          = "bid.accepted"
+	ProjectStatusChanged = "project.status_changed"
+	MessageReceived      = "message.received"
+)
+
+// Event is something that happened; Data holds the payload struct for its Type
+type Event struct {
+	// ID is assigned when the event is recorded and kept across redeliveries
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	ActorID    uint        `json:"actor_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// BidData is the payload of BidCreated and BidAccepted
+type BidData struct {
+	BidID          uint   `json:"bid_id"`
+	ProjectID      uint   `json:"project_id"`
+	ProjectTitle   string `json:"project_title"`
+	ClientID       uint   `json:"client_id"`
+	FreelancerID   uint   `json:"freelancer_id"`
+	FreelancerName string `json:"freelancer_name"`
+	Amount         int    `json:"amount"`
+	// RejectedFreelancerIDs lists the bidders turned down when a bid is accepted
+	RejectedFreelancerIDs []uint `json:"rejected_freelancer_ids,omitempty"`
+}
+
+// ProjectStatusData is the payload of ProjectStatusChanged
+type ProjectStatusData struct {
+	ProjectID    uint   `json:"project_id"`
+	ProjectTitle string `json:"project_title"`
+	ClientID     uint   `json:"client_id"`
+	FreelancerID *uint  `json:"freelancer_id"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+}
+
+// MessageData is the payload of MessageReceived
+type MessageData struct {
+	MessageID   uint   `json:"message_id"`
+	ChatID      uint   `json:"chat_id"`
+	ProjectID   uint   `json:"project_id"`
+	SenderID    uint   `json:"sender_id"`
+	SenderName  string `json:"sender_name"`
+	RecipientID uint   `json:"recipient_id"`
+	Type        string `json:"type"` // text, image or file
+	Preview     string `json:"preview"`
+}
+
+// decoders turn the JSON payload of each event type back into its Data struct
+var decoders = map[string]func(raw []byte) (interface{}, error){
+	BidCreated:           decode[BidData],
+	BidAccepted:          decode[BidData],
+	ProjectStatusChanged: decode[ProjectStatusData],
+	MessageReceived:      decode[MessageData],
+}
+
+func decode[T any](raw []byte) (interface{}, error) {
+	var data T
+	err := json.Unmarshal(raw, &data)
+	return data, err
+}
+
+// DecodeData unmarshals the JSON payload of an event of the given type into its Data struct
+func DecodeData(eventType string, raw []byte) (interface{}, error) {
+	decoder, ok := decoders[eventType]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+	return decoder(raw)
+}
+
+// Handler reacts to an event; it runs on the publisher's goroutine. An error
+// makes the relay publish the event again later, to every subscriber, so
+// handlers skip the events they have already processed.
+type Handler func(ctx context.Context, event Event) error
+
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers handler for the given event types
+func (b *Bus) Subscribe(handler Handler, eventTypes ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, eventType := range eventTypes {
+		b.handlers[eventType] = append(b.handlers[eventType], handler)
+	}
+}
+
+// Publish hands the event to every subscriber in turn and returns their
+// errors joined. A panicking subscriber counts as failed, so it can't take the
+// publisher down with it.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers[event.Type]
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := safeHandle(ctx, handler, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func safeHandle(ctx context.Context, handler Handler, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("event handler for %s panicked: %v", event.Type, r)
+		}
+	}()
+	return handler(ctx, event)
+}
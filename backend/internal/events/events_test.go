@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPublishReportsFailingSubscribers(t *testing.T) {
+	bus := NewBus()
+	refused := errors.New("database unavailable")
+
+	var handled []string
+	bus.Subscribe(func(ctx context.Context, event Event) error {
+		handled = append(handled, "failing")
+		return refused
+	}, BidCreated)
+	bus.Subscribe(func(ctx context.Context, event Event) error {
+		handled = append(handled, "panicking")
+		panic("boom")
+	}, BidCreated)
+	bus.Subscribe(func(ctx context.Context, event Event) error {
+		handled = append(handled, "working")
+		return nil
+	}, BidCreated)
+
+	err := bus.Publish(context.Background(), Event{Type: BidCreated})
+	if !errors.Is(err, refused) || !strings.Contains(err.Error(), "panicked: boom") {
+		t.Errorf("expected both failures reported, got %v", err)
+	}
+	if strings.Join(handled, ",") != "failing,panicking,working" {
+		t.Errorf("expected every subscriber to run, got %v", handled)
+	}
+
+	if err := bus.Publish(context.Background(), Event{Type: MessageReceived}); err != nil {
+		t.Errorf("expected an event nobody handles to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,517 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errAdminTargetNotFound = errors.New("target not found")
+	errAdminInvalidState   = errors.New("invalid state for this action")
+	errAdminSelfAction     = errors.New("admins cannot moderate themselves")
+)
+
+type ModerationRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type SuspendUserRequest struct {
+	Reason string     `json:"reason" binding:"required"`
+	Until  *time.Time `json:"until"` // omit for an indefinite suspension
+}
+
+type ChangeRoleRequest struct {
+	Role   string `json:"role" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+type RestoreProjectRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	Status string `json:"status"` // defaults to open, or in_progress when a freelancer is assigned
+}
+
+// AdminListUsers lists users with optional search, role and status filters
+func AdminListUsers(c *gin.Context) {
+	query := database.DB.Model(&models.User{})
+
+	if search := c.Query("search"); search != "" {
+		searchTerm := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(email) LIKE ? OR LOWER(name) LIKE ?", searchTerm, searchTerm)
+	}
+
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	var users []models.User
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users, "total": total})
+}
+
+func AdminGetUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var actions []models.AdminAction
+	database.DB.Where("target_type = ? AND target_id = ?", "user", user.ID).
+		Order("created_at DESC").Limit(50).Find(&actions)
+
+	c.JSON(http.StatusOK, gin.H{"user": user, "moderation_history": actions})
+}
+
+func AdminSuspendUser(c *gin.Context) {
+	var req SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Suspension end must be in the future"})
+		return
+	}
+
+	moderateUser(c, "user.suspend", req.Reason, map[string]interface{}{
+		"status":            "suspended",
+		"suspended_until":   req.Until,
+		"moderation_reason": req.Reason,
+	})
+}
+
+func AdminBanUser(c *gin.Context) {
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	moderateUser(c, "user.ban", req.Reason, map[string]interface{}{
+		"status":            "banned",
+		"suspended_until":   nil,
+		"moderation_reason": req.Reason,
+	})
+}
+
+func AdminReinstateUser(c *gin.Context) {
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	moderateUser(c, "user.reinstate", req.Reason, map[string]interface{}{
+		"status":            "active",
+		"suspended_until":   nil,
+		"moderation_reason": "",
+	})
+}
+
+func AdminChangeUserRole(c *gin.Context) {
+	var req ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Role != "freelancer" && req.Role != "client" && req.Role != "admin" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Must be 'freelancer', 'client' or 'admin'"})
+		return
+	}
+
+	reason := "role set to " + req.Role
+	if req.Reason != "" {
+		reason += ": " + req.Reason
+	}
+
+	moderateUser(c, "user.role", reason, map[string]interface{}{
+		"role": req.Role,
+	})
+}
+
+// moderateUser applies updates to the user in the :id route parameter and logs the action.
+// Suspending and banning also sign the user out everywhere.
+func moderateUser(c *gin.Context, action, reason string, updates map[string]interface{}) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	admin := c.MustGet("user").(models.User)
+
+	var target, before models.User
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if uint(id) == admin.ID {
+			return errAdminSelfAction
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&target, id).Error; err != nil {
+			return errAdminTargetNotFound
+		}
+		before = target
+
+		if err := tx.Model(&target).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return logAdminAction(tx, admin.ID, action, "user", target.ID, reason)
+	})
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	database.DB.First(&target, target.ID)
+	recordAudit(c, action, "user", target.ID, before, target)
+
+	if action == "user.suspend" || action == "user.ban" {
+		if _, err := revokeAllSessions(target.ID); err != nil {
+			// The new status already blocks every request; repeating the action retries the sign-out
+			log.Printf("Failed to revoke sessions of user %d after %s: %v", target.ID, action, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Account updated but its sessions could not be revoked, please retry"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": target})
+}
+
+// AdminCloseProject force-cancels a project, cancelling its contract and refunding escrow
+func AdminCloseProject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin := c.MustGet("user").(models.User)
+
+	var project, before models.Project
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, id).Error; err != nil {
+			return errAdminTargetNotFound
+		}
+		before = project
+		if project.Status == "cancelled" || project.Status == "completed" {
+			return errAdminInvalidState
+		}
+
+		if err := ApplyProjectStatus(tx, &project, "cancelled"); err != nil {
+			return err
+		}
+
+		var chats []models.Chat
+		if err := tx.Where("project_id = ?", project.ID).Find(&chats).Error; err != nil {
+			return err
+		}
+		for _, chat := range chats {
+			if err := createSystemMessage(tx, chat, project.ClientID, "此案件已被平台管理員關閉。"); err != nil {
+				return err
+			}
+		}
+
+		if err := recordStatusChange(c, tx, before, project); err != nil {
+			return err
+		}
+		return logAdminAction(tx, admin.ID, "project.close", "project", project.ID, req.Reason)
+	})
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	outbox.Flush(c.Request.Context())
+
+	database.DB.First(&project, project.ID)
+	recordAudit(c, "project.close", "project", project.ID, before, project)
+
+	c.JSON(http.StatusOK, gin.H{"project": project})
+}
+
+// AdminRestoreProject undoes a client's DeleteProject
+func AdminRestoreProject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req RestoreProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Status != "" && !services.IsValidProjectStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid statuses are: " + strings.Join(services.ValidProjectStatuses, ", ")})
+		return
+	}
+
+	admin := c.MustGet("user").(models.User)
+
+	var project, before models.Project
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, id).Error; err != nil {
+			return errAdminTargetNotFound
+		}
+		before = project
+		if project.Status != "deleted" {
+			return errAdminInvalidState
+		}
+
+		status := req.Status
+		if status == "" {
+			status = "open"
+			if project.FreelancerID != nil {
+				status = "in_progress"
+			}
+		}
+
+		if err := tx.Model(&project).Update("status", status).Error; err != nil {
+			return err
+		}
+
+		restored := before
+		restored.Status = status
+		if err := recordStatusChange(c, tx, before, restored); err != nil {
+			return err
+		}
+		return logAdminAction(tx, admin.ID, "project.restore", "project", project.ID, req.Reason)
+	})
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	outbox.Flush(c.Request.Context())
+
+	database.DB.First(&project, project.ID)
+	recordAudit(c, "project.restore", "project", project.ID, before, project)
+
+	c.JSON(http.StatusOK, gin.H{"project": project})
+}
+
+func AdminHideMessage(c *gin.Context) {
+	setMessageHidden(c, true)
+}
+
+func AdminUnhideMessage(c *gin.Context) {
+	setMessageHidden(c, false)
+}
+
+func setMessageHidden(c *gin.Context, hidden bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin := c.MustGet("user").(models.User)
+
+	var message, before models.Message
+	action := "message.unhide"
+	if hidden {
+		action = "message.hide"
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&message, id).Error; err != nil {
+			return errAdminTargetNotFound
+		}
+		before = message
+		if (message.HiddenAt != nil) == hidden {
+			return errAdminInvalidState
+		}
+
+		updates := map[string]interface{}{"hidden_at": nil, "hidden_by_id": nil}
+		if hidden {
+			updates = map[string]interface{}{"hidden_at": time.Now(), "hidden_by_id": admin.ID}
+		}
+
+		if err := tx.Model(&message).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return logAdminAction(tx, admin.ID, action, "message", message.ID, req.Reason)
+	})
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	database.DB.First(&message, message.ID)
+	recordAudit(c, action, "message", message.ID, before, message)
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// AdminGetChat shows a full conversation, hidden messages included, for dispute handling.
+// Viewing is logged like any other admin action.
+func AdminGetChat(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	reason := c.Query("reason")
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required to view a chat"})
+		return
+	}
+
+	admin := c.MustGet("user").(models.User)
+
+	var chat models.Chat
+	if err := database.DB.Unscoped().Preload("Project").Preload("Client").Preload("Freelancer").First(&chat, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+		return
+	}
+
+	var messages []models.Message
+	if err := database.DB.Unscoped().Preload("Sender").Where("chat_id = ?", chat.ID).Order("created_at ASC").Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+
+	if err := logAdminAction(database.DB, admin.ID, "chat.view", "chat", chat.ID, reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record admin action"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chat": chat, "messages": messages})
+}
+
+// AdminListActions lists logged admin actions, newest first
+func AdminListActions(c *gin.Context) {
+	query := database.DB.Model(&models.AdminAction{}).Preload("Admin")
+
+	if adminID := c.Query("admin_id"); adminID != "" {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	var actions []models.AdminAction
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&actions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch admin actions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"actions": actions})
+}
+
+func logAdminAction(tx *gorm.DB, adminID uint, action, targetType string, targetID uint, reason string) error {
+	return tx.Create(&models.AdminAction{
+		AdminID:    adminID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+	}).Error
+}
+
+func respondAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errAdminTargetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, errAdminInvalidState):
+		c.JSON(http.StatusConflict, gin.H{"error": "This action does not apply in the current state"})
+	case errors.Is(err, errAdminSelfAction):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admins cannot moderate their own account"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply admin action"})
+	}
+}
+
+// createSystemMessage posts a system message into a chat and bumps its updated_at
+func createSystemMessage(tx *gorm.DB, chat models.Chat, senderID uint, content string) error {
+	message := models.Message{
+		ChatID:   chat.ID,
+		SenderID: senderID,
+		Content:  content,
+		Type:     "system",
+	}
+	if err := tx.Create(&message).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&chat).Update("updated_at", message.CreatedAt).Error
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/repository"
+	"freelance-platform/internal/storage"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// allowedAttachmentTypes lists the sniffed MIME types accepted in chats
+var allowedAttachmentTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"application/zip",
+	"text/plain",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// UploadAttachment stores a file sent into a chat and posts it as a file or image message
+func (h *ChatHandler) UploadAttachment(c *gin.Context) {
+	chatID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	// Verify user has access to this chat
+	chat, err := h.chats.Open(c.Request.Context(), currentUser, uint(chatID))
+	if err != nil {
+		respondChatError(c, err, "Failed to send message")
+		return
+	}
+
+	// Cap the request body; leave some room for the multipart framing
+	maxSize := storage.MaxFileSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the %d byte limit", maxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+
+	if fileHeader.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the %d byte limit", maxSize)})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	// Trust the file content, not the client-provided Content-Type
+	detected, err := mimetype.DetectReader(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	if !mimetype.EqualsAny(detected.String(), allowedAttachmentTypes...) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File type " + detected.String() + " is not allowed"})
+		return
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(detected.String())
+	key := fmt.Sprintf("chats/%d/%s%s", chat.ID, randomHex(16), detected.Extension())
+
+	if err := storage.DefaultStorage.Put(c.Request.Context(), key, file, fileHeader.Size, contentType); err != nil {
+		log.Printf("Failed to store attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	messageType := "file"
+	if strings.HasPrefix(contentType, "image/") {
+		messageType = "image"
+	}
+
+	fileName := filepath.Base(fileHeader.Filename)
+	content := strings.TrimSpace(c.PostForm("content"))
+	if content == "" {
+		content = fileName
+	}
+
+	attachment := models.Attachment{
+		ChatID:      chat.ID,
+		UploaderID:  currentUser.ID,
+		StorageKey:  key,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        fileHeader.Size,
+	}
+	var message models.Message
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attachment).Error; err != nil {
+			return err
+		}
+
+		message = models.Message{
+			ChatID:   chat.ID,
+			SenderID: currentUser.ID,
+			Content:  content,
+			Type:     messageType,
+			FileURL:  fmt.Sprintf("/api/attachments/%d", attachment.ID),
+		}
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&attachment).Update("message_id", message.ID).Error; err != nil {
+			return err
+		}
+
+		// Unhide the chat for the sender, bump its updated_at timestamp and record the event
+		return h.chats.Record(c.Request.Context(), repository.New(tx), &chat, currentUser, &message)
+	})
+	if err != nil {
+		// Don't leave orphaned files behind
+		storage.DefaultStorage.Delete(c.Request.Context(), key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+
+	// Notify both sides
+	h.chats.Deliver(c.Request.Context(), &chat, &message)
+
+	c.JSON(http.StatusCreated, gin.H{"message": message, "attachment": attachment})
+}
+
+// DownloadAttachment streams a chat file to one of the two chat participants
+func (h *ChatHandler) DownloadAttachment(c *gin.Context) {
+	attachmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var attachment models.Attachment
+	if err := database.DB.First(&attachment, attachmentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	// Only the two chat participants (and admins) may fetch the file
+	var chat models.Chat
+	if err := database.DB.First(&chat, attachment.ChatID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	// Admins may inspect files when handling disputes; like viewing a chat, this is logged
+	isParticipant := chat.ClientID == currentUser.ID || chat.FreelancerID == currentUser.ID
+	switch {
+	case currentUser.Role == "admin" && !isParticipant:
+		if err := logAdminAction(database.DB, currentUser.ID, "attachment.view", "attachment", attachment.ID, c.Query("reason")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record admin action"})
+			return
+		}
+	case !isParticipant:
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this attachment"})
+		return
+	case attachment.MessageID != nil:
+		// Files in messages hidden by an admin are no longer served to participants
+		var hidden int64
+		database.DB.Model(&models.Message{}).Where("id = ? AND hidden_at IS NOT NULL", *attachment.MessageID).Count(&hidden)
+		if hidden > 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+	}
+
+	reader, err := storage.DefaultStorage.Open(c.Request.Context(), attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer reader.Close()
+
+	// Images may render inline, everything else is downloaded
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, reader, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=3600",
+	})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
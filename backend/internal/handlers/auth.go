@@ -1,16 +1,14 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
-	"os"
-	"strconv"
-	"time"
 
+	"freelance-platform/internal/auth"
 	"freelance-platform/internal/database"
 	"freelance-platform/internal/models"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -80,17 +78,20 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateJWT(user.ID)
+	// Ask the user to confirm the address; they can request another email if this one fails
+	if err := queueAccountEmail(c.Request.Context(), VerificationEmailJob, user); err != nil {
+		log.Printf("Failed to queue verification email to user %d: %v", user.ID, err)
+	}
+
+	// Start a session
+	session, err := issueSession(c, user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"user":  user,
-		"token": token,
-	})
+	session["user"] = user
+	c.JSON(http.StatusCreated, session)
 }
 
 func Login(c *gin.Context) {
@@ -113,23 +114,37 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateJWT(user.ID)
+	// Moderated accounts cannot sign in
+	if err := auth.CheckAccountStatus(user); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Accounts with 2FA get a short-lived token to exchange at /auth/login/2fa
+	if user.TwoFactorEnabled {
+		mfaToken, err := auth.IssueMFAToken(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int(auth.MFATokenTTL.Seconds()),
+		})
+		return
+	}
+
+	// Start a session
+	session, err := issueSession(c, user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"user":  user,
-		"token": token,
-	})
-}
-
-func Logout(c *gin.Context) {
-	// In a more complex implementation, you might want to invalidate the token
-	// For now, we'll just return success and let the client handle token removal
-	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	session["user"] = user
+	c.JSON(http.StatusOK, session)
 }
 
 func GetCurrentUser(c *gin.Context) {
@@ -163,6 +178,12 @@ func UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	// Admin accounts are managed through the admin API
+	if req.Role != "" && currentUser.Role == "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admins cannot change their own role"})
+		return
+	}
+
 	// Update user fields
 	updateData := models.User{
 		Name:       req.Name,
@@ -201,27 +222,10 @@ func UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"user": updatedUser})
-}
-
-func generateJWT(userID uint) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your_jwt_secret_key"
-	}
-
-	expireHours := os.Getenv("JWT_EXPIRE_HOURS")
-	hours := 24 // default
-	if expireHours != "" {
-		if h, err := strconv.Atoi(expireHours); err == nil {
-			hours = h
-		}
+	recordAudit(c, "user.profile", "user", updatedUser.ID, currentUser, updatedUser)
+	if updatedUser.Role != currentUser.Role {
+		recordAudit(c, "user.role", "user", updatedUser.ID, gin.H{"role": currentUser.Role}, gin.H{"role": updatedUser.Role})
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * time.Duration(hours)).Unix(),
-	})
-
-	return token.SignedString([]byte(secret))
-} 
\ No newline at end of file
+	c.JSON(http.StatusOK, gin.H{"user": updatedUser})
+}
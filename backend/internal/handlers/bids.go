@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateBidRequest struct {
+	Amount   int    `json:"amount" binding:"required,gt=0"`
+	Proposal string `json:"proposal" binding:"required"`
+	Timeline string `json:"timeline" binding:"required"`
+}
+
+// AcceptBid accepts a pending bid, rejects the competing ones and assigns the freelancer to the project
+func (h *ProjectHandler) AcceptBid(c *gin.Context) {
+	bidID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bid ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	bid, err := h.projects.AcceptBid(c.Request.Context(), currentUser, uint(bidID))
+	if err != nil {
+		respondBidTransitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bid": bid})
+}
+
+// RejectBid rejects a single pending bid on one of the current user's projects
+func (h *ProjectHandler) RejectBid(c *gin.Context) {
+	bidID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bid ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	bid, err := h.projects.RejectBid(c.Request.Context(), currentUser, uint(bidID))
+	if err != nil {
+		respondBidTransitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bid": bid})
+}
+
+// UpdateBid lets a freelancer revise a pending bid, keeping the previous version as a revision
+func (h *ProjectHandler) UpdateBid(c *gin.Context) {
+	bidID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bid ID"})
+		return
+	}
+
+	var req UpdateBidRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	bid, err := h.projects.UpdateBid(c.Request.Context(), currentUser, uint(bidID), services.BidRevisionInput(req))
+	if err != nil {
+		respondBidTransitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bid": bid})
+}
+
+// WithdrawBid lets a freelancer pull back one of their pending bids
+func (h *ProjectHandler) WithdrawBid(c *gin.Context) {
+	bidID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bid ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	bid, err := h.projects.WithdrawBid(c.Request.Context(), currentUser, uint(bidID))
+	if err != nil {
+		respondBidTransitionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bid": bid})
+}
+
+// GetBidRevisions returns the edit history of a bid to its freelancer and the project owner
+func (h *ProjectHandler) GetBidRevisions(c *gin.Context) {
+	bidID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bid ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	revisions, err := h.projects.BidRevisions(c.Request.Context(), currentUser, uint(bidID))
+	switch {
+	case errors.Is(err, services.ErrBidNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bid not found"})
+		return
+	case errors.Is(err, services.ErrBidNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this bid"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bid revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+func respondBidTransitionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrBidNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bid not found"})
+	case errors.Is(err, services.ErrBidNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only manage your own bids or bids on your own projects"})
+	case errors.Is(err, services.ErrBidOutOfBudget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bid amount must be within project budget range"})
+	case errors.Is(err, services.ErrBidExpired):
+		c.JSON(http.StatusConflict, gin.H{"error": "Bid has expired"})
+	case errors.Is(err, services.ErrProjectNotBidding):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project is not open for bidding"})
+	case errors.Is(err, services.ErrBidNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending bids can be changed"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update bid"})
+	}
+}
@@ -1,12 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
-	"freelance-platform/internal/database"
 	"freelance-platform/internal/models"
+	"freelance-platform/internal/presence"
+	"freelance-platform/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,8 +23,19 @@ type SendMessageRequest struct {
 	Type    string `json:"type"` // defaults to "text"
 }
 
-// GetChats returns all chats for the current user
-func GetChats(c *gin.Context) {
+// ChatHandler serves the chat, message and attachment endpoints
+type ChatHandler struct {
+	chats    *services.ChatService
+	presence presence.Store
+}
+
+// NewChatHandler returns a chat handler; presence may be nil when it isn't tracked
+func NewChatHandler(chats *services.ChatService, presence presence.Store) *ChatHandler {
+	return &ChatHandler{chats: chats, presence: presence}
+}
+
+// GetChats returns a page of the current user's chats, most recently active first
+func (h *ChatHandler) GetChats(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
@@ -31,45 +43,47 @@ func GetChats(c *gin.Context) {
 	}
 
 	currentUser := user.(models.User)
-	var chats []models.Chat
-
-	// Get chats where user is either client or freelancer
-	query := database.DB.Preload("Project").Preload("Client").Preload("Freelancer")
-	if currentUser.Role == "client" {
-		query = query.Where("client_id = ? AND client_hidden = ?", currentUser.ID, false)
-	} else {
-		query = query.Where("freelancer_id = ? AND freelancer_hidden = ?", currentUser.ID, false)
+
+	page, ok := pageQuery(c)
+	if !ok {
+		return
 	}
 
-	if err := query.Order("updated_at DESC").Find(&chats).Error; err != nil {
+	chats, meta, err := h.chats.List(c.Request.Context(), currentUser, page)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chats"})
 		return
 	}
 
-	// Add unread count for each chat
+	// Add the other participant's presence for each chat
 	type ChatWithUnread struct {
-		models.Chat
-		UnreadCount int64 `json:"unread_count"`
+		services.ChatSummary
+		Presence *presence.Status `json:"presence,omitempty"`
+	}
+
+	var statuses map[uint]presence.Status
+	if h.presence != nil && len(chats) > 0 {
+		var otherIDs []uint
+		for _, chat := range chats {
+			otherIDs = append(otherIDs, services.OtherParticipantID(chat.Chat, currentUser.ID))
+		}
+		statuses, _ = h.presence.Statuses(c.Request.Context(), otherIDs)
 	}
 
 	var chatsWithUnread []ChatWithUnread
 	for _, chat := range chats {
-		var unreadCount int64
-		database.DB.Model(&models.Message{}).
-			Where("chat_id = ? AND sender_id != ? AND read_at IS NULL", chat.ID, currentUser.ID).
-			Count(&unreadCount)
-		
-		chatsWithUnread = append(chatsWithUnread, ChatWithUnread{
-			Chat:        chat,
-			UnreadCount: unreadCount,
-		})
+		chatWithUnread := ChatWithUnread{ChatSummary: chat}
+		if status, ok := statuses[services.OtherParticipantID(chat.Chat, currentUser.ID)]; ok {
+			chatWithUnread.Presence = &status
+		}
+		chatsWithUnread = append(chatsWithUnread, chatWithUnread)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"chats": chatsWithUnread})
+	c.JSON(http.StatusOK, gin.H{"chats": chatsWithUnread, "pagination": meta})
 }
 
 // CreateChat creates a new chat between client and freelancer for a project
-func CreateChat(c *gin.Context) {
+func (h *ChatHandler) CreateChat(c *gin.Context) {
 	var req CreateChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -84,66 +98,24 @@ func CreateChat(c *gin.Context) {
 
 	currentUser := user.(models.User)
 
-	// Only clients can create chats
-	if currentUser.Role != "client" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only clients can create chats"})
-		return
-	}
-
-	// Verify project exists and user owns it
-	var project models.Project
-	if err := database.DB.First(&project, req.ProjectID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
-		return
-	}
-
-	if project.ClientID != currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can only create chats for your own projects"})
-		return
-	}
-
-	// Verify freelancer exists
-	var freelancer models.User
-	if err := database.DB.First(&freelancer, req.FreelancerID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Freelancer not found"})
-		return
-	}
-
-	if freelancer.Role != "freelancer" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Selected user is not a freelancer"})
-		return
-	}
-
-	// Check if chat already exists
-	var existingChat models.Chat
-	if err := database.DB.Where("project_id = ? AND client_id = ? AND freelancer_id = ?", 
-		req.ProjectID, currentUser.ID, req.FreelancerID).First(&existingChat).Error; err == nil {
-		// Chat already exists, return it
-		database.DB.Preload("Project").Preload("Client").Preload("Freelancer").First(&existingChat, existingChat.ID)
-		c.JSON(http.StatusOK, gin.H{"chat": existingChat})
+	chat, created, err := h.chats.Create(c.Request.Context(), currentUser, req.ProjectID, req.FreelancerID)
+	if err != nil {
+		respondChatError(c, err, "Failed to create chat")
 		return
 	}
 
-	// Create new chat
-	chat := models.Chat{
-		ProjectID:    req.ProjectID,
-		ClientID:     currentUser.ID,
-		FreelancerID: req.FreelancerID,
+	// An existing chat between the two is returned as is
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
 	}
 
-	if err := database.DB.Create(&chat).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chat"})
-		return
-	}
-
-	// Load relationships
-	database.DB.Preload("Project").Preload("Client").Preload("Freelancer").First(&chat, chat.ID)
-
-	c.JSON(http.StatusCreated, gin.H{"chat": chat})
+	c.JSON(status, gin.H{"chat": chat})
 }
 
-// GetChatMessages returns all messages for a specific chat
-func GetChatMessages(c *gin.Context) {
+// GetChatMessages returns a page of a chat's messages. The first page holds the
+// newest messages and next_cursor loads older ones; each page is oldest first.
+func (h *ChatHandler) GetChatMessages(c *gin.Context) {
 	chatID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
@@ -158,37 +130,22 @@ func GetChatMessages(c *gin.Context) {
 
 	currentUser := user.(models.User)
 
-	// Verify user has access to this chat
-	var chat models.Chat
-	if err := database.DB.First(&chat, chatID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+	page, ok := pageQuery(c)
+	if !ok {
 		return
 	}
 
-	if chat.ClientID != currentUser.ID && chat.FreelancerID != currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
-		return
-	}
-
-	// Check if chat is hidden for current user
-	if (currentUser.Role == "client" && chat.ClientHidden) || 
-	   (currentUser.Role == "freelancer" && chat.FreelancerHidden) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
-		return
-	}
-
-	// Get messages for this chat
-	var messages []models.Message
-	if err := database.DB.Preload("Sender").Where("chat_id = ?", chatID).Order("created_at ASC").Find(&messages).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+	messages, meta, err := h.chats.Messages(c.Request.Context(), currentUser, uint(chatID), page)
+	if err != nil {
+		respondChatError(c, err, "Failed to fetch messages")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"messages": messages})
+	c.JSON(http.StatusOK, gin.H{"messages": messages, "pagination": meta})
 }
 
 // SendMessage sends a new message in a chat
-func SendMessage(c *gin.Context) {
+func (h *ChatHandler) SendMessage(c *gin.Context) {
 	var req SendMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -203,64 +160,17 @@ func SendMessage(c *gin.Context) {
 
 	currentUser := user.(models.User)
 
-	// Verify user has access to this chat
-	var chat models.Chat
-	if err := database.DB.First(&chat, req.ChatID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
-		return
-	}
-
-	if chat.ClientID != currentUser.ID && chat.FreelancerID != currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
-		return
-	}
-
-	// If chat is hidden for current user, unhide it when they send a message
-	chatUpdated := false
-	if currentUser.Role == "client" && chat.ClientHidden {
-		chat.ClientHidden = false
-		chatUpdated = true
-	} else if currentUser.Role == "freelancer" && chat.FreelancerHidden {
-		chat.FreelancerHidden = false
-		chatUpdated = true
-	}
-
-	// Save chat if it was updated
-	if chatUpdated {
-		if err := database.DB.Save(&chat).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chat"})
-			return
-		}
-	}
-
-	// Create message
-	message := models.Message{
-		ChatID:   req.ChatID,
-		SenderID: currentUser.ID,
-		Content:  req.Content,
-		Type:     req.Type,
-	}
-
-	if message.Type == "" {
-		message.Type = "text"
-	}
-
-	if err := database.DB.Create(&message).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+	message, err := h.chats.Send(c.Request.Context(), currentUser, req.ChatID, req.Content, req.Type)
+	if err != nil {
+		respondChatError(c, err, "Failed to send message")
 		return
 	}
 
-	// Update chat's updated_at timestamp
-	database.DB.Model(&chat).Update("updated_at", message.CreatedAt)
-
-	// Load sender relationship
-	database.DB.Preload("Sender").First(&message, message.ID)
-
 	c.JSON(http.StatusCreated, gin.H{"message": message})
 }
 
 // MarkMessagesAsRead marks all messages in a chat as read for the current user
-func MarkMessagesAsRead(c *gin.Context) {
+func (h *ChatHandler) MarkMessagesAsRead(c *gin.Context) {
 	chatID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
@@ -275,24 +185,8 @@ func MarkMessagesAsRead(c *gin.Context) {
 
 	currentUser := user.(models.User)
 
-	// Verify user has access to this chat
-	var chat models.Chat
-	if err := database.DB.First(&chat, chatID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
-		return
-	}
-
-	if chat.ClientID != currentUser.ID && chat.FreelancerID != currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
-		return
-	}
-
-	// Mark all unread messages in this chat as read (except messages sent by current user)
-	now := time.Now()
-	if err := database.DB.Model(&models.Message{}).
-		Where("chat_id = ? AND sender_id != ? AND read_at IS NULL", chatID, currentUser.ID).
-		Update("read_at", now).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark messages as read"})
+	if err := h.chats.MarkRead(c.Request.Context(), currentUser, uint(chatID)); err != nil {
+		respondChatError(c, err, "Failed to mark messages as read")
 		return
 	}
 
@@ -300,7 +194,7 @@ func MarkMessagesAsRead(c *gin.Context) {
 }
 
 // GetUnreadCount returns the total unread message count for the current user
-func GetUnreadCount(c *gin.Context) {
+func (h *ChatHandler) GetUnreadCount(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
@@ -309,29 +203,17 @@ func GetUnreadCount(c *gin.Context) {
 
 	currentUser := user.(models.User)
 
-	// Count unread messages across all chats where user is participant
-	var unreadCount int64
-	
-	// Get all chat IDs where user is participant
-	var chatIDs []uint
-	if currentUser.Role == "client" {
-		database.DB.Model(&models.Chat{}).Where("client_id = ? AND client_hidden = ?", currentUser.ID, false).Pluck("id", &chatIDs)
-	} else {
-		database.DB.Model(&models.Chat{}).Where("freelancer_id = ? AND freelancer_hidden = ?", currentUser.ID, false).Pluck("id", &chatIDs)
-	}
-
-	// Count unread messages in these chats (excluding messages sent by current user)
-	if len(chatIDs) > 0 {
-		database.DB.Model(&models.Message{}).
-			Where("chat_id IN ? AND sender_id != ? AND read_at IS NULL", chatIDs, currentUser.ID).
-			Count(&unreadCount)
+	count, err := h.chats.UnreadCount(c.Request.Context(), currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread messages"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"unread_count": unreadCount})
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
 }
 
 // DeleteChat hides a chat for the current user (doesn't delete for other participant)
-func DeleteChat(c *gin.Context) {
+func (h *ChatHandler) DeleteChat(c *gin.Context) {
 	chatID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
@@ -346,47 +228,32 @@ func DeleteChat(c *gin.Context) {
 
 	currentUser := user.(models.User)
 
-	// Verify user has access to this chat
-	var chat models.Chat
-	if err := database.DB.First(&chat, chatID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+	if err := h.chats.Hide(c.Request.Context(), currentUser, uint(chatID)); err != nil {
+		respondChatError(c, err, "Failed to hide chat")
 		return
 	}
 
-	if chat.ClientID != currentUser.ID && chat.FreelancerID != currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
-		return
-	}
-
-	// Hide the chat for the current user only
-	if currentUser.Role == "client" {
-		chat.ClientHidden = true
-	} else {
-		chat.FreelancerHidden = true
-	}
-
-	// Save the updated chat
-	if err := database.DB.Save(&chat).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hide chat"})
-		return
-	}
-
-	// If both users have hidden the chat, we can safely delete it and all messages
-	if chat.ClientHidden && chat.FreelancerHidden {
-		// Delete all messages in this chat first
-		if err := database.DB.Where("chat_id = ?", chatID).Delete(&models.Message{}).Error; err != nil {
-			// Log error but don't fail the request
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete chat messages"})
-			return
-		}
+	c.JSON(http.StatusOK, gin.H{"message": "Chat hidden successfully"})
+}
 
-		// Delete the chat
-		if err := database.DB.Delete(&chat).Error; err != nil {
-			// Log error but don't fail the request
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete chat"})
-			return
-		}
+// respondChatError maps chat service errors to responses, using fallback for unexpected failures
+func respondChatError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrChatNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+	case errors.Is(err, services.ErrNotChatParticipant):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+	case errors.Is(err, services.ErrNotClient):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only clients can create chats"})
+	case errors.Is(err, services.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, services.ErrNotProjectOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only create chats for your own projects"})
+	case errors.Is(err, services.ErrFreelancerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Freelancer not found"})
+	case errors.Is(err, services.ErrNotAFreelancer):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Selected user is not a freelancer"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Chat hidden successfully"})
-} 
\ No newline at end of file
+}
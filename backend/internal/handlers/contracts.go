@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"freelance-platform/internal/ledger"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MilestoneRequest struct {
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description"`
+	Amount      int        `json:"amount" binding:"required,gt=0"`
+	DueDate     *time.Time `json:"due_date"`
+}
+
+type UpdateMilestonesRequest struct {
+	Milestones []MilestoneRequest `json:"milestones" binding:"required,min=1,dive"`
+}
+
+type SubmitMilestoneRequest struct {
+	Note    string `json:"note" binding:"required"`
+	FileURL string `json:"file_url"`
+}
+
+type RequestChangesRequest struct {
+	Feedback string `json:"feedback" binding:"required"`
+}
+
+var (
+	errContractNotFound        = errors.New("contract not found")
+	errContractForbidden       = errors.New("not allowed on this contract")
+	errContractNotActive       = errors.New("contract is not active")
+	errMilestonesLocked        = errors.New("milestones already in progress")
+	errMilestonePaymentPending = errors.New("milestone payment in progress")
+	errMilestoneAmounts        = errors.New("milestone amounts do not match contract")
+	errMilestoneNotFound       = errors.New("milestone not found")
+	errMilestoneState          = errors.New("milestone is not in the right state")
+	errMilestoneOutOfOrder     = errors.New("earlier milestones are not approved")
+)
+
+// ContractHandler serves the contract and milestone endpoints
+type ContractHandler struct {
+	db *gorm.DB
+}
+
+func NewContractHandler(db *gorm.DB) *ContractHandler {
+	return &ContractHandler{db: db}
+}
+
+// GetContracts returns the contracts the current user is a party to
+func (h *ContractHandler) GetContracts(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	query := h.db.Preload("Project").Preload("Client").Preload("Freelancer").
+		Preload("Milestones", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Where("client_id = ? OR freelancer_id = ?", currentUser.ID, currentUser.ID)
+
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var contracts []models.Contract
+	if err := query.Order("created_at DESC").Find(&contracts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contracts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contracts": contracts})
+}
+
+// GetContract returns a contract with its milestones and deliverables
+func (h *ContractHandler) GetContract(c *gin.Context) {
+	contractID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contract ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	contract, err := h.loadContract(uint(contractID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contract not found"})
+		return
+	}
+
+	if contract.ClientID != currentUser.ID && contract.FreelancerID != currentUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this contract"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contract": contract})
+}
+
+// UpdateMilestones replaces the milestone plan of a contract; only the client may do
+// this and only before any work has been submitted
+func (h *ContractHandler) UpdateMilestones(c *gin.Context) {
+	contractID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contract ID"})
+		return
+	}
+
+	var req UpdateMilestonesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var contract models.Contract
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&contract, contractID).Error; err != nil {
+			return errContractNotFound
+		}
+
+		if contract.ClientID != currentUser.ID {
+			return errContractForbidden
+		}
+
+		if contract.Status != "active" {
+			return errContractNotActive
+		}
+
+		// Lock the current plan so no payment can start on it while it is replaced
+		var current []models.Milestone
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("contract_id = ?", contract.ID).Find(&current).Error; err != nil {
+			return err
+		}
+
+		// A charge in flight would have no milestone to fund once it succeeds
+		var paying int64
+		if err := tx.Model(&models.Payment{}).
+			Where("milestone_id IN (?) AND status IN ?",
+				tx.Model(&models.Milestone{}).Select("id").Where("contract_id = ?", contract.ID),
+				[]string{"requires_confirmation", "processing"}).
+			Count(&paying).Error; err != nil {
+			return err
+		}
+		if paying > 0 {
+			return errMilestonePaymentPending
+		}
+
+		// The plan is frozen as soon as any milestone has moved past pending or holds money
+		var started int64
+		tx.Model(&models.Milestone{}).
+			Where("contract_id = ? AND (status != ? OR escrow_status != ?)", contract.ID, "pending", "unfunded").
+			Count(&started)
+		if started > 0 {
+			return errMilestonesLocked
+		}
+
+		total := 0
+		for _, m := range req.Milestones {
+			total += m.Amount
+		}
+		if total != contract.Amount {
+			return errMilestoneAmounts
+		}
+
+		if err := tx.Where("contract_id = ?", contract.ID).Delete(&models.Milestone{}).Error; err != nil {
+			return err
+		}
+
+		milestones := make([]models.Milestone, 0, len(req.Milestones))
+		for i, m := range req.Milestones {
+			milestones = append(milestones, models.Milestone{
+				ContractID:  contract.ID,
+				Position:    i + 1,
+				Title:       m.Title,
+				Description: m.Description,
+				Amount:      m.Amount,
+				DueDate:     m.DueDate,
+				Status:      "pending",
+			})
+		}
+		return tx.Create(&milestones).Error
+	})
+	if err != nil {
+		respondContractError(c, err)
+		return
+	}
+
+	contract, _ := h.loadContract(uint(contractID))
+	c.JSON(http.StatusOK, gin.H{"contract": contract})
+}
+
+// SubmitMilestone records a deliverable from the freelancer and puts the milestone up for review
+func (h *ContractHandler) SubmitMilestone(c *gin.Context) {
+	var req SubmitMilestoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.transitionMilestone(c, func(tx *gorm.DB, contract models.Contract, milestone *models.Milestone, currentUser models.User) error {
+		if contract.FreelancerID != currentUser.ID {
+			return errContractForbidden
+		}
+
+		if milestone.Status != "pending" && milestone.Status != "changes_requested" {
+			return errMilestoneState
+		}
+
+		// Milestones are delivered in order
+		var unapproved int64
+		tx.Model(&models.Milestone{}).
+			Where("contract_id = ? AND position < ? AND status != ?", contract.ID, milestone.Position, "approved").
+			Count(&unapproved)
+		if unapproved > 0 {
+			return errMilestoneOutOfOrder
+		}
+
+		deliverable := models.Deliverable{
+			MilestoneID: milestone.ID,
+			Note:        req.Note,
+			FileURL:     req.FileURL,
+			Status:      "submitted",
+		}
+		if err := tx.Create(&deliverable).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(milestone).Update("status", "submitted").Error
+	})
+}
+
+// ApproveMilestone accepts the latest deliverable; approving the last milestone completes the project
+func (h *ContractHandler) ApproveMilestone(c *gin.Context) {
+	var held, released models.Milestone
+	h.transitionMilestone(c, func(tx *gorm.DB, contract models.Contract, milestone *models.Milestone, currentUser models.User) error {
+		if contract.ClientID != currentUser.ID {
+			return errContractForbidden
+		}
+
+		if milestone.Status != "submitted" {
+			return errMilestoneState
+		}
+
+		now := time.Now()
+		if err := reviewLatestDeliverable(tx, milestone.ID, "approved", ""); err != nil {
+			return err
+		}
+		if err := tx.Model(milestone).Updates(map[string]interface{}{
+			"status":      "approved",
+			"approved_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Pay the freelancer out of escrow if the milestone was funded
+		if milestone.EscrowStatus == "funded" {
+			held = *milestone
+			if _, err := ledger.ReleaseMilestone(tx, milestone, contract.FreelancerID); err != nil {
+				return err
+			}
+			released = *milestone
+		}
+
+		// Complete the contract and the project once every milestone is approved
+		var remaining int64
+		tx.Model(&models.Milestone{}).Where("contract_id = ? AND status != ?", contract.ID, "approved").Count(&remaining)
+		if remaining > 0 {
+			return nil
+		}
+
+		if err := tx.Model(&contract).Updates(map[string]interface{}{
+			"status":       "completed",
+			"completed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var project models.Project
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, contract.ProjectID).Error; err != nil {
+			return err
+		}
+		before := project
+		if err := ApplyProjectStatus(tx, &project, "completed"); err != nil {
+			return err
+		}
+		return recordStatusChange(c, tx, before, project)
+	})
+
+	if c.Writer.Status() == http.StatusOK {
+		outbox.Flush(c.Request.Context())
+		if released.ID != 0 {
+			recordAudit(c, "milestone.release", "milestone", released.ID, held, released)
+		}
+	}
+}
+
+// RequestMilestoneChanges sends the latest deliverable back to the freelancer with feedback
+func (h *ContractHandler) RequestMilestoneChanges(c *gin.Context) {
+	var req RequestChangesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.transitionMilestone(c, func(tx *gorm.DB, contract models.Contract, milestone *models.Milestone, currentUser models.User) error {
+		if contract.ClientID != currentUser.ID {
+			return errContractForbidden
+		}
+
+		if milestone.Status != "submitted" {
+			return errMilestoneState
+		}
+
+		if err := reviewLatestDeliverable(tx, milestone.ID, "changes_requested", req.Feedback); err != nil {
+			return err
+		}
+		return tx.Model(milestone).Update("status", "changes_requested").Error
+	})
+}
+
+// transitionMilestone loads and locks the milestone from the :id param and its active
+// contract, runs apply in a transaction and responds with the updated contract
+func (h *ContractHandler) transitionMilestone(c *gin.Context, apply func(tx *gorm.DB, contract models.Contract, milestone *models.Milestone, currentUser models.User) error) {
+	milestoneID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid milestone ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var contractID uint
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var milestone models.Milestone
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&milestone, milestoneID).Error; err != nil {
+			return errMilestoneNotFound
+		}
+
+		var contract models.Contract
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&contract, milestone.ContractID).Error; err != nil {
+			return errContractNotFound
+		}
+		contractID = contract.ID
+
+		if contract.ClientID != currentUser.ID && contract.FreelancerID != currentUser.ID {
+			return errContractForbidden
+		}
+
+		if contract.Status != "active" {
+			return errContractNotActive
+		}
+
+		return apply(tx, contract, &milestone, currentUser)
+	})
+	if err != nil {
+		respondContractError(c, err)
+		return
+	}
+
+	contract, _ := h.loadContract(contractID)
+	c.JSON(http.StatusOK, gin.H{"contract": contract})
+}
+
+// reviewLatestDeliverable records the client's decision on the most recent submission
+func reviewLatestDeliverable(tx *gorm.DB, milestoneID uint, status, feedback string) error {
+	var deliverable models.Deliverable
+	if err := tx.Where("milestone_id = ? AND status = ?", milestoneID, "submitted").
+		Order("created_at DESC").First(&deliverable).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&deliverable).Updates(map[string]interface{}{
+		"status":      status,
+		"feedback":    feedback,
+		"reviewed_at": time.Now(),
+	}).Error
+}
+
+func (h *ContractHandler) loadContract(id uint) (models.Contract, error) {
+	var contract models.Contract
+	err := h.db.Preload("Project").Preload("Client").Preload("Freelancer").
+		Preload("Milestones", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Milestones.Deliverables", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		First(&contract, id).Error
+	return contract, err
+}
+
+func respondContractError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errContractNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contract not found"})
+	case errors.Is(err, errMilestoneNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Milestone not found"})
+	case errors.Is(err, errContractForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to perform this action on the contract"})
+	case errors.Is(err, errContractNotActive):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Contract is not active"})
+	case errors.Is(err, errMilestonePaymentPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "A milestone payment is still being processed"})
+	case errors.Is(err, errMilestonesLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": "Milestones can no longer be changed once work has been submitted or funded"})
+	case errors.Is(err, errMilestoneAmounts):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Milestone amounts must add up to the contract amount"})
+	case errors.Is(err, errMilestoneState):
+		c.JSON(http.StatusConflict, gin.H{"error": "Milestone is not in a state that allows this action"})
+	case errors.Is(err, errMilestoneOutOfOrder):
+		c.JSON(http.StatusConflict, gin.H{"error": "Earlier milestones must be approved first"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contract"})
+	}
+}
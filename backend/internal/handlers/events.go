@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordEvent records an event caused by the current request's user in the
+// outbox through tx; flush the outbox once tx commits
+func recordEvent(c *gin.Context, tx *gorm.DB, eventType string, data interface{}) error {
+	return outbox.Record(c.Request.Context(), tx, events.Event{Type: eventType, ActorID: actorID(c), Data: data})
+}
+
+// recordStatusChange records ProjectStatusChanged through tx when a change moved the project to another status
+func recordStatusChange(c *gin.Context, tx *gorm.DB, before, after models.Project) error {
+	return services.RecordStatusChange(c.Request.Context(), tx, actorID(c), before, after)
+}
+
+// actorID is the ID of the current request's user, or 0 without one
+func actorID(c *gin.Context) uint {
+	if user, exists := c.Get("user"); exists {
+		return user.(models.User).ID
+	}
+	return 0
+}
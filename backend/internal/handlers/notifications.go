@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"freelance-platform/internal/mailer"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationPreferencesRequest struct {
+	Channels map[string]string `json:"channels"` // only the types to change
+	// QuietHours with an empty start and end turns them off; omit it to leave them alone
+	QuietHours *services.QuietHours `json:"quiet_hours"`
+	Locale     string               `json:"locale"`
+}
+
+// NotificationHandler serves the notification center endpoints
+type NotificationHandler struct {
+	notifications *services.NotificationService
+}
+
+func NewNotificationHandler(notifications *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notifications: notifications}
+}
+
+// GetNotifications returns a page of the current user's notifications, newest
+// first; ?unread=true leaves out the ones already read
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	page, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	unreadOnly := c.Query("unread") == "true"
+	notifications, meta, err := h.notifications.List(c.Request.Context(), currentUser, unreadOnly, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "pagination": meta})
+}
+
+// GetUnreadCount returns the number of unread notifications for the badge
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	count, err := h.notifications.UnreadCount(c.Request.Context(), currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
+}
+
+// MarkAsRead marks one of the current user's notifications as read
+func (h *NotificationHandler) MarkAsRead(c *gin.Context) {
+	notificationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	notification, err := h.notifications.MarkRead(c.Request.Context(), currentUser, uint(notificationID))
+	if errors.Is(err, services.ErrNotificationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notification as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notification": notification})
+}
+
+// MarkAllAsRead marks every notification of the current user as read
+func (h *NotificationHandler) MarkAllAsRead(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	updated, err := h.notifications.MarkAllRead(c.Request.Context(), currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// GetNotificationPreferences returns the current user's channels, quiet hours and email locale
+func (h *NotificationHandler) GetNotificationPreferences(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	preferences, err := h.notifications.Preferences(c.Request.Context(), currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+// UpdateNotificationPreferences changes some of the current user's notification preferences
+func (h *NotificationHandler) UpdateNotificationPreferences(c *gin.Context) {
+	var req NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	preferences, err := h.notifications.UpdatePreferences(c.Request.Context(), currentUser, services.PreferencesUpdate(req))
+	switch {
+	case errors.Is(err, services.ErrUnknownNotificationType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification type. Valid types are: " + strings.Join(services.NotificationTypes, ", ")})
+	case errors.Is(err, services.ErrInvalidChannel):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel. Valid channels are: in_app, email, none (project.matched: email, none)"})
+	case errors.Is(err, services.ErrInvalidQuietHours):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quiet hours need a different HH:MM start and end"})
+	case errors.Is(err, services.ErrUnsupportedLocale):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale. Supported locales are: " + strings.Join(mailer.Locales, ", ")})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"freelance-platform/internal/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageQuery reads the limit, cursor and total query parameters, answering
+// with 400 when they are malformed
+func pageQuery(c *gin.Context) (pagination.Page, bool) {
+	page, err := pagination.FromQuery(c.Request.URL.Query())
+	switch {
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return page, false
+	case errors.Is(err, pagination.ErrInvalidLimit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Limit must be a positive number"})
+		return page, false
+	}
+	return page, true
+}
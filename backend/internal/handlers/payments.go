@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"freelance-platform/internal/audit"
+	"freelance-platform/internal/jobs"
+	"freelance-platform/internal/ledger"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/payments"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PayoutRequest struct {
+	Amount      int64  `json:"amount" binding:"required,gt=0"`
+	Destination string `json:"destination" binding:"required"`
+}
+
+// MoneyTransfer is the payload of the jobs that ask the provider to move
+// money: the payment to refund or the payout to send
+type MoneyTransfer struct {
+	ID uint `json:"id"`
+}
+
+// Refunds and payouts are recorded first and sent to the provider by the
+// worker once they commit. The provider dedups repeats by our reference, so a
+// job that dies after the call can safely run again; the outcome arrives
+// through the webhook.
+var (
+	RefundPaymentJob = jobs.Definition[MoneyTransfer]{Name: "payments.refund", MaxAttempts: 20}
+	SendPayoutJob    = jobs.Definition[MoneyTransfer]{Name: "payments.payout", MaxAttempts: 20}
+)
+
+var (
+	errInsufficientBalance = errors.New("insufficient balance")
+	// errPaymentObjectUnknown means a webhook is about a payment or payout we
+	// have no record of yet; the provider is asked to send it again later
+	errPaymentObjectUnknown = errors.New("payment object not recorded yet")
+)
+
+// PaymentHandler serves the payment, wallet and payout endpoints and runs
+// the jobs that move money through the provider
+type PaymentHandler struct {
+	db *gorm.DB
+}
+
+func NewPaymentHandler(db *gorm.DB) *PaymentHandler {
+	return &PaymentHandler{db: db}
+}
+
+// FundMilestone starts a payment that moves the milestone amount into escrow once the provider confirms it
+func (h *PaymentHandler) FundMilestone(c *gin.Context) {
+	milestoneID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid milestone ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var milestone models.Milestone
+	if err := h.db.First(&milestone, milestoneID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Milestone not found"})
+		return
+	}
+
+	var contract models.Contract
+	if err := h.db.First(&contract, milestone.ContractID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contract not found"})
+		return
+	}
+
+	if contract.ClientID != currentUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the client can fund milestones"})
+		return
+	}
+
+	if contract.Status != "active" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Contract is not active"})
+		return
+	}
+
+	if milestone.EscrowStatus != "unfunded" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Milestone is already funded"})
+		return
+	}
+
+	provider := payments.DefaultProvider
+	var payment models.Payment
+	reused := false
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the milestone so the contract's plan cannot be replaced under the payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&milestone, milestone.ID).Error; err != nil {
+			return err
+		}
+
+		// Reuse a payment that is still in flight instead of charging twice
+		if err := tx.Where("milestone_id = ? AND status IN ?", milestone.ID, []string{"requires_confirmation", "processing"}).
+			First(&payment).Error; err == nil {
+			reused = true
+			return nil
+		}
+
+		payment = models.Payment{
+			MilestoneID: milestone.ID,
+			ClientID:    currentUser.ID,
+			Provider:    provider.Name(),
+			Amount:      int64(milestone.Amount),
+			Currency:    "TWD",
+			Status:      "requires_confirmation",
+		}
+		return tx.Create(&payment).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Milestone not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment"})
+		return
+	}
+	if reused {
+		c.JSON(http.StatusOK, gin.H{"payment": payment})
+		return
+	}
+
+	intent, err := provider.CreateIntent(c.Request.Context(), payments.IntentRequest{
+		Amount:    payment.Amount,
+		Currency:  payment.Currency,
+		Reference: fmt.Sprintf("payment:%d", payment.ID),
+	})
+	if err != nil {
+		log.Printf("Failed to create payment intent: %v", err)
+		h.db.Model(&payment).Update("status", "failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Payment provider is unavailable"})
+		return
+	}
+
+	h.db.Model(&payment).Update("provider_intent_id", intent.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"payment":       payment,
+		"client_secret": intent.ClientSecret,
+	})
+}
+
+// ConfirmPayment confirms a payment server-side; the outcome arrives later through the webhook
+func (h *PaymentHandler) ConfirmPayment(c *gin.Context) {
+	paymentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var payment models.Payment
+	if err := h.db.First(&payment, paymentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+
+	if payment.ClientID != currentUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only confirm your own payments"})
+		return
+	}
+
+	if payment.Status != "requires_confirmation" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment cannot be confirmed in its current state"})
+		return
+	}
+
+	if _, err := payments.DefaultProvider.ConfirmIntent(c.Request.Context(), payment.ProviderIntentID); err != nil {
+		log.Printf("Failed to confirm payment %d: %v", payment.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Payment provider rejected the confirmation"})
+		return
+	}
+
+	// Don't overwrite a webhook that already settled the payment
+	h.db.Model(&models.Payment{}).
+		Where("id = ? AND status = ?", payment.ID, "requires_confirmation").
+		Update("status", "processing")
+	h.db.First(&payment, payment.ID)
+
+	c.JSON(http.StatusOK, gin.H{"payment": payment})
+}
+
+// PaymentWebhook returns the endpoint for the named provider's notifications.
+// Each event ID is processed at most once.
+func (h *PaymentHandler) PaymentWebhook(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.receivePaymentWebhook(c, name)
+	}
+}
+
+func (h *PaymentHandler) receivePaymentWebhook(c *gin.Context, name string) {
+	provider := payments.DefaultProvider
+	if provider == nil || provider.Name() != name {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read payload"})
+		return
+	}
+
+	event, err := provider.VerifyWebhook(payload, c.Request.Header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook signature"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Claim the event ID; a conflict means we have already handled it
+		record := models.PaymentEvent{
+			Provider:    provider.Name(),
+			EventID:     event.ID,
+			Type:        event.Type,
+			Payload:     string(payload),
+			ProcessedAt: time.Now(),
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return applyPaymentEvent(tx, provider.Name(), event)
+	})
+	if errors.Is(err, errPaymentObjectUnknown) {
+		// Nothing was recorded, so the redelivery is processed in full
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment not recorded yet"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to process %s webhook %s: %v", provider.Name(), event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// applyPaymentEvent updates payments, payouts and the ledger for a verified webhook event
+func applyPaymentEvent(tx *gorm.DB, provider string, event payments.WebhookEvent) error {
+	switch event.Type {
+	case payments.EventPaymentSucceeded, payments.EventPaymentFailed, payments.EventRefundSucceeded:
+		var payment models.Payment
+		if err := lockPaymentObject(tx, &payment, provider, "provider_intent_id", "payment", event); err != nil {
+			return err
+		}
+
+		// Late or out of order events about a payment in another state are ignored
+		switch event.Type {
+		case payments.EventPaymentFailed:
+			return tx.Model(&payment).
+				Where("status IN ?", []string{"requires_confirmation", "processing"}).
+				Update("status", "failed").Error
+		case payments.EventRefundSucceeded:
+			return tx.Model(&payment).
+				Where("status = ?", "refund_pending").
+				Update("status", "refunded").Error
+		}
+
+		// Another event about a charge we already settled
+		switch payment.Status {
+		case "succeeded", "refund_pending", "refunded":
+			return nil
+		}
+		if err := tx.Model(&payment).Update("status", "succeeded").Error; err != nil {
+			return err
+		}
+
+		// Move the money into escrow, unless another payment already did or
+		// the contract ended meanwhile; then this charge goes back
+		var milestone models.Milestone
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&milestone, payment.MilestoneID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The milestone plan was replaced; there is nothing left to fund
+			return queueRefund(tx, payment)
+		}
+		if err != nil {
+			return err
+		}
+		var contract models.Contract
+		if err := tx.First(&contract, milestone.ContractID).Error; err != nil {
+			return err
+		}
+		if contract.Status != "active" || (milestone.EscrowStatus != "" && milestone.EscrowStatus != "unfunded") {
+			return queueRefund(tx, payment)
+		}
+		_, err = ledger.FundMilestone(tx, &milestone)
+		return err
+
+	case payments.EventPayoutPaid, payments.EventPayoutFailed:
+		var payout models.Payout
+		if err := lockPaymentObject(tx, &payout, provider, "provider_payout_id", "payout", event); err != nil {
+			return err
+		}
+		if payout.Status != "pending" {
+			return nil
+		}
+
+		if event.Type == payments.EventPayoutPaid {
+			return tx.Model(&payout).Update("status", "paid").Error
+		}
+
+		// The money never left; give it back to the freelancer
+		if err := tx.Model(&payout).Update("status", "failed").Error; err != nil {
+			return err
+		}
+		_, err := ledger.ReversePayout(tx, &payout)
+		return err
+	}
+
+	return nil
+}
+
+// lockPaymentObject locks the payment or payout an event is about into dest,
+// looking it up by the provider's ID in column. An event that arrives before
+// that ID was stored is matched through the reference we sent, "kind:ID".
+// Returns errPaymentObjectUnknown when neither matches.
+func lockPaymentObject(tx *gorm.DB, dest interface{}, provider, column, kind string, event payments.WebhookEvent) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider = ? AND "+column+" = ?", provider, event.ObjectID).
+		First(dest).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var id uint
+	if _, scanErr := fmt.Sscanf(event.Reference, kind+":%d", &id); scanErr != nil || event.ObjectID == "" {
+		return errPaymentObjectUnknown
+	}
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider = ? AND ("+column+" = '' OR "+column+" IS NULL)", provider).
+		First(dest, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errPaymentObjectUnknown
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(dest).Update(column, event.ObjectID).Error
+}
+
+// refundMilestonePayment returns a funded milestone's charge to the client.
+// The refund is queued in tx and sent to the provider after it commits.
+func refundMilestonePayment(tx *gorm.DB, milestone models.Milestone) error {
+	var payment models.Payment
+	if err := tx.Where("milestone_id = ? AND status = ?", milestone.ID, "succeeded").First(&payment).Error; err != nil {
+		// Funded without a provider charge (e.g. imported data); the ledger refund is enough
+		return nil
+	}
+
+	return queueRefund(tx, payment)
+}
+
+// queueRefund marks a succeeded payment refund_pending and queues
+// RefundPaymentJob in tx
+func queueRefund(tx *gorm.DB, payment models.Payment) error {
+	if err := tx.Model(&payment).Update("status", "refund_pending").Error; err != nil {
+		return err
+	}
+
+	_, err := RefundPaymentJob.Enqueue(tx.Statement.Context, tx, MoneyTransfer{ID: payment.ID}, jobs.Options{
+		UniqueKey: fmt.Sprintf("%s:%d", RefundPaymentJob.Name, payment.ID),
+	})
+	return err
+}
+
+// RefundPayment runs RefundPaymentJob
+func (h *PaymentHandler) RefundPayment(ctx context.Context, task MoneyTransfer) error {
+	var payment models.Payment
+	err := h.db.WithContext(ctx).First(&payment, task.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return jobs.Permanent(err)
+	}
+	if err != nil || payment.Status != "refund_pending" {
+		return err
+	}
+
+	provider, err := providerFor(payment.Provider)
+	if err != nil {
+		return err
+	}
+
+	result, err := provider.Refund(ctx, payments.RefundRequest{
+		IntentID:  payment.ProviderIntentID,
+		Amount:    payment.Amount,
+		Reference: fmt.Sprintf("payment:%d", payment.ID),
+	})
+	if err != nil {
+		return err
+	}
+
+	audit.Record(audit.Entry{
+		Action:     "payment.refund",
+		TargetType: "payment",
+		TargetID:   payment.ID,
+		After:      gin.H{"amount": payment.Amount, "provider_refund_id": result.ID},
+	})
+	return nil
+}
+
+// SendPayout runs SendPayoutJob
+func (h *PaymentHandler) SendPayout(ctx context.Context, task MoneyTransfer) error {
+	var payout models.Payout
+	err := h.db.WithContext(ctx).First(&payout, task.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return jobs.Permanent(err)
+	}
+	if err != nil || payout.Status != "pending" || payout.ProviderPayoutID != "" {
+		return err
+	}
+
+	provider, err := providerFor(payout.Provider)
+	if err != nil {
+		return err
+	}
+
+	result, err := provider.Payout(ctx, payments.PayoutRequest{
+		Amount:      payout.Amount,
+		Currency:    "TWD",
+		Destination: payout.Destination,
+		Reference:   fmt.Sprintf("payout:%d", payout.ID),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The webhook may have beaten us to it
+	if err := h.db.WithContext(ctx).Model(&models.Payout{}).
+		Where("id = ? AND (provider_payout_id = '' OR provider_payout_id IS NULL)", payout.ID).
+		Update("provider_payout_id", result.ID).Error; err != nil {
+		return err
+	}
+
+	audit.Record(audit.Entry{
+		Action:     "payout.send",
+		TargetType: "payout",
+		TargetID:   payout.ID,
+		Before:     gin.H{"provider_payout_id": payout.ProviderPayoutID},
+		After:      gin.H{"provider_payout_id": result.ID},
+	})
+	return nil
+}
+
+// providerFor returns the configured provider if it is the named one
+func providerFor(name string) (payments.PaymentProvider, error) {
+	if payments.DefaultProvider == nil || payments.DefaultProvider.Name() != name {
+		return nil, fmt.Errorf("payment provider %s is not configured", name)
+	}
+	return payments.DefaultProvider, nil
+}
+
+// CreatePayout pays part of a freelancer's released earnings out to their provider account
+func (h *PaymentHandler) CreatePayout(c *gin.Context) {
+	var req PayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	if currentUser.Role != "freelancer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only freelancers can request payouts"})
+		return
+	}
+
+	provider := payments.DefaultProvider
+	var payout models.Payout
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the balance so concurrent payouts cannot overdraw it
+		account, err := ledger.PayableAccount(tx, currentUser.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, account.ID).Error; err != nil {
+			return err
+		}
+		if ledger.NormalBalance(account) < req.Amount {
+			return errInsufficientBalance
+		}
+
+		payout = models.Payout{
+			FreelancerID: currentUser.ID,
+			Provider:     provider.Name(),
+			Destination:  req.Destination,
+			Amount:       req.Amount,
+			Status:       "pending",
+		}
+		if err := tx.Create(&payout).Error; err != nil {
+			return err
+		}
+
+		if _, err := ledger.RecordPayout(tx, &payout); err != nil {
+			return err
+		}
+
+		_, err = SendPayoutJob.Enqueue(c.Request.Context(), tx, MoneyTransfer{ID: payout.ID}, jobs.Options{})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errInsufficientBalance) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Payout exceeds available balance"})
+			return
+		}
+		log.Printf("Failed to create payout: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payout"})
+		return
+	}
+	recordAudit(c, "payout.create", "payout", payout.ID, nil, payout)
+
+	c.JSON(http.StatusCreated, gin.H{"payout": payout})
+}
+
+// GetWallet returns the current user's available earnings and payout history
+func (h *PaymentHandler) GetWallet(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var balance int64
+	var account models.LedgerAccount
+	if err := h.db.Where("code = ?", fmt.Sprintf("payable:freelancer:%d", currentUser.ID)).First(&account).Error; err == nil {
+		balance = ledger.NormalBalance(account)
+	}
+
+	var payouts []models.Payout
+	if err := h.db.Where("freelancer_id = ?", currentUser.ID).Order("created_at DESC").Find(&payouts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance":  balance,
+		"currency": "TWD",
+		"payouts":  payouts,
+	})
+}
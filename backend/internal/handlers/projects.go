@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
-	"freelance-platform/internal/database"
+	"freelance-platform/internal/ledger"
 	"freelance-platform/internal/models"
+	"freelance-platform/internal/repository"
+	"freelance-platform/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ProjectRequest struct {
@@ -30,81 +35,78 @@ type BidRequest struct {
 	Timeline  string `json:"timeline" binding:"required"`
 }
 
-func GetProjects(c *gin.Context) {
-	var projects []models.Project
-	
-	query := database.DB.Preload("Client").Preload("Freelancer").Preload("Bids")
-	
+// ProjectHandler serves the project and bidding endpoints
+type ProjectHandler struct {
+	projects *services.ProjectService
+}
+
+func NewProjectHandler(projects *services.ProjectService) *ProjectHandler {
+	return &ProjectHandler{projects: projects}
+}
+
+func (h *ProjectHandler) GetProjects(c *gin.Context) {
 	// If requesting own projects, don't filter by status
-	if c.Query("my_projects") != "true" {
-		query = query.Where("status = ?", "open")
-	} else {
-		// For own projects, exclude deleted ones
-		query = query.Where("status != ?", "deleted")
-	}
+	filter := repository.ProjectFilter{IncludeClosed: c.Query("my_projects") == "true"}
 	
 	// Add filters for Taiwan market
-	if category := c.Query("category"); category != "" && category != "全部類別" {
-		query = query.Where("category = ?", category)
+	if category := c.Query("category"); category != "全部類別" {
+		filter.Category = category
 	}
 	
-	if location := c.Query("location"); location != "" && location != "全部地點" {
-		query = query.Where("location = ?", location)
+	if location := c.Query("location"); location != "全部地點" {
+		filter.Location = location
 	}
 	
-	if urgency := c.Query("urgency"); urgency != "" {
-		query = query.Where("urgency = ?", urgency)
-	}
+	filter.Urgency = c.Query("urgency")
 	
 	// Budget range filter
-	if minBudget := c.Query("min_budget"); minBudget != "" {
-		if min, err := strconv.Atoi(minBudget); err == nil {
-			query = query.Where("budget_max >= ?", min)
-		}
+	if min, err := strconv.Atoi(c.Query("min_budget")); err == nil {
+		filter.MinBudget = &min
 	}
 	
-	if maxBudget := c.Query("max_budget"); maxBudget != "" {
-		if max, err := strconv.Atoi(maxBudget); err == nil {
-			query = query.Where("budget_min <= ?", max)
-		}
+	if max, err := strconv.Atoi(c.Query("max_budget")); err == nil {
+		filter.MaxBudget = &max
 	}
 	
 	// Search functionality
-	if search := c.Query("search"); search != "" {
-		searchTerm := "%" + strings.ToLower(search) + "%"
-		query = query.Where("LOWER(title) LIKE ? OR LOWER(description) LIKE ? OR LOWER(skills) LIKE ?", 
-			searchTerm, searchTerm, searchTerm)
-	}
+	filter.Search = c.Query("search")
 	
 	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	page, ok := pageQuery(c)
+	if !ok {
+		return
+	}
 	
-	// Order by most recent first
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&projects).Error; err != nil {
+	projects, meta, err := h.projects.List(c.Request.Context(), filter, page)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
 		return
 	}
 	
-	c.JSON(http.StatusOK, gin.H{"projects": projects})
+	response := gin.H{"projects": projects, "pagination": meta}
+	
+	// Counts per filter value for the sidebar, on request
+	if c.Query("facets") == "true" {
+		facets, err := h.projects.Facets(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count projects"})
+			return
+		}
+		response["facets"] = facets
+	}
+	
+	c.JSON(http.StatusOK, response)
 }
 
-func GetProject(c *gin.Context) {
+func (h *ProjectHandler) GetProject(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
 		return
 	}
 	
-	var project models.Project
-	if err := database.DB.Preload("Client").Preload("Freelancer").Preload("Bids.Freelancer").First(&project, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
-		return
-	}
-	
-	// Check if project is deleted
-	if project.Status == "deleted" {
+	project, err := h.projects.Get(c.Request.Context(), uint(id))
+	if errors.Is(err, services.ErrProjectDeleted) {
 		c.JSON(http.StatusGone, gin.H{
 			"error":   "Project has been deleted",
 			"message": "案件已被刪除",
@@ -112,11 +114,15 @@ func GetProject(c *gin.Context) {
 		})
 		return
 	}
+	if err != nil {
+		respondProjectError(c, err, "Failed to fetch project")
+		return
+	}
 	
 	c.JSON(http.StatusOK, gin.H{"project": project})
 }
 
-func CreateProject(c *gin.Context) {
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	var req ProjectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -131,43 +137,16 @@ func CreateProject(c *gin.Context) {
 	
 	currentUser := user.(models.User)
 	
-	// Validate budget range
-	if req.BudgetMin >= req.BudgetMax {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Budget minimum must be less than maximum"})
-		return
-	}
-	
-	project := models.Project{
-		Title:        req.Title,
-		Description:  req.Description,
-		BudgetMin:    req.BudgetMin,
-		BudgetMax:    req.BudgetMax,
-		Currency:     "TWD",
-		Category:     req.Category,
-		Location:     req.Location,
-		Skills:       req.Skills,
-		Requirements: req.Requirements,
-		Urgency:      req.Urgency,
-		ClientID:     currentUser.ID,
-		Status:       "open",
-	}
-	
-	if project.Urgency == "" {
-		project.Urgency = "一般"
-	}
-	
-	if err := database.DB.Create(&project).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+	project, err := h.projects.Create(c.Request.Context(), currentUser, services.ProjectInput(req))
+	if err != nil {
+		respondProjectError(c, err, "Failed to create project")
 		return
 	}
 	
-	// Load the client relationship
-	database.DB.Preload("Client").First(&project, project.ID)
-	
 	c.JSON(http.StatusCreated, gin.H{"project": project})
 }
 
-func UpdateProject(c *gin.Context) {
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
@@ -188,50 +167,16 @@ func UpdateProject(c *gin.Context) {
 	
 	currentUser := user.(models.User)
 	
-	var project models.Project
-	if err := database.DB.First(&project, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
-		return
-	}
-	
-	// Check if user owns the project
-	if project.ClientID != currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own projects"})
-		return
-	}
-	
-	// Validate budget range
-	if req.BudgetMin >= req.BudgetMax {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Budget minimum must be less than maximum"})
-		return
-	}
-	
-	// Update project
-	project.Title = req.Title
-	project.Description = req.Description
-	project.BudgetMin = req.BudgetMin
-	project.BudgetMax = req.BudgetMax
-	project.Currency = "TWD"
-	project.Category = req.Category
-	project.Location = req.Location
-	project.Skills = req.Skills
-	project.Requirements = req.Requirements
-	if req.Urgency != "" {
-		project.Urgency = req.Urgency
-	}
-	
-	if err := database.DB.Save(&project).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+	project, err := h.projects.Update(c.Request.Context(), currentUser, uint(id), services.ProjectInput(req))
+	if err != nil {
+		respondProjectError(c, err, "Failed to update project")
 		return
 	}
 	
-	// Load relationships
-	database.DB.Preload("Client").Preload("Freelancer").First(&project, project.ID)
-	
 	c.JSON(http.StatusOK, gin.H{"project": project})
 }
 
-func DeleteProject(c *gin.Context) {
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
@@ -246,51 +191,19 @@ func DeleteProject(c *gin.Context) {
 	
 	currentUser := user.(models.User)
 	
-	var project models.Project
-	if err := database.DB.First(&project, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
-		return
-	}
-	
-	// Check if user owns the project
-	if project.ClientID != currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own projects"})
-		return
-	}
-	
-	// Check if project is already deleted
-	if project.Status == "deleted" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project is already deleted"})
-		return
-	}
-	
-	// Find all chats related to this project
-	var chats []models.Chat
-	if err := database.DB.Where("project_id = ?", project.ID).Find(&chats).Error; err == nil {
-		// Add system message to each chat before marking project as deleted
-		for _, chat := range chats {
-			systemMessage := models.Message{
-				ChatID:   chat.ID,
-				SenderID: currentUser.ID, // Use the project owner as sender
-				Content:  "此案件已被發案者刪除。",
-				Type:     "system",
-			}
-			database.DB.Create(&systemMessage)
-		}
-	}
-	
-	// Mark project as deleted instead of hard deleting
-	project.Status = "deleted"
-	if err := database.DB.Save(&project).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+	before, project, err := h.projects.Delete(c.Request.Context(), currentUser, uint(id))
+	if err != nil {
+		respondProjectError(c, err, "Failed to delete project")
 		return
 	}
+
+	recordAudit(c, "project.delete", "project", project.ID, before, project)
 	
 	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
 }
 
 // Bidding functionality
-func CreateBid(c *gin.Context) {
+func (h *ProjectHandler) CreateBid(c *gin.Context) {
 	var req BidRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -305,64 +218,16 @@ func CreateBid(c *gin.Context) {
 	
 	currentUser := user.(models.User)
 	
-	// Check if user is a freelancer
-	if currentUser.Role != "freelancer" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only freelancers can place bids"})
-		return
-	}
-	
-	// Check if project exists and is open
-	var project models.Project
-	if err := database.DB.First(&project, req.ProjectID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
-		return
-	}
-	
-	if project.Status != "open" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project is not open for bidding"})
-		return
-	}
-	
-	// Check if user is not the project owner
-	if project.ClientID == currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot bid on your own project"})
-		return
-	}
-	
-	// Check if user has already bid on this project
-	var existingBid models.Bid
-	if err := database.DB.Where("project_id = ? AND freelancer_id = ?", req.ProjectID, currentUser.ID).First(&existingBid).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "You have already placed a bid on this project"})
-		return
-	}
-	
-	// Validate bid amount is within project budget range
-	if req.Amount < project.BudgetMin || req.Amount > project.BudgetMax {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bid amount must be within project budget range"})
-		return
-	}
-	
-	bid := models.Bid{
-		ProjectID:    req.ProjectID,
-		FreelancerID: currentUser.ID,
-		Amount:       req.Amount,
-		Proposal:     req.Proposal,
-		Timeline:     req.Timeline,
-		Status:       "pending",
-	}
-	
-	if err := database.DB.Create(&bid).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create bid"})
+	bid, err := h.projects.PlaceBid(c.Request.Context(), currentUser, services.BidInput(req))
+	if err != nil {
+		respondProjectError(c, err, "Failed to create bid")
 		return
 	}
 	
-	// Load relationships
-	database.DB.Preload("Project").Preload("Freelancer").First(&bid, bid.ID)
-	
 	c.JSON(http.StatusCreated, gin.H{"bid": bid})
 }
 
-func GetProjectBids(c *gin.Context) {
+func (h *ProjectHandler) GetProjectBids(c *gin.Context) {
 	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
@@ -377,29 +242,23 @@ func GetProjectBids(c *gin.Context) {
 	
 	currentUser := user.(models.User)
 	
-	// Check if user owns the project
-	var project models.Project
-	if err := database.DB.First(&project, projectID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
-		return
-	}
-	
-	if project.ClientID != currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view bids for your own projects"})
+	page, ok := pageQuery(c)
+	if !ok {
 		return
 	}
 	
-	var bids []models.Bid
-	if err := database.DB.Preload("Freelancer").Where("project_id = ?", projectID).Order("created_at DESC").Find(&bids).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bids"})
+	// Optional status filter: pending, accepted, rejected, withdrawn, expired
+	bids, meta, err := h.projects.ListBids(c.Request.Context(), currentUser, uint(projectID), c.Query("status"), page)
+	if err != nil {
+		respondProjectError(c, err, "Failed to fetch bids")
 		return
 	}
 	
-	c.JSON(http.StatusOK, gin.H{"bids": bids})
+	c.JSON(http.StatusOK, gin.H{"bids": bids, "pagination": meta})
 }
 
 // UpdateProjectStatus allows clients to update their project status (e.g., close project)
-func UpdateProjectStatus(c *gin.Context) {
+func (h *ProjectHandler) UpdateProjectStatus(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
@@ -422,42 +281,93 @@ func UpdateProjectStatus(c *gin.Context) {
 
 	currentUser := user.(models.User)
 
-	var project models.Project
-	if err := database.DB.First(&project, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	before, project, err := h.projects.UpdateStatus(c.Request.Context(), currentUser, uint(id), req.Status)
+	if err != nil {
+		respondProjectError(c, err, "Failed to update project status")
 		return
 	}
 
-	// Check if user owns the project
-	if project.ClientID != currentUser.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own projects"})
-		return
-	}
+	recordAudit(c, "project.status", "project", project.ID, before, project)
 
-	// Validate status
-	validStatuses := []string{"open", "in_progress", "completed", "cancelled"}
-	isValidStatus := false
-	for _, validStatus := range validStatuses {
-		if req.Status == validStatus {
-			isValidStatus = true
-			break
-		}
-	}
+	c.JSON(http.StatusOK, gin.H{"project": project})
+}
 
-	if !isValidStatus {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid statuses are: open, in_progress, completed, cancelled"})
-		return
+// respondProjectError maps project service errors to responses, using fallback for unexpected failures
+func respondProjectError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, services.ErrNotProjectOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only manage your own projects"})
+	case errors.Is(err, services.ErrInvalidBudget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Budget minimum must be less than maximum"})
+	case errors.Is(err, services.ErrProjectAlreadyDeleted):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project is already deleted"})
+	case errors.Is(err, services.ErrInvalidProjectStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid statuses are: " + strings.Join(services.ValidProjectStatuses, ", ")})
+	case errors.Is(err, services.ErrProjectUnderContract):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This project completes automatically once all contract milestones are approved"})
+	case errors.Is(err, services.ErrNotFreelancer):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only freelancers can place bids"})
+	case errors.Is(err, services.ErrOwnProject):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot bid on your own project"})
+	case errors.Is(err, services.ErrDuplicateBid):
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already placed a bid on this project"})
+	case errors.Is(err, services.ErrProjectNotBidding):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project is not open for bidding"})
+	case errors.Is(err, services.ErrBidOutOfBudget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bid amount must be within project budget range"})
+	case errors.Is(err, services.ErrInvalidBidStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid statuses are: " + strings.Join(services.ValidBidStatuses, ", ")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
 	}
+}
 
-	// Update project status
-	project.Status = req.Status
-	if err := database.DB.Save(&project).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project status"})
-		return
+// ApplyProjectStatus changes a project's status inside tx, keeping the completion
+// timestamp, the participants' completed project counts and any contract in sync
+func ApplyProjectStatus(tx *gorm.DB, project *models.Project, status string) error {
+	wasCompleted := project.Status == "completed"
+
+	updates := map[string]interface{}{"status": status}
+	if status == "completed" && !wasCompleted {
+		updates["completed_at"] = time.Now()
+	}
+	if err := tx.Model(project).Updates(updates).Error; err != nil {
+		return err
 	}
 
-	// Load relationships
-	database.DB.Preload("Client").Preload("Freelancer").First(&project, project.ID)
+	// Cancelling or deleting the project also cancels its running contract and refunds any escrowed milestones
+	if status == "cancelled" || status == "deleted" {
+		var contract models.Contract
+		if err := tx.Where("project_id = ? AND status = ?", project.ID, "active").First(&contract).Error; err == nil {
+			if err := tx.Model(&contract).Update("status", "cancelled").Error; err != nil {
+				return err
+			}
 
-	c.JSON(http.StatusOK, gin.H{"project": project})
-} 
\ No newline at end of file
+			var funded []models.Milestone
+			if err := tx.Where("contract_id = ? AND escrow_status = ?", contract.ID, "funded").Find(&funded).Error; err != nil {
+				return err
+			}
+			for i := range funded {
+				if _, err := ledger.RefundMilestone(tx, &funded[i]); err != nil {
+					return err
+				}
+				if err := refundMilestonePayment(tx, funded[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if wasCompleted == (status == "completed") {
+		return nil
+	}
+	if err := recomputeUserStats(tx, project.ClientID); err != nil {
+		return err
+	}
+	if project.FreelancerID != nil {
+		return recomputeUserStats(tx, *project.FreelancerID)
+	}
+	return nil
+}
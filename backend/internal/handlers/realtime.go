@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/presence"
+	"freelance-platform/internal/realtime"
+	"freelance-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TypingEvent is sent by a client while its user is composing a message
+type TypingEvent struct {
+	ChatID uint `json:"chat_id"`
+	Typing bool `json:"typing"`
+}
+
+// ChatWebSocket upgrades the connection and streams chat events for the current user
+func ChatWebSocket(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	if realtime.DefaultHub == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Realtime service unavailable"})
+		return
+	}
+
+	// The upgrader writes its own error response on failure
+	if err := realtime.DefaultHub.ServeWS(c.Writer, c.Request, currentUser.ID); err != nil {
+		log.Printf("WebSocket upgrade failed for user %d: %v", currentUser.ID, err)
+	}
+}
+
+// RealtimeHooks wires websocket connections to presence tracking and typing indicators
+func RealtimeHooks() realtime.Hooks {
+	return realtime.Hooks{
+		OnConnect: func(userID uint, connID string) {
+			touchPresence(userID, connID)
+			publishPresence(userID)
+		},
+		OnHeartbeat: touchPresence,
+		OnDisconnect: func(userID uint, connID string) {
+			if presence.DefaultStore == nil {
+				return
+			}
+			if err := presence.DefaultStore.Remove(context.Background(), userID, connID); err != nil {
+				log.Printf("Failed to remove presence for user %d: %v", userID, err)
+			}
+			publishPresence(userID)
+		},
+		OnEvent: handleClientEvent,
+	}
+}
+
+// handleClientEvent relays ephemeral client events; nothing here is persisted
+func handleClientEvent(userID uint, event realtime.Event) {
+	switch event.Type {
+	case realtime.EventTyping:
+		var typing TypingEvent
+		if err := json.Unmarshal(event.Data, &typing); err != nil || typing.ChatID == 0 {
+			return
+		}
+
+		// Only participants may signal typing in a chat
+		var chat models.Chat
+		if err := database.DB.First(&chat, typing.ChatID).Error; err != nil {
+			return
+		}
+		if chat.ClientID != userID && chat.FreelancerID != userID {
+			return
+		}
+
+		realtime.Publish([]uint{services.OtherParticipantID(chat, userID)}, realtime.EventTyping, gin.H{
+			"chat_id": chat.ID,
+			"user_id": userID,
+			"typing":  typing.Typing,
+		})
+	}
+}
+
+func touchPresence(userID uint, connID string) {
+	if presence.DefaultStore == nil {
+		return
+	}
+	if err := presence.DefaultStore.Touch(context.Background(), userID, connID, presence.TTL()); err != nil {
+		log.Printf("Failed to update presence for user %d: %v", userID, err)
+	}
+}
+
+// publishPresence tells everyone the user shares a chat with about the user's current presence
+func publishPresence(userID uint) {
+	if presence.DefaultStore == nil {
+		return
+	}
+
+	statuses, err := presence.DefaultStore.Statuses(context.Background(), []uint{userID})
+	if err != nil {
+		log.Printf("Failed to read presence for user %d: %v", userID, err)
+		return
+	}
+
+	var chats []models.Chat
+	if err := database.DB.Where("client_id = ? OR freelancer_id = ?", userID, userID).Find(&chats).Error; err != nil {
+		return
+	}
+
+	seen := make(map[uint]bool)
+	var recipients []uint
+	for _, chat := range chats {
+		other := services.OtherParticipantID(chat, userID)
+		if !seen[other] {
+			seen[other] = true
+			recipients = append(recipients, other)
+		}
+	}
+
+	if len(recipients) > 0 {
+		realtime.Publish(recipients, realtime.EventPresence, statuses[userID])
+	}
+}
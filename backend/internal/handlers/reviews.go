@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReviewRequest struct {
+	Communication int    `json:"communication" binding:"required,min=1,max=5"`
+	Quality       int    `json:"quality" binding:"required,min=1,max=5"`
+	Timeliness    int    `json:"timeliness" binding:"required,min=1,max=5"`
+	Comment       string `json:"comment"`
+}
+
+var (
+	errReviewNotAllowed  = errors.New("not a project participant")
+	errReviewNotComplete = errors.New("project is not completed")
+	errReviewClosed      = errors.New("review window closed")
+	errReviewDuplicate   = errors.New("already reviewed")
+)
+
+// CreateReview lets the client and the hired freelancer review each other once a project is completed
+func CreateReview(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var review models.Review
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the project so both sides submitting at once are serialized
+		var project models.Project
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+			return gorm.ErrRecordNotFound
+		}
+
+		if project.FreelancerID == nil || (project.ClientID != currentUser.ID && *project.FreelancerID != currentUser.ID) {
+			return errReviewNotAllowed
+		}
+
+		if project.Status != "completed" || project.CompletedAt == nil {
+			return errReviewNotComplete
+		}
+
+		if time.Now().After(project.CompletedAt.Add(reviewWindow())) {
+			return errReviewClosed
+		}
+
+		revieweeID := project.ClientID
+		if currentUser.ID == project.ClientID {
+			revieweeID = *project.FreelancerID
+		}
+
+		var existing int64
+		tx.Model(&models.Review{}).Where("project_id = ? AND reviewer_id = ?", project.ID, currentUser.ID).Count(&existing)
+		if existing > 0 {
+			return errReviewDuplicate
+		}
+
+		review = models.Review{
+			ProjectID:     project.ID,
+			ReviewerID:    currentUser.ID,
+			RevieweeID:    revieweeID,
+			Communication: req.Communication,
+			Quality:       req.Quality,
+			Timeliness:    req.Timeliness,
+			Overall:       float64(req.Communication+req.Quality+req.Timeliness) / 3,
+			Comment:       req.Comment,
+		}
+		if err := tx.Create(&review).Error; err != nil {
+			return err
+		}
+
+		// Once both sides have reviewed, reveal both reviews
+		var counterpart models.Review
+		if err := tx.Where("project_id = ? AND reviewer_id = ?", project.ID, revieweeID).First(&counterpart).Error; err == nil {
+			return publishReviews(tx, []models.Review{review, counterpart})
+		}
+
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		case errors.Is(err, errReviewNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the client and the hired freelancer can review this project"})
+		case errors.Is(err, errReviewNotComplete):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reviews can only be submitted for completed projects"})
+		case errors.Is(err, errReviewClosed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The review window for this project has closed"})
+		case errors.Is(err, errReviewDuplicate):
+			c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this project"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		}
+		return
+	}
+
+	// Reload to pick up published_at
+	database.DB.First(&review, review.ID)
+
+	c.JSON(http.StatusCreated, gin.H{"review": review})
+}
+
+// GetProjectReviews returns the published reviews of a project, plus the caller's own hidden one
+func GetProjectReviews(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var reviews []models.Review
+	if err := database.DB.Preload("Reviewer").
+		Where("project_id = ? AND (reviewer_id = ? OR "+reviewVisible+")", projectID, currentUser.ID, reviewWindowClosedBefore()).
+		Order("created_at ASC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+// GetUserReviews returns the published reviews a user has received with per-dimension averages
+func GetUserReviews(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var reviews []models.Review
+	if err := database.DB.Preload("Reviewer").Preload("Project").
+		Where("reviewee_id = ? AND "+reviewVisible, user.ID, reviewWindowClosedBefore()).
+		Order("published_at DESC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	var averages struct {
+		Communication float64 `json:"communication"`
+		Quality       float64 `json:"quality"`
+		Timeliness    float64 `json:"timeliness"`
+	}
+	database.DB.Model(&models.Review{}).
+		Select("COALESCE(AVG(communication), 0) AS communication, COALESCE(AVG(quality), 0) AS quality, COALESCE(AVG(timeliness), 0) AS timeliness").
+		Where("reviewee_id = ? AND "+reviewVisible, user.ID, reviewWindowClosedBefore()).
+		Scan(&averages)
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews":            reviews,
+		"rating":             user.Rating,
+		"completed_projects": user.CompletedProjects,
+		"averages":           averages,
+	})
+}
+
+// reviewVisible matches reviews that are published, or whose review window closed
+// before the bound time and are only waiting for the worker to publish them
+const reviewVisible = "(reviews.published_at IS NOT NULL OR EXISTS (SELECT 1 FROM projects WHERE projects.id = reviews.project_id AND projects.completed_at <= ?))"
+
+// reviewWindowClosedBefore is the completion time before which a project's reviews are due
+func reviewWindowClosedBefore() time.Time {
+	return time.Now().Add(-reviewWindow())
+}
+
+// PublishDueReviews reveals reviews whose project review window has closed without a counterpart review
+func PublishDueReviews() (int64, error) {
+	var published int64
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var reviews []models.Review
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Joins("JOIN projects ON projects.id = reviews.project_id").
+			Where("reviews.published_at IS NULL AND projects.completed_at <= ?", reviewWindowClosedBefore()).
+			Find(&reviews).Error; err != nil {
+			return err
+		}
+
+		published = int64(len(reviews))
+		if len(reviews) == 0 {
+			return nil
+		}
+		return publishReviews(tx, reviews)
+	})
+
+	return published, err
+}
+
+// publishReviews marks reviews as visible and recomputes the ratings of everyone they affect
+func publishReviews(tx *gorm.DB, reviews []models.Review) error {
+	now := time.Now()
+	ids := make([]uint, 0, len(reviews))
+	reviewees := make(map[uint]bool)
+	for _, review := range reviews {
+		ids = append(ids, review.ID)
+		reviewees[review.RevieweeID] = true
+	}
+
+	if err := tx.Model(&models.Review{}).Where("id IN ?", ids).Update("published_at", now).Error; err != nil {
+		return err
+	}
+
+	for userID := range reviewees {
+		if err := recomputeUserStats(tx, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recomputeUserStats recalculates a user's aggregate rating and completed project count inside tx
+func recomputeUserStats(tx *gorm.DB, userID uint) error {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	var rating float64
+	if err := tx.Model(&models.Review{}).
+		Select("COALESCE(AVG(overall), 0)").
+		Where("reviewee_id = ? AND published_at IS NOT NULL", userID).
+		Scan(&rating).Error; err != nil {
+		return err
+	}
+
+	var completed int64
+	if err := tx.Model(&models.Project{}).
+		Where("status = ? AND (client_id = ? OR freelancer_id = ?)", "completed", userID, userID).
+		Count(&completed).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&user).Updates(map[string]interface{}{
+		"rating":             rating,
+		"completed_projects": completed,
+	}).Error
+}
+
+// reviewWindow returns how long after completion reviews can be submitted, configured via REVIEW_WINDOW_DAYS
+func reviewWindow() time.Duration {
+	days := 14
+	if value := os.Getenv("REVIEW_WINDOW_DAYS"); value != "" {
+		if d, err := strconv.Atoi(value); err == nil && d > 0 {
+			days = d
+		}
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
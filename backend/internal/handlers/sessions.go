@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"freelance-platform/internal/auth"
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errRefreshTokenInvalid = errors.New("invalid refresh token")
+	errRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// issueSession starts a new refresh token family for the user and returns the token pair
+func issueSession(c *gin.Context, userID uint) (gin.H, error) {
+	familyID := auth.RandomID()
+
+	refreshToken, hash := auth.NewRefreshToken()
+	record := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(auth.RefreshTTL()),
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return sessionTokens(userID, familyID, refreshToken)
+}
+
+func sessionTokens(userID uint, familyID, refreshToken string) (gin.H, error) {
+	accessToken, _, err := auth.IssueAccessToken(userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(auth.AccessTTL().Seconds()),
+	}, nil
+}
+
+func RefreshSession(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var current models.RefreshToken
+	reused := false
+	newToken, newHash := auth.NewRefreshToken()
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", auth.HashToken(req.RefreshToken)).
+			First(&current).Error; err != nil {
+			return errRefreshTokenInvalid
+		}
+
+		// A rotated token being presented again means it leaked; the reuse is
+		// detected here and the whole family is revoked below
+		if current.RevokedAt != nil {
+			reused = true
+			return nil
+		}
+
+		if time.Now().After(current.ExpiresAt) {
+			return errRefreshTokenInvalid
+		}
+
+		next := models.RefreshToken{
+			UserID:    current.UserID,
+			FamilyID:  current.FamilyID,
+			TokenHash: newHash,
+			ExpiresAt: time.Now().Add(auth.RefreshTTL()),
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		}
+		if err := tx.Create(&next).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&current).Updates(map[string]interface{}{
+			"revoked_at":     now,
+			"replaced_by_id": next.ID,
+		}).Error
+	})
+
+	if err == nil && reused {
+		if err = revokeFamilies(current.UserID, []string{current.FamilyID}); err == nil {
+			recordAuditAs(c, nil, "session.reuse_revoke", "user", current.UserID, gin.H{"revoked_sessions": 0}, gin.H{"revoked_sessions": 1})
+			err = errRefreshTokenReused
+		}
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errRefreshTokenInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	case errors.Is(err, errRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, session revoked"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	tokens, err := sessionTokens(current.UserID, current.FamilyID, newToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func Logout(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	if err := revokeFamilies(currentUser.ID, []string{c.GetString("session_id")}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	auth.Revoke(c.Request.Context(), c.GetString("token_id"))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every session of the current user, on every device
+func LogoutAll(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	revoked, err := revokeAllSessions(currentUser.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	recordAudit(c, "session.revoke_all", "user", currentUser.ID, gin.H{"revoked_sessions": 0}, gin.H{"revoked_sessions": revoked})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all devices"})
+}
+
+// revokeAllSessions revokes every refresh token family the user still has open
+// and returns how many there were
+func revokeAllSessions(userID uint) (int, error) {
+	var familyIDs []string
+	if err := database.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Distinct().Pluck("family_id", &familyIDs).Error; err != nil {
+		return 0, err
+	}
+
+	return len(familyIDs), revokeFamilies(userID, familyIDs)
+}
+
+// revokeFamilies revokes all refresh tokens of the given families and denylists
+// their session IDs so outstanding access tokens stop working immediately
+func revokeFamilies(userID uint, familyIDs []string) error {
+	if len(familyIDs) == 0 {
+		return nil
+	}
+
+	if err := database.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND family_id IN ? AND revoked_at IS NULL", userID, familyIDs).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	auth.Revoke(context.Background(), familyIDs...)
+	return nil
+}
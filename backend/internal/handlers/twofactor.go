@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"freelance-platform/internal/auth"
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	recoveryCodeCount = 10
+
+	// After maxSecondFactorFailures wrong codes in a row, TOTP or recovery,
+	// no code is accepted for secondFactorLockout and the MFA token is burned
+	maxSecondFactorFailures = 5
+	secondFactorLockout     = 15 * time.Minute
+)
+
+var (
+	errInvalidSecondFactor   = errors.New("invalid two-factor code")
+	errSecondFactorLocked    = errors.New("too many invalid two-factor codes")
+	errTwoFactorNotPending   = errors.New("two-factor setup not started")
+	errTwoFactorNotEnabled   = errors.New("two-factor authentication not enabled")
+	errTwoFactorEnabled      = errors.New("two-factor authentication already enabled")
+	errReauthInvalidPassword = errors.New("invalid password")
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+// ReauthRequest asks for the password and a current second factor before
+// changing two-factor settings
+type ReauthRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+func GetTwoFactorStatus(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var remaining int64
+	database.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", currentUser.ID).
+		Count(&remaining)
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  currentUser.TwoFactorEnabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// SetupTwoFactor starts enrollment by provisioning a new secret. 2FA is not
+// enforced until the user proves their app works via ConfirmTwoFactor.
+func SetupTwoFactor(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	if currentUser.TwoFactorEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret := auth.NewTOTPSecret()
+	if err := database.DB.Model(&currentUser).Update("totp_pending_secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor setup"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPProvisioningURI(totpIssuer(), currentUser.Email, secret),
+	})
+}
+
+func ConfirmTwoFactor(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, currentUser.ID).Error; err != nil {
+			return err
+		}
+		if locked.TwoFactorEnabled {
+			return errTwoFactorEnabled
+		}
+		if locked.TOTPPendingSecret == "" {
+			return errTwoFactorNotPending
+		}
+
+		step, ok := auth.ValidateTOTP(locked.TOTPPendingSecret, req.Code, time.Now())
+		if !ok {
+			return errInvalidSecondFactor
+		}
+
+		if err := tx.Model(&locked).Updates(map[string]interface{}{
+			"two_factor_enabled":  true,
+			"totp_secret":         locked.TOTPPendingSecret,
+			"totp_pending_secret": "",
+			"totp_last_step":      step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, locked.ID)
+		return err
+	})
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	recordAudit(c, "user.2fa_enable", "user", currentUser.ID, gin.H{"two_factor_enabled": false}, gin.H{"two_factor_enabled": true})
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":        true,
+		"recovery_codes": codes,
+	})
+}
+
+func DisableTwoFactor(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var req ReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := reauthenticate(currentUser.ID, req, func(tx *gorm.DB, user *models.User) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", currentUser.ID).Updates(map[string]interface{}{
+			"two_factor_enabled":  false,
+			"totp_secret":         "",
+			"totp_pending_secret": "",
+			"totp_last_step":      0,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", currentUser.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	recordAudit(c, "user.2fa_disable", "user", currentUser.ID, gin.H{"two_factor_enabled": true}, gin.H{"two_factor_enabled": false})
+
+	c.JSON(http.StatusOK, gin.H{"enabled": false})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, e.g. after most were used up
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	var req ReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var codes []string
+	err := reauthenticate(currentUser.ID, req, func(tx *gorm.DB, user *models.User) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, currentUser.ID)
+		return err
+	})
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// LoginTwoFactor completes a login that was paused for the second factor
+func LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, ok := auth.ParseMFAToken(req.MFAToken)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	// Each MFA token completes at most one login
+	if auth.DefaultDenylist != nil {
+		if used, err := auth.DefaultDenylist.Contains(c.Request.Context(), claims.TokenID); err != nil || used {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+			return
+		}
+	}
+
+	var user models.User
+	err := withSecondFactor(claims.UserID, req.Code, func(tx *gorm.DB, verified *models.User) error {
+		user = *verified
+		return nil
+	})
+	if errors.Is(err, errSecondFactorLocked) {
+		// Guessing has to start over from the password
+		auth.Revoke(context.Background(), claims.TokenID)
+	}
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	auth.Revoke(context.Background(), claims.TokenID)
+
+	// The account may have been moderated since the password step
+	if err := auth.CheckAccountStatus(user); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := issueSession(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	session["user"] = user
+	c.JSON(http.StatusOK, session)
+}
+
+// reauthenticate checks the password and a current second factor and then
+// runs fn like withSecondFactor
+func reauthenticate(userID uint, req ReauthRequest, fn func(tx *gorm.DB, user *models.User) error) error {
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return errTwoFactorNotEnabled
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return errReauthInvalidPassword
+	}
+
+	return withSecondFactor(userID, req.Code, fn)
+}
+
+// withSecondFactor locks the user, verifies code and runs fn in the same
+// transaction. A wrong code is counted, and the count committed, before
+// errInvalidSecondFactor or errSecondFactorLocked is returned.
+func withSecondFactor(userID uint, code string, fn func(tx *gorm.DB, user *models.User) error) error {
+	var failed error
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.TwoFactorEnabled {
+			return errTwoFactorNotEnabled
+		}
+
+		err := verifySecondFactor(tx, &user, code)
+		if errors.Is(err, errInvalidSecondFactor) {
+			failed = errInvalidSecondFactor
+			locked, err := recordSecondFactorFailure(tx, &user)
+			if locked {
+				failed = errSecondFactorLocked
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		return fn(tx, &user)
+	})
+	if err != nil {
+		return err
+	}
+	return failed
+}
+
+// verifySecondFactor accepts either a TOTP code newer than the last one used or
+// an unused recovery code, which is burned. The user row must be locked.
+func verifySecondFactor(tx *gorm.DB, user *models.User, code string) error {
+	if user.SecondFactorLockedUntil != nil && user.SecondFactorLockedUntil.After(time.Now()) {
+		return errSecondFactorLocked
+	}
+
+	if step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok && step > user.TOTPLastStep {
+		user.TOTPLastStep = step
+		return tx.Model(user).Updates(map[string]interface{}{
+			"totp_last_step":         step,
+			"second_factor_failures": 0,
+		}).Error
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, auth.HashToken(auth.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInvalidSecondFactor
+	}
+	return tx.Model(user).Update("second_factor_failures", 0).Error
+}
+
+// recordSecondFactorFailure counts a wrong code against the locked user row
+// and reports whether that started a lockout
+func recordSecondFactorFailure(tx *gorm.DB, user *models.User) (bool, error) {
+	failures := user.SecondFactorFailures + 1
+	if failures < maxSecondFactorFailures {
+		return false, tx.Model(user).Update("second_factor_failures", failures).Error
+	}
+
+	return true, tx.Model(user).Updates(map[string]interface{}{
+		"second_factor_failures":     0,
+		"second_factor_locked_until": time.Now().Add(secondFactorLockout),
+	}).Error
+}
+
+// replaceRecoveryCodes discards existing recovery codes and returns a fresh set in plain text
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := auth.NewRecoveryCodes(recoveryCodeCount)
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: auth.HashToken(code)}
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errInvalidSecondFactor):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+	case errors.Is(err, errSecondFactorLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many invalid two-factor codes, try again later"})
+	case errors.Is(err, errReauthInvalidPassword):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+	case errors.Is(err, errTwoFactorNotPending):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start two-factor setup first"})
+	case errors.Is(err, errTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+	case errors.Is(err, errTwoFactorEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process two-factor request"})
+	}
+}
+
+// totpIssuer is the account label shown in authenticator apps
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Freelance Platform"
+}
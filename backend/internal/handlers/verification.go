@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"freelance-platform/internal/auth"
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/jobs"
+	"freelance-platform/internal/mailer"
+	"freelance-platform/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+// AccountEmail is the payload of the account email jobs
+type AccountEmail struct {
+	UserID uint `json:"user_id"`
+}
+
+// Account emails are sent by the worker, so a slow mail server doesn't hold up the request
+var (
+	VerificationEmailJob  = jobs.Definition[AccountEmail]{Name: "email.verify"}
+	PasswordResetEmailJob = jobs.Definition[AccountEmail]{Name: "email.reset_password"}
+)
+
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+func VerifyEmail(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		userID, err := auth.ConsumeOneTimeToken(tx, req.Token, auth.PurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		return tx.Model(&user).Update("email_verified_at", now).Error
+	})
+	if err != nil {
+		respondOneTimeTokenError(c, err, "Failed to verify email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func ResendVerificationEmail(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	if currentUser.EmailVerifiedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already verified"})
+		return
+	}
+
+	if err := queueAccountEmail(c.Request.Context(), VerificationEmailJob, currentUser); err != nil {
+		log.Printf("Failed to queue verification email to user %d: %v", currentUser.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+func ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Respond the same way whether or not the account exists
+	var user models.User
+	if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err == nil {
+		if err := queueAccountEmail(c.Request.Context(), PasswordResetEmailJob, user); err != nil {
+			log.Printf("Failed to queue password reset email to user %d: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a reset link has been sent"})
+}
+
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	var userID uint
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		userID, err = auth.ConsumeOneTimeToken(tx, req.Token, auth.PurposeResetPassword)
+		if err != nil {
+			return err
+		}
+
+		// Following the emailed link also proves ownership of the address
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"password":          string(hashedPassword),
+			"email_verified_at": gorm.Expr("COALESCE(email_verified_at, ?)", time.Now()),
+		}).Error
+	})
+	if err != nil {
+		respondOneTimeTokenError(c, err, "Failed to reset password")
+		return
+	}
+
+	// Sign out everywhere the old password was used
+	revoked, err := revokeAllSessions(userID)
+	if err != nil {
+		log.Printf("Failed to revoke sessions of user %d after password reset: %v", userID, err)
+	}
+	recordAuditAs(c, &userID, "user.password_reset", "user", userID, gin.H{"revoked_sessions": 0}, gin.H{"password_changed": true, "revoked_sessions": revoked})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+func respondOneTimeTokenError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, auth.ErrInvalidOneTimeToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
+
+// queueAccountEmail queues an account email for the user; while one is still
+// waiting, asking again doesn't queue another
+func queueAccountEmail(ctx context.Context, job jobs.Definition[AccountEmail], user models.User) error {
+	_, err := job.Enqueue(ctx, database.DB, AccountEmail{UserID: user.ID}, jobs.Options{
+		UniqueKey: fmt.Sprintf("%s:%d", job.Name, user.ID),
+	})
+	return err
+}
+
+// SendVerificationEmail runs VerificationEmailJob
+func SendVerificationEmail(ctx context.Context, payload AccountEmail) error {
+	user, err := accountEmailUser(ctx, payload)
+	if err != nil || user.EmailVerifiedAt != nil {
+		return err
+	}
+
+	token, err := auth.IssueOneTimeToken(database.DB, user.ID, auth.PurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	link := frontendLink("/verify-email", token)
+	return mailer.Send(ctx, linkEmail(user.Email, "請驗證您的電子郵件 / Verify your email",
+		fmt.Sprintf("%s 您好，\n\n請點擊以下連結驗證您的電子郵件（48 小時內有效）：", user.Name),
+		fmt.Sprintf("Hi %s, please confirm your email address (valid for 48 hours):", user.Name),
+		link))
+}
+
+// SendPasswordResetEmail runs PasswordResetEmailJob
+func SendPasswordResetEmail(ctx context.Context, payload AccountEmail) error {
+	user, err := accountEmailUser(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.IssueOneTimeToken(database.DB, user.ID, auth.PurposeResetPassword, resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+
+	link := frontendLink("/reset-password", token)
+	return mailer.Send(ctx, linkEmail(user.Email, "重設密碼 / Reset your password",
+		fmt.Sprintf("%s 您好，\n\n我們收到重設密碼的請求，請點擊以下連結設定新密碼（1 小時內有效）。若非本人操作，請忽略此信。", user.Name),
+		fmt.Sprintf("Hi %s, use the link below to choose a new password (valid for 1 hour). If you did not ask for this, ignore this email.", user.Name),
+		link))
+}
+
+// accountEmailUser loads the recipient of an account email; a user deleted
+// since the email was queued fails the job for good
+func accountEmailUser(ctx context.Context, payload AccountEmail) (models.User, error) {
+	var user models.User
+	err := database.DB.WithContext(ctx).First(&user, payload.UserID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, jobs.Permanent(err)
+	}
+	return user, err
+}
+
+// linkEmail builds a short bilingual email around a single call-to-action link
+func linkEmail(to, subject, zh, en, link string) mailer.Message {
+	text := fmt.Sprintf("%s\n%s\n\n%s\n%s\n", zh, link, en, link)
+	htmlBody := fmt.Sprintf("<p>%s</p><p>%s</p><p><a href=\"%s\">%s</a></p>",
+		strings.ReplaceAll(html.EscapeString(zh), "\n", "<br>"),
+		html.EscapeString(en),
+		html.EscapeString(link), html.EscapeString(link))
+
+	return mailer.Message{To: to, Subject: subject, Text: text, HTML: htmlBody}
+}
+
+// frontendLink points at a frontend page carrying the token as a query parameter
+func frontendLink(path, token string) string {
+	base := os.Getenv("FRONTEND_URL")
+	if base == "" {
+		base = "http://localhost:3000"
+	}
+	return strings.TrimRight(base, "/") + path + "?token=" + url.QueryEscape(token)
+}
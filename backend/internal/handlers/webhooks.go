@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookRequest struct {
+	URL    *string  `json:"url"`
+	Events []string `json:"events"` // any of bid.created, bid.accepted, project.status_changed
+	Active *bool    `json:"active"`
+}
+
+// WebhookHandler serves the endpoints integrators manage their webhooks with
+type WebhookHandler struct {
+	webhooks *services.WebhookService
+}
+
+func NewWebhookHandler(webhooks *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+// GetWebhooks lists the current user's webhooks
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	webhooks, err := h.webhooks.List(c.Request.Context(), currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+// CreateWebhook registers a webhook; the response holds its signing secret,
+// which is not shown again
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	webhook, secret, err := h.webhooks.Create(c.Request.Context(), currentUser, services.WebhookInput(req))
+	if err != nil {
+		respondWebhookError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": webhook, "secret": secret})
+}
+
+// UpdateWebhook changes the URL, subscribed events or active flag of a webhook
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	webhook, err := h.webhooks.Update(c.Request.Context(), currentUser, uint(webhookID), services.WebhookInput(req))
+	if err != nil {
+		respondWebhookError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhook": webhook})
+}
+
+// DeleteWebhook removes a webhook together with its delivery log
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	if err := h.webhooks.Delete(c.Request.Context(), currentUser, uint(webhookID)); err != nil {
+		respondWebhookError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries returns a page of a webhook's delivery log, newest
+// first; ?status= narrows it to pending, succeeded or dead deliveries
+func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	page, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	deliveries, meta, err := h.webhooks.Deliveries(c.Request.Context(), currentUser, uint(webhookID), c.Query("status"), page)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to fetch webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries, "pagination": meta})
+}
+
+// GetWebhookDelivery returns one delivery with every attempt made
+func (h *WebhookHandler) GetWebhookDelivery(c *gin.Context) {
+	webhookID, deliveryID, ok := webhookDeliveryParams(c)
+	if !ok {
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	delivery, err := h.webhooks.Delivery(c.Request.Context(), currentUser, webhookID, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to fetch webhook delivery")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delivery": delivery})
+}
+
+// RedeliverWebhook queues a delivery's event again, typically a dead one once
+// the receiving end is fixed
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	webhookID, deliveryID, ok := webhookDeliveryParams(c)
+	if !ok {
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser := user.(models.User)
+
+	delivery, err := h.webhooks.Redeliver(c.Request.Context(), currentUser, webhookID, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to redeliver webhook")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"delivery": delivery})
+}
+
+func webhookDeliveryParams(c *gin.Context) (uint, uint, bool) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return 0, 0, false
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("deliveryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return 0, 0, false
+	}
+	return uint(webhookID), uint(deliveryID), true
+}
+
+// respondWebhookError maps webhook service errors to responses, falling back
+// to a 500 with message
+func respondWebhookError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+	case errors.Is(err, services.ErrWebhookDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+	case errors.Is(err, services.ErrInvalidWebhookURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook URL must be an absolute http or https URL"})
+	case errors.Is(err, services.ErrInvalidWebhookEvents):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subscribe to at least one event. Valid events are: " + strings.Join(services.WebhookEvents, ", ")})
+	case errors.Is(err, services.ErrInvalidDeliveryStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid statuses are: " + strings.Join(services.ValidWebhookDeliveryStatuses, ", ")})
+	case errors.Is(err, services.ErrTooManyWebhooks):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook limit reached"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,136 @@
+// Package jobs is a durable background job queue kept in Postgres. Jobs are
+// enqueued as rows, possibly inside the transaction that calls for them, and
+// picked up by workers with SELECT ... FOR UPDATE SKIP LOCKED, so any number
+// of workers can share the queue without handing a job out twice.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	// DefaultTimeout bounds one run of a job; until it passes, a claimed job
+	// is invisible to other workers
+	DefaultTimeout = 5 * time.Minute
+
+	baseBackoff = 15 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// activeJob matches the rows covered by idx_jobs_unique_key_active
+const activeJob = "status IN ('queued', 'running')"
+
+// Definition names a job type and the payload its handler takes
+type Definition[T any] struct {
+	Name string
+	// MaxAttempts is how many times the job runs before it is left dead; 0 means DefaultMaxAttempts
+	MaxAttempts int
+	// Timeout is how long one run may take; 0 means DefaultTimeout
+	Timeout time.Duration
+}
+
+// Options tune a single enqueued job
+type Options struct {
+	// RunAt delays the job until then; zero runs it as soon as a worker is free
+	RunAt time.Time
+	// UniqueKey, when set, skips enqueueing while a job with the same key is queued or running
+	UniqueKey string
+}
+
+// Enqueue adds a job to the queue through db, which may be a transaction so
+// the job is only queued if the transaction commits. When a job with the same
+// unique key is already queued or running, that job is returned instead.
+func (d Definition[T]) Enqueue(ctx context.Context, db *gorm.DB, payload T, opts Options) (models.Job, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	job := models.Job{
+		Type:        d.Name,
+		Payload:     body,
+		Status:      models.JobQueued,
+		MaxAttempts: d.maxAttempts(),
+		RunAt:       opts.RunAt,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if opts.UniqueKey == "" {
+		return job, db.WithContext(ctx).Create(&job).Error
+	}
+
+	job.UniqueKey = &opts.UniqueKey
+	for try := 1; ; try++ {
+		result := db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "unique_key"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: activeJob}}},
+			DoNothing:   true,
+		}).Create(&job)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return job, result.Error
+		}
+
+		var existing models.Job
+		err := db.WithContext(ctx).Where("unique_key = ?", opts.UniqueKey).Where(activeJob).First(&existing).Error
+		// Try again if the other job finished in the meantime
+		if !errors.Is(err, gorm.ErrRecordNotFound) || try == 3 {
+			return existing, err
+		}
+	}
+}
+
+func (d Definition[T]) maxAttempts() int {
+	if d.MaxAttempts > 0 {
+		return d.MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
+
+func (d Definition[T]) timeout() time.Duration {
+	if d.Timeout > 0 {
+		return d.Timeout
+	}
+	return DefaultTimeout
+}
+
+// permanentError marks a failure retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job is left dead right away instead of retried
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Backoff is how long a job waits after the given failed attempt, counted
+// from 1: 15s doubling each time, at most an hour
+func Backoff(attempt int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// Prune deletes the jobs that finished before the given time and returns how many went
+func Prune(ctx context.Context, db *gorm.DB, before time.Time) (int64, error) {
+	result := db.WithContext(ctx).Where("finished_at < ?", before).Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
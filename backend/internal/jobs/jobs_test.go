@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  15 * time.Second,
+		2:  30 * time.Second,
+		3:  time.Minute,
+		8:  32 * time.Minute,
+		9:  time.Hour,
+		40: time.Hour,
+	}
+	for attempt, want := range cases {
+		if got := Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	cause := errors.New("no such user")
+	err := Permanent(cause)
+
+	var permanent permanentError
+	if !errors.As(err, &permanent) {
+		t.Fatal("expected Permanent to be recognised")
+	}
+	if !errors.Is(err, cause) || err.Error() != cause.Error() {
+		t.Errorf("expected Permanent to wrap %v, got %v", cause, err)
+	}
+}
+
+func TestDefinitionDefaults(t *testing.T) {
+	def := Definition[struct{}]{Name: "test"}
+	if def.maxAttempts() != DefaultMaxAttempts || def.timeout() != DefaultTimeout {
+		t.Errorf("expected the defaults, got %d attempts and %v", def.maxAttempts(), def.timeout())
+	}
+
+	def = Definition[struct{}]{Name: "test", MaxAttempts: 1, Timeout: time.Second}
+	if def.maxAttempts() != 1 || def.timeout() != time.Second {
+		t.Errorf("expected the definition's own limits, got %d attempts and %v", def.maxAttempts(), def.timeout())
+	}
+}
@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Worker runs the jobs of the types registered on it
+type Worker struct {
+	// Concurrency is how many jobs run at once
+	Concurrency int
+	// PollInterval is how long an idle worker waits before looking for due jobs again
+	PollInterval time.Duration
+
+	db        *gorm.DB
+	id        string
+	handlers  map[string]handler
+	types     []string
+	schedules []*schedule
+}
+
+type handler struct {
+	run     func(ctx context.Context, payload json.RawMessage) error
+	timeout time.Duration
+}
+
+// schedule enqueues a job once every interval
+type schedule struct {
+	name     string
+	interval time.Duration
+	enqueue  func(ctx context.Context, db *gorm.DB, runAt time.Time, uniqueKey string) error
+	last     time.Time
+}
+
+func NewWorker(db *gorm.DB) *Worker {
+	hostname, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+
+	return &Worker{
+		Concurrency:  4,
+		PollInterval: time.Second,
+		db:           db,
+		id:           fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(b)),
+		handlers:     make(map[string]handler),
+	}
+}
+
+// Handle registers fn to run the jobs of def. A job whose fn returns an error
+// or panics is retried with Backoff until it runs out of attempts, unless the
+// error is Permanent.
+func Handle[T any](w *Worker, def Definition[T], fn func(ctx context.Context, payload T) error) {
+	w.handlers[def.Name] = handler{
+		run: func(ctx context.Context, raw json.RawMessage) error {
+			var payload T
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return Permanent(fmt.Errorf("decode payload: %w", err))
+			}
+			return fn(ctx, payload)
+		},
+		timeout: def.timeout(),
+	}
+	w.types = append(w.types, def.Name)
+}
+
+// Every enqueues a def job with payload once per interval, at the start of
+// each interval counted from the Unix epoch. Workers sharing the queue agree
+// on the intervals, so the job runs once per interval however many of them
+// there are.
+func Every[T any](w *Worker, def Definition[T], interval time.Duration, payload T) {
+	w.schedules = append(w.schedules, &schedule{
+		name:     def.Name,
+		interval: interval,
+		enqueue: func(ctx context.Context, db *gorm.DB, runAt time.Time, uniqueKey string) error {
+			_, err := def.Enqueue(ctx, db, payload, Options{RunAt: runAt, UniqueKey: uniqueKey})
+			return err
+		},
+	})
+}
+
+// Run schedules and works jobs until ctx is cancelled, then waits for the
+// jobs it is running to finish
+func (w *Worker) Run(ctx context.Context) {
+	log.Printf("Worker %s running %d job types", w.id, len(w.types))
+
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Schedule(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to schedule jobs: %v", err)
+		}
+		if _, err := w.Work(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim jobs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Schedule enqueues the periodic jobs whose interval started by now and that
+// were not enqueued for it yet
+func (w *Worker) Schedule(ctx context.Context, now time.Time) error {
+	for _, s := range w.schedules {
+		start := now.Truncate(s.interval)
+		if !start.After(s.last) {
+			continue
+		}
+
+		// A job already done for this interval doesn't block the unique key, so look for it first
+		uniqueKey := fmt.Sprintf("%s@%d", s.name, start.Unix())
+		var count int64
+		if err := w.db.WithContext(ctx).Model(&models.Job{}).Where("unique_key = ?", uniqueKey).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := s.enqueue(ctx, w.db, start, uniqueKey); err != nil {
+				return err
+			}
+		}
+		s.last = start
+	}
+	return nil
+}
+
+// Work runs the jobs due at now, Concurrency at a time, until none is left or
+// ctx is cancelled, and returns how many ran. Cancelling ctx stops claiming
+// jobs but lets the ones already claimed finish.
+func (w *Worker) Work(ctx context.Context, now time.Time) (int, error) {
+	concurrency := w.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ran := 0
+	for ctx.Err() == nil {
+		claimed, err := w.claim(ctx, now, concurrency)
+		if err != nil || len(claimed) == 0 {
+			return ran, err
+		}
+
+		var wg sync.WaitGroup
+		for _, job := range claimed {
+			wg.Add(1)
+			go func(job models.Job) {
+				defer wg.Done()
+				w.run(context.WithoutCancel(ctx), job, now)
+			}(job)
+		}
+		wg.Wait()
+		ran += len(claimed)
+	}
+	return ran, nil
+}
+
+// claim locks up to limit due jobs of the registered types and marks them
+// running under this worker until their timeout. A running job whose lock
+// expired is handed out again, or left dead if that was its last attempt.
+func (w *Worker) claim(ctx context.Context, now time.Time, limit int) ([]models.Job, error) {
+	if len(w.types) == 0 {
+		return nil, nil
+	}
+
+	// Leases start from the actual time, as now may have been a while ago
+	leaseStart := time.Now()
+	if now.After(leaseStart) {
+		leaseStart = now
+	}
+
+	var claimed []models.Job
+	err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []models.Job
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("type IN ?", w.types).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until <= ?)", models.JobQueued, now, models.JobRunning, now).
+			Order("run_at, id").
+			Limit(limit).
+			Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		for _, job := range due {
+			h := w.handlers[job.Type]
+			if job.Status == models.JobRunning && job.Attempts >= job.MaxAttempts {
+				err := tx.Model(&job).Updates(map[string]interface{}{
+					"status":       models.JobDead,
+					"last_error":   "timed out",
+					"locked_by":    nil,
+					"locked_until": nil,
+					"finished_at":  now,
+				}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			lockedUntil := leaseStart.Add(h.timeout)
+			job.Status = models.JobRunning
+			job.Attempts++
+			job.LockedBy = w.id
+			job.LockedUntil = &lockedUntil
+			err := tx.Model(&job).Updates(map[string]interface{}{
+				"status":       job.Status,
+				"attempts":     job.Attempts,
+				"locked_by":    job.LockedBy,
+				"locked_until": lockedUntil,
+			}).Error
+			if err != nil {
+				return err
+			}
+			claimed = append(claimed, job)
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// run runs a claimed job and records how it went, unless the job's lock
+// expired meanwhile and another worker took it over
+func (w *Worker) run(ctx context.Context, job models.Job, now time.Time) {
+	h := w.handlers[job.Type]
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		runCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+		return h.run(runCtx, job.Payload)
+	}()
+
+	updates := map[string]interface{}{"locked_by": nil, "locked_until": nil}
+	var permanent permanentError
+	switch {
+	case err == nil:
+		updates["status"] = models.JobSucceeded
+		updates["last_error"] = ""
+		updates["finished_at"] = now
+	case job.Attempts >= job.MaxAttempts || errors.As(err, &permanent):
+		log.Printf("Job %d (%s) failed for good: %v", job.ID, job.Type, err)
+		updates["status"] = models.JobDead
+		updates["last_error"] = err.Error()
+		updates["finished_at"] = now
+	default:
+		log.Printf("Job %d (%s) failed on attempt %d: %v", job.ID, job.Type, job.Attempts, err)
+		updates["status"] = models.JobQueued
+		updates["last_error"] = err.Error()
+		updates["run_at"] = now.Add(Backoff(job.Attempts))
+	}
+
+	result := w.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ? AND attempts = ?", job.ID, models.JobRunning, w.id, job.Attempts).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("Failed to record the outcome of job %d: %v", job.ID, result.Error)
+	} else if result.RowsAffected == 0 {
+		log.Printf("Job %d (%s) outlived its timeout and was handed to another worker", job.ID, job.Type)
+	}
+}
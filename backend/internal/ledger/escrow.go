@@ -0,0 +1,201 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrEscrowState = errors.New("milestone escrow is not in the right state")
+
+// FeeBasisPoints returns the platform fee in basis points from PLATFORM_FEE_PERCENT (default 10%)
+func FeeBasisPoints() int64 {
+	percent := 10.0
+	if value := os.Getenv("PLATFORM_FEE_PERCENT"); value != "" {
+		if p, err := strconv.ParseFloat(value, 64); err == nil && p >= 0 && p <= 100 {
+			percent = p
+		}
+	}
+
+	return int64(math.Round(percent * 100))
+}
+
+// PlatformFee returns the fee kept by the platform when releasing amount
+func PlatformFee(amount int64) int64 {
+	return amount * FeeBasisPoints() / 10000
+}
+
+// FundMilestone moves the client's payment for a milestone into the contract's escrow
+func FundMilestone(tx *gorm.DB, milestone *models.Milestone) (models.JournalEntry, error) {
+	err := moveEscrow(tx, milestone, []string{"", "unfunded"}, map[string]interface{}{
+		"escrow_status": "funded",
+		"funded_at":     time.Now(),
+	})
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+
+	clearing, err := ClearingAccount(tx)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+	escrow, err := EscrowAccount(tx, milestone.ContractID)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+
+	amount := int64(milestone.Amount)
+	entry, err := Post(tx, Entry{
+		Kind:        "fund",
+		Reference:   fmt.Sprintf("milestone:%d:fund", milestone.ID),
+		MilestoneID: &milestone.ID,
+		Description: fmt.Sprintf("Client funding for milestone %d", milestone.ID),
+		Legs: []Leg{
+			{Account: clearing, Amount: amount},
+			{Account: escrow, Amount: -amount},
+		},
+	})
+	return entry, err
+}
+
+// ReleaseMilestone pays a funded milestone out of escrow to the freelancer, keeping the platform fee
+func ReleaseMilestone(tx *gorm.DB, milestone *models.Milestone, freelancerID uint) (models.JournalEntry, error) {
+	err := moveEscrow(tx, milestone, []string{"funded"}, map[string]interface{}{"escrow_status": "released"})
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+
+	escrow, err := EscrowAccount(tx, milestone.ContractID)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+	payable, err := PayableAccount(tx, freelancerID)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+
+	amount := int64(milestone.Amount)
+	fee := PlatformFee(amount)
+	legs := []Leg{
+		{Account: escrow, Amount: amount},
+		{Account: payable, Amount: -(amount - fee)},
+	}
+	if fee > 0 {
+		fees, err := FeeAccount(tx)
+		if err != nil {
+			return models.JournalEntry{}, err
+		}
+		legs = append(legs, Leg{Account: fees, Amount: -fee})
+	}
+
+	entry, err := Post(tx, Entry{
+		Kind:        "release",
+		Reference:   fmt.Sprintf("milestone:%d:release", milestone.ID),
+		MilestoneID: &milestone.ID,
+		Description: fmt.Sprintf("Release of milestone %d (fee %d)", milestone.ID, fee),
+		Legs:        legs,
+	})
+	return entry, err
+}
+
+// RefundMilestone returns a funded milestone's escrow to the client
+func RefundMilestone(tx *gorm.DB, milestone *models.Milestone) (models.JournalEntry, error) {
+	err := moveEscrow(tx, milestone, []string{"funded"}, map[string]interface{}{"escrow_status": "refunded"})
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+
+	clearing, err := ClearingAccount(tx)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+	escrow, err := EscrowAccount(tx, milestone.ContractID)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+
+	amount := int64(milestone.Amount)
+	entry, err := Post(tx, Entry{
+		Kind:        "refund",
+		Reference:   fmt.Sprintf("milestone:%d:refund", milestone.ID),
+		MilestoneID: &milestone.ID,
+		Description: fmt.Sprintf("Refund of milestone %d", milestone.ID),
+		Legs: []Leg{
+			{Account: escrow, Amount: amount},
+			{Account: clearing, Amount: -amount},
+		},
+	})
+	return entry, err
+}
+
+// moveEscrow applies updates to the milestone if its escrow is in one of the
+// from states, and fails with ErrEscrowState otherwise. The update locks the
+// row first, so of two transactions moving the same escrow only one succeeds.
+func moveEscrow(tx *gorm.DB, milestone *models.Milestone, from []string, updates map[string]interface{}) error {
+	result := tx.Model(&models.Milestone{}).
+		Where("id = ? AND escrow_status IN ?", milestone.ID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrEscrowState
+	}
+
+	milestone.EscrowStatus = updates["escrow_status"].(string)
+	if fundedAt, ok := updates["funded_at"].(time.Time); ok {
+		milestone.FundedAt = &fundedAt
+	}
+	return nil
+}
+
+// RecordPayout moves money from a freelancer's payable balance out to the payment provider
+func RecordPayout(tx *gorm.DB, payout *models.Payout) (models.JournalEntry, error) {
+	payable, err := PayableAccount(tx, payout.FreelancerID)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+	clearing, err := ClearingAccount(tx)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+
+	return Post(tx, Entry{
+		Kind:        "payout",
+		Reference:   fmt.Sprintf("payout:%d", payout.ID),
+		Description: fmt.Sprintf("Payout %d to freelancer %d", payout.ID, payout.FreelancerID),
+		Legs: []Leg{
+			{Account: payable, Amount: payout.Amount},
+			{Account: clearing, Amount: -payout.Amount},
+		},
+	})
+}
+
+// ReversePayout puts a failed payout back on the freelancer's payable balance
+func ReversePayout(tx *gorm.DB, payout *models.Payout) (models.JournalEntry, error) {
+	payable, err := PayableAccount(tx, payout.FreelancerID)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+	clearing, err := ClearingAccount(tx)
+	if err != nil {
+		return models.JournalEntry{}, err
+	}
+
+	return Post(tx, Entry{
+		Kind:        "payout_reversal",
+		Reference:   fmt.Sprintf("payout:%d:reversal", payout.ID),
+		Description: fmt.Sprintf("Reversal of failed payout %d", payout.ID),
+		Legs: []Leg{
+			{Account: clearing, Amount: payout.Amount},
+			{Account: payable, Amount: -payout.Amount},
+		},
+	})
+}
@@ -0,0 +1,145 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Account types
+const (
+	Asset     = "asset"
+	Liability = "liability"
+	Revenue   = "revenue"
+	Expense   = "expense"
+)
+
+var (
+	ErrUnbalanced     = errors.New("journal entry does not balance")
+	ErrTooFewPostings = errors.New("journal entry needs at least two postings")
+	ErrZeroPosting    = errors.New("postings must have a non-zero amount")
+	ErrDuplicateEntry = errors.New("journal entry with this reference already posted")
+)
+
+// Leg is one side of an entry to be posted; positive amounts debit, negative amounts credit
+type Leg struct {
+	Account models.LedgerAccount
+	Amount  int64
+}
+
+// Entry describes a journal entry before it is posted
+type Entry struct {
+	Kind        string
+	Reference   string
+	MilestoneID *uint
+	Description string
+	Legs        []Leg
+}
+
+// Post validates and writes a balanced journal entry inside tx and moves the
+// running balances of the affected accounts. Posted entries are never changed;
+// corrections are made by posting a new entry.
+func Post(tx *gorm.DB, entry Entry) (models.JournalEntry, error) {
+	var journal models.JournalEntry
+
+	if len(entry.Legs) < 2 {
+		return journal, ErrTooFewPostings
+	}
+
+	var sum int64
+	for _, leg := range entry.Legs {
+		if leg.Amount == 0 {
+			return journal, ErrZeroPosting
+		}
+		sum += leg.Amount
+	}
+	if sum != 0 {
+		return journal, fmt.Errorf("%w: off by %d", ErrUnbalanced, sum)
+	}
+
+	var existing int64
+	if err := tx.Model(&models.JournalEntry{}).Where("reference = ?", entry.Reference).Count(&existing).Error; err != nil {
+		return journal, err
+	}
+	if existing > 0 {
+		return journal, ErrDuplicateEntry
+	}
+
+	journal = models.JournalEntry{
+		Kind:        entry.Kind,
+		Reference:   entry.Reference,
+		MilestoneID: entry.MilestoneID,
+		Description: entry.Description,
+		PostedAt:    time.Now(),
+	}
+	for _, leg := range entry.Legs {
+		journal.Postings = append(journal.Postings, models.Posting{
+			AccountID: leg.Account.ID,
+			Amount:    leg.Amount,
+		})
+	}
+
+	if err := tx.Create(&journal).Error; err != nil {
+		return journal, err
+	}
+
+	for _, leg := range entry.Legs {
+		if err := tx.Model(&models.LedgerAccount{}).Where("id = ?", leg.Account.ID).
+			Update("balance", gorm.Expr("balance + ?", leg.Amount)).Error; err != nil {
+			return journal, err
+		}
+	}
+
+	return journal, nil
+}
+
+// Account returns the account with the given code, creating it on first use
+func Account(tx *gorm.DB, code, name, accountType string, ownerID *uint) (models.LedgerAccount, error) {
+	account := models.LedgerAccount{
+		Code:     code,
+		Name:     name,
+		Type:     accountType,
+		OwnerID:  ownerID,
+		Currency: "TWD",
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return account, err
+	}
+
+	err := tx.Where("code = ?", code).First(&account).Error
+	return account, err
+}
+
+// ClearingAccount holds money received from or sent to the payment provider
+func ClearingAccount(tx *gorm.DB) (models.LedgerAccount, error) {
+	return Account(tx, "cash:clearing", "Payment provider clearing", Asset, nil)
+}
+
+// EscrowAccount holds a contract's funded but unreleased milestone money
+func EscrowAccount(tx *gorm.DB, contractID uint) (models.LedgerAccount, error) {
+	return Account(tx, fmt.Sprintf("escrow:contract:%d", contractID), fmt.Sprintf("Escrow for contract %d", contractID), Liability, nil)
+}
+
+// PayableAccount holds what the platform owes a freelancer
+func PayableAccount(tx *gorm.DB, freelancerID uint) (models.LedgerAccount, error) {
+	return Account(tx, fmt.Sprintf("payable:freelancer:%d", freelancerID), fmt.Sprintf("Payable to freelancer %d", freelancerID), Liability, &freelancerID)
+}
+
+// FeeAccount collects the platform's fees
+func FeeAccount(tx *gorm.DB) (models.LedgerAccount, error) {
+	return Account(tx, "revenue:platform_fees", "Platform fees", Revenue, nil)
+}
+
+// NormalBalance returns an account's balance with the sign flipped for credit-normal
+// accounts, so a healthy liability or revenue account reads as a positive number
+func NormalBalance(account models.LedgerAccount) int64 {
+	if account.Type == Liability || account.Type == Revenue {
+		return -account.Balance
+	}
+	return account.Balance
+}
@@ -0,0 +1,109 @@
+package ledger
+
+import (
+	"fmt"
+
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Issue is a single reconciliation failure
+type Issue struct {
+	Check   string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("[%s] %s", i.Check, i.Message)
+}
+
+// Reconcile verifies the ledger: every entry balances, the whole ledger sums to
+// zero, cached account balances match their postings, and each contract's escrow
+// holds exactly its funded milestones
+func Reconcile(db *gorm.DB) ([]Issue, error) {
+	var issues []Issue
+
+	// Every journal entry must balance on its own
+	var unbalanced []struct {
+		EntryID uint
+		Total   int64
+	}
+	if err := db.Model(&models.Posting{}).
+		Select("entry_id, SUM(amount) AS total").
+		Group("entry_id").
+		Having("SUM(amount) != 0").
+		Scan(&unbalanced).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range unbalanced {
+		issues = append(issues, Issue{"entry", fmt.Sprintf("journal entry %d is off by %d", u.EntryID, u.Total)})
+	}
+
+	// Entries with fewer than two postings are malformed
+	var thin []uint
+	if err := db.Model(&models.JournalEntry{}).
+		Joins("LEFT JOIN postings ON postings.entry_id = journal_entries.id").
+		Group("journal_entries.id").
+		Having("COUNT(postings.id) < 2").
+		Pluck("journal_entries.id", &thin).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range thin {
+		issues = append(issues, Issue{"entry", fmt.Sprintf("journal entry %d has fewer than two postings", id)})
+	}
+
+	// The trial balance must be zero
+	var total int64
+	if err := db.Model(&models.Posting{}).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+		return nil, err
+	}
+	if total != 0 {
+		issues = append(issues, Issue{"trial_balance", fmt.Sprintf("ledger is off by %d", total)})
+	}
+
+	// Cached balances must equal the sum of each account's postings
+	var accounts []models.LedgerAccount
+	if err := db.Order("id ASC").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		var sum int64
+		if err := db.Model(&models.Posting{}).Select("COALESCE(SUM(amount), 0)").
+			Where("account_id = ?", account.ID).Scan(&sum).Error; err != nil {
+			return nil, err
+		}
+		if sum != account.Balance {
+			issues = append(issues, Issue{"account", fmt.Sprintf("%s has balance %d but postings sum to %d", account.Code, account.Balance, sum)})
+		}
+		if account.Type == Liability && NormalBalance(account) < 0 {
+			issues = append(issues, Issue{"account", fmt.Sprintf("%s is overdrawn (%d)", account.Code, NormalBalance(account))})
+		}
+	}
+
+	// Each escrow account must hold exactly the funded, unreleased milestones of its contract
+	var contracts []models.Contract
+	if err := db.Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+	for _, contract := range contracts {
+		var funded int64
+		if err := db.Model(&models.Milestone{}).Select("COALESCE(SUM(amount), 0)").
+			Where("contract_id = ? AND escrow_status = ?", contract.ID, "funded").
+			Scan(&funded).Error; err != nil {
+			return nil, err
+		}
+
+		var held int64
+		var escrow models.LedgerAccount
+		if err := db.Where("code = ?", fmt.Sprintf("escrow:contract:%d", contract.ID)).First(&escrow).Error; err == nil {
+			held = NormalBalance(escrow)
+		}
+
+		if held != funded {
+			issues = append(issues, Issue{"escrow", fmt.Sprintf("contract %d escrow holds %d but funded milestones total %d", contract.ID, held, funded)})
+		}
+	}
+
+	return issues, nil
+}
@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"freelance-platform/internal/appenv"
+)
+
+// Message is a single outgoing email with a plain text body and an optional HTML alternative
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers outgoing email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var DefaultMailer Mailer
+
+// Connect selects the mailer through MAILER (smtp or capture). The capture
+// mailer keeps messages in memory, which is what local development and tests
+// want; it is the default outside production and refused in production,
+// where the emails would never leave the process.
+func Connect() {
+	mailer := os.Getenv("MAILER")
+	if mailer == "" && !appenv.IsProduction() {
+		mailer = "capture"
+	}
+
+	switch mailer {
+	case "smtp":
+		DefaultMailer = NewSMTPMailer(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			User:     os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		})
+	case "capture":
+		if appenv.IsProduction() {
+			log.Fatal("MAILER=capture never sends email; use smtp in production")
+		}
+		DefaultMailer = NewCaptureMailer()
+	default:
+		log.Fatalf("MAILER must be smtp or capture, got %q", mailer)
+	}
+
+	log.Println("Mailer ready")
+}
+
+// Send delivers a message through the default mailer
+func Send(ctx context.Context, msg Message) error {
+	return DefaultMailer.Send(ctx, msg)
+}
@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+// DefaultLocale is used for locales that have no templates
+const DefaultLocale = "zh-TW"
+
+// Locales lists the locales with email templates
+var Locales = []string{"zh-TW", "en"}
+
+//go:embed templates
+var templates embed.FS
+
+// Render builds a message from the templates/<locale>/<name>.txt.tmpl and
+// .html.tmpl pair. The text template defines the subject as a "subject"
+// block. The caller fills in To.
+func Render(locale, name string, data interface{}) (Message, error) {
+	if !SupportsLocale(locale) {
+		locale = DefaultLocale
+	}
+	base := "templates/" + locale + "/" + name
+
+	text, err := template.ParseFS(templates, base+".txt.tmpl")
+	if err != nil {
+		return Message{}, err
+	}
+	html, err := htmltemplate.ParseFS(templates, base+".html.tmpl")
+	if err != nil {
+		return Message{}, err
+	}
+
+	var subject, textBody, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return Message{}, err
+	}
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(textBody.String()) + "\n",
+		HTML:    htmlBody.String(),
+	}, nil
+}
+
+// SupportsLocale reports whether there are templates for locale
+func SupportsLocale(locale string) bool {
+	for _, supported := range Locales {
+		if locale == supported {
+			return true
+		}
+	}
+	return false
+}
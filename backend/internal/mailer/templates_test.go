@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderNotification(t *testing.T) {
+	data := map[string]interface{}{
+		"Name":            "王小明",
+		"Type":            "bid.created",
+		"ProjectTitle":    "<b>官網改版</b>",
+		"ActorName":       "Alice",
+		"Link":            "http://localhost:3000/projects/1",
+		"PreferencesLink": "http://localhost:3000/settings",
+	}
+
+	zh, err := Render("zh-TW", "notification", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zh.Subject != "「<b>官網改版</b>」收到新的投標" || !strings.Contains(zh.Text, "Alice 對您的案件") {
+		t.Errorf("unexpected zh-TW message %+v", zh)
+	}
+	if strings.Contains(zh.HTML, "<b>") || !strings.Contains(zh.HTML, "&lt;b&gt;") {
+		t.Errorf("expected the HTML body to escape data, got %s", zh.HTML)
+	}
+
+	en, err := Render("en", "notification", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(en.Subject, "New bid on") || !strings.Contains(en.Text, "Hi 王小明") {
+		t.Errorf("unexpected en message %+v", en)
+	}
+
+	// Unknown locales fall back to zh-TW
+	fallback, err := Render("fr", "notification", data)
+	if err != nil || fallback.Subject != zh.Subject {
+		t.Errorf("expected the zh-TW templates, got %+v, %v", fallback, err)
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	data := map[string]interface{}{
+		"Name": "Alice",
+		"Messages": []map[string]string{
+			{"SenderName": "Bob", "ProjectTitle": "App", "Preview": "Can you start Monday?", "Link": "http://localhost:3000/messages?chat=1"},
+		},
+		"Projects":        []map[string]interface{}{},
+		"PreferencesLink": "http://localhost:3000/settings",
+	}
+
+	msg, err := Render("en", "digest", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Your notification digest: unread messages in 1 chat" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "Bob (App): Can you start Monday?") || strings.Contains(msg.Text, "matching your skills") {
+		t.Errorf("unexpected text %s", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, `href="http://localhost:3000/messages?chat=1"`) {
+		t.Errorf("unexpected HTML %s", msg.HTML)
+	}
+}
@@ -1,15 +1,26 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
-	"os"
 	"strings"
 
+	"freelance-platform/internal/auth"
 	"freelance-platform/internal/database"
 	"freelance-platform/internal/models"
+	"freelance-platform/internal/realtime"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken  = errors.New("Invalid token")
+	ErrInvalidClaims = errors.New("Invalid token claims")
+	ErrInvalidUserID = errors.New("Invalid user ID in token")
+	ErrUserNotFound  = errors.New("User not found")
+	ErrTokenRevoked  = errors.New("Token has been revoked")
 )
 
 func RequireAuth() gin.HandlerFunc {
@@ -30,49 +41,154 @@ func RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			secret := os.Getenv("JWT_SECRET")
-			if secret == "" {
-				secret = "your_jwt_secret_key"
-			}
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		user, claims, err := authenticate(tokenString)
+		if err != nil {
+			c.JSON(authErrorStatus(err), gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		// Extract user ID from token
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		// Set user and session in context
+		c.Set("user", user)
+		c.Set("session_id", claims.SessionID)
+		c.Set("token_id", claims.TokenID)
+		c.Next()
+	})
+}
+
+// RequireWebSocketAuth works like RequireAuth but also accepts the token in the
+// "token" query parameter, since browsers cannot set headers on websocket upgrades.
+// Browsers on any site other than the frontend are turned away.
+func RequireWebSocketAuth() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if !realtime.AllowedOrigin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Origin not allowed"})
 			c.Abort()
 			return
 		}
 
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			tokenString = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if tokenString == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token required"})
 			c.Abort()
 			return
 		}
 
-		// Find user in database
-		var user models.User
-		if err := database.DB.First(&user, uint(userID)).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		user, claims, err := authenticate(tokenString)
+		if err != nil {
+			c.JSON(authErrorStatus(err), gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		// Set user in context
+		// Set user and session in context
 		c.Set("user", user)
+		c.Set("session_id", claims.SessionID)
+		c.Set("token_id", claims.TokenID)
+		c.Next()
+	})
+}
+
+// AuthenticateToken validates a JWT and loads the user it was issued for
+func AuthenticateToken(tokenString string) (models.User, error) {
+	user, _, err := authenticate(tokenString)
+	return user, err
+}
+
+// authenticate validates a JWT, rejects revoked sessions and loads the user
+func authenticate(tokenString string) (models.User, auth.AccessClaims, error) {
+	var user models.User
+
+	// Parse and validate token
+	claims, ok := auth.ParseAccessToken(tokenString)
+	if !ok {
+		return user, claims, ErrInvalidToken
+	}
+
+	if claims.SessionID == "" || claims.TokenID == "" {
+		return user, claims, ErrInvalidClaims
+	}
+
+	if claims.UserID == 0 {
+		return user, claims, ErrInvalidUserID
+	}
+
+	// Reject tokens whose session was logged out
+	if auth.DefaultDenylist != nil {
+		revoked, err := auth.DefaultDenylist.Contains(context.Background(), claims.TokenID, claims.SessionID)
+		if err != nil {
+			log.Printf("Failed to check token denylist: %v", err)
+			return user, claims, ErrInvalidToken
+		}
+		if revoked {
+			return user, claims, ErrTokenRevoked
+		}
+	}
+
+	// Find user in database
+	if err := database.DB.First(&user, claims.UserID).Error; err != nil {
+		return user, claims, ErrUserNotFound
+	}
+
+	// Suspended and banned accounts lose access immediately
+	if err := auth.CheckAccountStatus(user); err != nil {
+		return user, claims, err
+	}
+
+	return user, claims, nil
+}
+
+// authErrorStatus maps authentication failures to HTTP status codes
+func authErrorStatus(err error) int {
+	if errors.Is(err, auth.ErrAccountSuspended) || errors.Is(err, auth.ErrAccountBanned) {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
+
+// RequireRole allows only users with one of the given roles. It must run after RequireAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		role := user.(models.User).Role
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	})
+}
+
+// RequireVerifiedEmail blocks users who have not confirmed their email address.
+// It must run after RequireAuth.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		if user.(models.User).EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address first"})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	})
-} 
\ No newline at end of file
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,7 +15,7 @@ func Logger() gin.HandlerFunc {
 			param.ClientIP,
 			param.TimeStamp.Format(time.RFC1123),
 			param.Method,
-			param.Path,
+			redactQuery(param.Path),
 			param.Request.Proto,
 			param.StatusCode,
 			param.Latency,
@@ -21,4 +23,31 @@ func Logger() gin.HandlerFunc {
 			param.ErrorMessage,
 		)
 	})
-} 
\ No newline at end of file
+}
+
+// redactedParams are query parameters that carry credentials and never reach the log
+var redactedParams = []string{"token"}
+
+// redactQuery replaces the values of redactedParams in a logged path
+func redactQuery(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?[unparsable query]"
+	}
+	redacted := false
+	for _, name := range redactedParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	cases := map[string]string{
+		"/ws/chat?token=secret":              "/ws/chat?token=REDACTED",
+		"/ws/chat?chat=1&token=secret":       "/ws/chat?chat=1&token=REDACTED",
+		"/api/projects?search=go&cursor=abc": "/api/projects?search=go&cursor=abc",
+		"/api/projects":                      "/api/projects",
+	}
+	for path, want := range cases {
+		if got := redactQuery(path); got != want {
+			t.Errorf("redactQuery(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"freelance-platform/internal/outbox"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrackEvents notes the outbox events each request records, so that flushing
+// after the commit publishes only those instead of the whole backlog
+func TrackEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(outbox.Track(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminAction records a moderation action taken by an admin
+type AdminAction struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	AdminID    uint      `json:"admin_id" gorm:"not null;index"`
+	Admin      User      `json:"admin,omitempty"`
+	Action     string    `json:"action" gorm:"not null;index"` // user.suspend, user.ban, user.reinstate, user.role, project.close, project.restore, message.hide, message.unhide, chat.view, attachment.view
+	TargetType string    `json:"target_type" gorm:"not null;index:idx_admin_action_target"`
+	TargetID   uint      `json:"target_id" gorm:"not null;index:idx_admin_action_target"`
+	Reason     string    `json:"reason" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
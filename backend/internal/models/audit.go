@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditImmutable is returned when code tries to change a written audit record
+var ErrAuditImmutable = errors.New("audit log entries cannot be modified")
+
+// AuditLog is an append-only record of a change made through the API. Changes
+// holds a JSON object of the fields that changed: {"field": {"from": x, "to": y}}.
+type AuditLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActorID    *uint     `json:"actor_id" gorm:"index"`
+	Actor      *User     `json:"actor,omitempty"`
+	Action     string    `json:"action" gorm:"not null;index"` // e.g. project.status, project.delete, user.profile, user.role
+	TargetType string    `json:"target_type" gorm:"not null;index:idx_audit_target"`
+	TargetID   uint      `json:"target_id" gorm:"not null;index:idx_audit_target"`
+	Changes    string    `json:"changes" gorm:"type:jsonb;not null;default:'{}'"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error { return ErrAuditImmutable }
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error { return ErrAuditImmutable }
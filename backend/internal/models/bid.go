@@ -15,8 +15,20 @@ type Bid struct {
 	Amount       int            `json:"amount" gorm:"not null"` // Amount in TWD
 	Proposal     string         `json:"proposal" gorm:"type:text"` // Detailed proposal
 	Timeline     string         `json:"timeline"` // e.g., "2週", "1個月"
-	Status       string         `json:"status" gorm:"default:pending"` // pending, accepted, rejected
+	Status       string         `json:"status" gorm:"default:pending"` // pending, accepted, rejected, withdrawn, expired
+	ExpiresAt    *time.Time     `json:"expires_at" gorm:"index"` // Pending bids expire automatically after this time
+	Revisions    []BidRevision  `json:"revisions,omitempty"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
-} 
\ No newline at end of file
+}
+
+// BidRevision keeps the previous version of a bid each time the freelancer edits it
+type BidRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	BidID     uint      `json:"bid_id" gorm:"not null;index"`
+	Amount    int       `json:"amount" gorm:"not null"`
+	Proposal  string    `json:"proposal" gorm:"type:text"`
+	Timeline  string    `json:"timeline"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -29,10 +29,26 @@ type Message struct {
 	SenderID uint           `json:"sender_id" gorm:"not null"`
 	Sender   User           `json:"sender,omitempty"`
 	Content  string         `json:"content" gorm:"type:text;not null"`
-	Type     string         `json:"type" gorm:"default:text"` // text, file, image
+	Type     string         `json:"type" gorm:"default:text"` // text, file, image, system
 	FileURL  string         `json:"file_url"`
 	ReadAt   *time.Time     `json:"read_at"`
+	HiddenAt   *time.Time   `json:"hidden_at"` // Hidden by an admin; participants only see a placeholder
+	HiddenByID *uint        `json:"hidden_by_id,omitempty"`
 	CreatedAt time.Time     `json:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
-} 
\ No newline at end of file
+} 
+
+// Attachment is a file uploaded into a chat; the file itself lives in storage under StorageKey
+type Attachment struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	ChatID      uint           `json:"chat_id" gorm:"not null;index"`
+	MessageID   *uint          `json:"message_id" gorm:"index"`
+	UploaderID  uint           `json:"uploader_id" gorm:"not null"`
+	StorageKey  string         `json:"-" gorm:"not null;uniqueIndex"`
+	FileName    string         `json:"file_name" gorm:"not null"`
+	ContentType string         `json:"content_type" gorm:"not null"`
+	Size        int64          `json:"size" gorm:"not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
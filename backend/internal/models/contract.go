@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Contract is created when a bid is accepted and splits the agreed amount into milestones
+type Contract struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	ProjectID    uint           `json:"project_id" gorm:"not null;uniqueIndex"`
+	Project      Project        `json:"project,omitempty"`
+	BidID        uint           `json:"bid_id" gorm:"not null"`
+	ClientID     uint           `json:"client_id" gorm:"not null;index"`
+	Client       User           `json:"client,omitempty"`
+	FreelancerID uint           `json:"freelancer_id" gorm:"not null;index"`
+	Freelancer   User           `json:"freelancer,omitempty"`
+	Amount       int            `json:"amount" gorm:"not null"`       // Total in TWD, equal to the sum of milestone amounts
+	Status       string         `json:"status" gorm:"default:active"` // active, completed, cancelled
+	Milestones   []Milestone    `json:"milestones,omitempty"`
+	CompletedAt  *time.Time     `json:"completed_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type Milestone struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	ContractID   uint           `json:"contract_id" gorm:"not null;index"`
+	Position     int            `json:"position" gorm:"not null"` // 1-based order within the contract
+	Title        string         `json:"title" gorm:"not null"`
+	Description  string         `json:"description" gorm:"type:text"`
+	Amount       int            `json:"amount" gorm:"not null"` // Amount in TWD
+	DueDate      *time.Time     `json:"due_date"`
+	Status       string         `json:"status" gorm:"default:pending"`         // pending, submitted, changes_requested, approved
+	EscrowStatus string         `json:"escrow_status" gorm:"default:unfunded"` // unfunded, funded, released, refunded
+	Deliverables []Deliverable  `json:"deliverables,omitempty"`
+	ApprovedAt   *time.Time     `json:"approved_at"`
+	FundedAt     *time.Time     `json:"funded_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// Deliverable is one submission of work for a milestone and the client's answer to it
+type Deliverable struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	MilestoneID uint       `json:"milestone_id" gorm:"not null;index"`
+	Note        string     `json:"note" gorm:"type:text;not null"`
+	FileURL     string     `json:"file_url"`
+	Status      string     `json:"status" gorm:"default:submitted"` // submitted, approved, changes_requested
+	Feedback    string     `json:"feedback" gorm:"type:text"`       // Client's feedback when requesting changes
+	ReviewedAt  *time.Time `json:"reviewed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead" // failed on its last attempt
+)
+
+// Job is a unit of background work waiting for, or done by, a worker
+type Job struct {
+	ID      uint            `json:"id" gorm:"primaryKey"`
+	Type    string          `json:"type" gorm:"not null"`
+	Payload json.RawMessage `json:"payload" gorm:"type:jsonb;not null"`
+	// UniqueKey keeps a second job with the same key out while one is queued or running
+	UniqueKey   *string `json:"unique_key"`
+	Status      string  `json:"status" gorm:"not null;default:queued"`
+	Attempts    int     `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int     `json:"max_attempts" gorm:"not null"`
+	// RunAt is when a queued job becomes due
+	RunAt    time.Time `json:"run_at" gorm:"not null"`
+	LockedBy string    `json:"locked_by,omitempty"`
+	// LockedUntil is when a running job's worker is presumed dead and the job is handed out again
+	LockedUntil *time.Time `json:"locked_until"`
+	LastError   string     `json:"last_error,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrLedgerImmutable is returned when code tries to change a posted journal entry
+var ErrLedgerImmutable = errors.New("posted ledger entries cannot be modified")
+
+// LedgerAccount is an account in the double-entry ledger. Balance is a running
+// total of its postings (debits positive, credits negative) kept for fast reads;
+// cmd/reconcile checks it against the postings themselves.
+type LedgerAccount struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Code      string    `json:"code" gorm:"not null;uniqueIndex"` // e.g. escrow:contract:12, payable:freelancer:3
+	Name      string    `json:"name" gorm:"not null"`
+	Type      string    `json:"type" gorm:"not null"` // asset, liability, revenue, expense
+	OwnerID   *uint     `json:"owner_id" gorm:"index"`
+	Currency  string    `json:"currency" gorm:"default:TWD"`
+	Balance   int64     `json:"balance" gorm:"not null;default:0"` // Amount in TWD, debit-positive
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JournalEntry groups postings that move money together; its postings always sum to zero
+type JournalEntry struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Kind        string    `json:"kind" gorm:"not null;index"`            // fund, release, refund, payout
+	Reference   string    `json:"reference" gorm:"not null;uniqueIndex"` // Idempotency key, e.g. milestone:5:fund
+	MilestoneID *uint     `json:"milestone_id" gorm:"index"`
+	Description string    `json:"description"`
+	Postings    []Posting `json:"postings,omitempty" gorm:"foreignKey:EntryID"`
+	PostedAt    time.Time `json:"posted_at" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Posting is one leg of a journal entry against a single account
+type Posting struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	EntryID   uint      `json:"entry_id" gorm:"not null;index"`
+	AccountID uint      `json:"account_id" gorm:"not null;index"`
+	Amount    int64     `json:"amount" gorm:"not null"` // Debit positive, credit negative
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (e *JournalEntry) BeforeUpdate(tx *gorm.DB) error { return ErrLedgerImmutable }
+func (e *JournalEntry) BeforeDelete(tx *gorm.DB) error { return ErrLedgerImmutable }
+func (p *Posting) BeforeUpdate(tx *gorm.DB) error      { return ErrLedgerImmutable }
+func (p *Posting) BeforeDelete(tx *gorm.DB) error      { return ErrLedgerImmutable }
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification is an entry in a user's notification center, created from a
+// domain event. The optional IDs point at what the notification is about.
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Type      string     `json:"type" gorm:"not null"` // bid.created, bid.accepted, bid.rejected, project.status_changed, message.received
+	Title     string     `json:"title" gorm:"not null"`
+	Body      string     `json:"body" gorm:"type:text"`
+	ActorID   *uint      `json:"actor_id"`
+	ProjectID *uint      `json:"project_id"`
+	BidID     *uint      `json:"bid_id"`
+	ChatID    *uint      `json:"chat_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	// SendEmail is set when the user wants this type by email; EmailedAt once it went out
+	SendEmail bool       `json:"-" gorm:"not null;default:false"`
+	EmailedAt *time.Time `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationPreference is the channel a user picked for one type of
+// notification; types without a row use the default channel
+type NotificationPreference struct {
+	UserID    uint      `json:"-" gorm:"primaryKey"`
+	EventType string    `json:"event_type" gorm:"primaryKey"`
+	Channel   string    `json:"channel" gorm:"not null"` // in_app, email, none
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotificationSetting holds a user's notification options that aren't tied
+// to a type. Quiet hours are minutes after midnight in Asia/Taipei.
+type NotificationSetting struct {
+	UserID          uint       `json:"-" gorm:"primaryKey"`
+	Locale          string     `json:"locale" gorm:"not null;default:zh-TW"` // zh-TW, en
+	QuietHoursStart *int       `json:"quiet_hours_start"`
+	QuietHoursEnd   *int       `json:"quiet_hours_end"`
+	LastDigestAt    *time.Time `json:"last_digest_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
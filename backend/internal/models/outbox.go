@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event written in the transaction that made the
+// change it describes, waiting for the relay to publish it
+type OutboxEvent struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+	// EventID identifies the event to its consumers, which use it to skip redeliveries
+	EventID    string          `json:"event_id" gorm:"not null;uniqueIndex"`
+	Type       string          `json:"type" gorm:"not null"`
+	ActorID    uint            `json:"actor_id"`
+	Payload    json.RawMessage `json:"payload" gorm:"type:jsonb;not null"`
+	OccurredAt time.Time       `json:"occurred_at" gorm:"not null"`
+	Attempts   int             `json:"attempts" gorm:"not null;default:0"`
+	// NextAttemptAt is when the relay may pick the event up, pushed back while it is being published or after a failure
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"not null"`
+	LastError     string     `json:"last_error,omitempty"`
+	PublishedAt   *time.Time `json:"published_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ProcessedEvent records that a consumer handled an event
+type ProcessedEvent struct {
+	Consumer    string    `json:"consumer" gorm:"primaryKey"`
+	EventID     string    `json:"event_id" gorm:"primaryKey"`
+	ProcessedAt time.Time `json:"processed_at" gorm:"not null"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// Payment is a client's charge at the payment provider that funds one milestone
+type Payment struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	MilestoneID      uint      `json:"milestone_id" gorm:"not null;index"`
+	ClientID         uint      `json:"client_id" gorm:"not null;index"`
+	Provider         string    `json:"provider" gorm:"not null"`
+	ProviderIntentID string    `json:"provider_intent_id" gorm:"index"`
+	Amount           int64     `json:"amount" gorm:"not null"` // Amount in TWD
+	Currency         string    `json:"currency" gorm:"default:TWD"`
+	Status           string    `json:"status" gorm:"default:requires_confirmation"` // requires_confirmation, processing, succeeded, failed, refund_pending, refunded
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Payout sends a freelancer's released earnings to their account at the payment provider
+type Payout struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	FreelancerID     uint      `json:"freelancer_id" gorm:"not null;index"`
+	Provider         string    `json:"provider" gorm:"not null"`
+	ProviderPayoutID string    `json:"provider_payout_id" gorm:"index"`
+	Destination      string    `json:"destination" gorm:"not null"`
+	Amount           int64     `json:"amount" gorm:"not null"`        // Amount in TWD
+	Status           string    `json:"status" gorm:"default:pending"` // pending, paid, failed
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// PaymentEvent records every processed provider webhook so redeliveries are ignored
+type PaymentEvent struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Provider    string    `json:"provider" gorm:"not null;uniqueIndex:idx_payment_event"`
+	EventID     string    `json:"event_id" gorm:"not null;uniqueIndex:idx_payment_event"`
+	Type        string    `json:"type"`
+	Payload     string    `json:"-" gorm:"type:text"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
@@ -26,7 +26,17 @@ type Project struct {
 	Freelancer   *User          `json:"freelancer,omitempty"`
 	Bids         []Bid          `json:"bids,omitempty"`
 	Deadline     *time.Time     `json:"deadline"`
+	CompletedAt  *time.Time     `json:"completed_at"` // Set when the project reaches completed; opens the review window
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	// Only set on search results
+	SearchRank   float64           `json:"search_rank,omitempty" gorm:"->;-:migration"`
+	Highlight    *ProjectHighlight `json:"highlight,omitempty" gorm:"-"`
+}
+
+// ProjectHighlight holds HTML-escaped text with the search terms wrapped in <mark>
+type ProjectHighlight struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
 } 
\ No newline at end of file
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Review is one side's evaluation of the other after a project is completed.
+// Reviews stay hidden until both sides have submitted or the review window closes.
+type Review struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	ProjectID     uint       `json:"project_id" gorm:"not null;uniqueIndex:idx_review_project_reviewer"`
+	Project       Project    `json:"project,omitempty"`
+	ReviewerID    uint       `json:"reviewer_id" gorm:"not null;uniqueIndex:idx_review_project_reviewer"`
+	Reviewer      User       `json:"reviewer,omitempty"`
+	RevieweeID    uint       `json:"reviewee_id" gorm:"not null;index"`
+	Communication int        `json:"communication" gorm:"not null"` // 1-5 stars
+	Quality       int        `json:"quality" gorm:"not null"`       // 1-5 stars
+	Timeliness    int        `json:"timeliness" gorm:"not null"`    // 1-5 stars
+	Overall       float64    `json:"overall" gorm:"not null"`       // Average of the three dimensions
+	Comment       string     `json:"comment" gorm:"type:text"`
+	PublishedAt   *time.Time `json:"published_at" gorm:"index"` // nil while hidden
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -10,11 +10,23 @@ type User struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	Email        string         `json:"email" gorm:"uniqueIndex;not null"`
 	Password     string         `json:"-" gorm:"not null"`
+	EmailVerifiedAt *time.Time  `json:"email_verified_at"`
+	// Two-factor authentication (TOTP)
+	TwoFactorEnabled  bool      `json:"two_factor_enabled" gorm:"default:false"`
+	TOTPSecret        string    `json:"-"`
+	TOTPPendingSecret string    `json:"-"` // set during enrollment until the first code is confirmed
+	TOTPLastStep      int64     `json:"-"` // last accepted time step, so a code cannot be replayed
+	SecondFactorFailures    int        `json:"-" gorm:"not null;default:0"` // consecutive wrong codes
+	SecondFactorLockedUntil *time.Time `json:"-"`                           // no codes are accepted until then
 	Name         string         `json:"name" gorm:"not null"`
 	Avatar       string         `json:"avatar"`
 	Bio          string         `json:"bio"` // Self introduction
 	Skills       string         `json:"skills"` // JSON array of skills for freelancers
-	Role         string         `json:"role" gorm:"default:freelancer"` // freelancer (接案者), client (發案者)
+	Role         string         `json:"role" gorm:"default:freelancer"` // freelancer (接案者), client (發案者), admin
+	// Moderation
+	Status           string     `json:"status" gorm:"default:active;index"` // active, suspended, banned
+	SuspendedUntil   *time.Time `json:"suspended_until"`
+	ModerationReason string     `json:"moderation_reason,omitempty"`
 	Rating       float64        `json:"rating" gorm:"default:0"`
 	CompletedProjects int       `json:"completed_projects" gorm:"default:0"`
 	// Professional fields for freelancers
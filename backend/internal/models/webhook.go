@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is an endpoint a user registered to receive events about their
+// projects and bids. Payloads are signed with Secret, which is only shown
+// when the webhook is created.
+type Webhook struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	URL       string    `json:"url" gorm:"not null"`
+	Secret    string    `json:"-" gorm:"not null"`
+	Events    []string  `json:"events" gorm:"serializer:json;type:jsonb;not null"` // bid.created, bid.accepted, project.status_changed
+	Active    bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead" // out of attempts; only a manual redelivery sends the event again
+)
+
+// WebhookDelivery is one event queued for one webhook. Failed attempts are
+// retried with exponential backoff until the delivery succeeds or runs out of
+// attempts and is left dead.
+type WebhookDelivery struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	WebhookID uint            `json:"webhook_id" gorm:"not null"`
+	EventID   string          `json:"event_id" gorm:"not null"` // the same for redeliveries, so receivers can drop duplicates
+	EventType string          `json:"event_type" gorm:"not null"`
+	Payload   json.RawMessage `json:"payload" gorm:"type:jsonb;not null"`
+	Status    string          `json:"status" gorm:"not null;default:pending"` // pending, succeeded, dead
+	Attempts  int             `json:"attempts" gorm:"not null;default:0"`
+	// NextAttemptAt is when a pending delivery is due; a dispatcher working on it pushes it back meanwhile
+	NextAttemptAt time.Time                `json:"next_attempt_at"`
+	LastError     string                   `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time               `json:"delivered_at"`
+	AttemptLog    []WebhookDeliveryAttempt `json:"attempt_log,omitempty" gorm:"foreignKey:DeliveryID"`
+	CreatedAt     time.Time                `json:"created_at"`
+	UpdatedAt     time.Time                `json:"updated_at"`
+}
+
+// WebhookDeliveryAttempt logs one HTTP request made for a delivery
+type WebhookDeliveryAttempt struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	DeliveryID uint      `json:"delivery_id" gorm:"not null"`
+	StatusCode *int      `json:"status_code"`        // nil when no response came back
+	Response   string    `json:"response,omitempty"` // start of the response body
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
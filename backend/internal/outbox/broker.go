@@ -0,0 +1,42 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+
+	"freelance-platform/internal/events"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Broker carries events to consumers outside the process
+type Broker interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+// streamMaxLen roughly caps how many events the Redis stream keeps
+const streamMaxLen = 100000
+
+// RedisBroker appends events to a Redis stream. Consumers read it through a
+// consumer group and, as events may repeat, skip the IDs they have seen.
+type RedisBroker struct {
+	client *redis.Client
+	stream string
+}
+
+func NewRedisBroker(client *redis.Client, stream string) *RedisBroker {
+	return &RedisBroker{client: client, stream: stream}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"id": event.ID, "type": event.Type, "event": payload},
+	}).Err()
+}
@@ -0,0 +1,124 @@
+// Package outbox makes domain events as durable as the changes they describe.
+// An event is written to the outbox_events table in the same transaction as
+// the change, and a relay publishes it to the in-process bus, and to an
+// external broker when one is configured, after the transaction commits.
+//
+// Delivery is at least once: an event whose relay crashed before marking it
+// published goes out again. Consumers skip the repeats with MarkProcessed.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Record adds event to the outbox through db, which should be the transaction
+// making the change the event describes, so that the event is written if and
+// only if the change commits. The event is given an ID unless it has one.
+func Record(ctx context.Context, db *gorm.DB, event events.Event) error {
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	payload, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	err = db.WithContext(ctx).Create(&models.OutboxEvent{
+		EventID:       event.ID,
+		Type:          event.Type,
+		ActorID:       event.ActorID,
+		Payload:       payload,
+		OccurredAt:    event.OccurredAt,
+		NextAttemptAt: event.OccurredAt,
+	}).Error
+	if err == nil {
+		track(ctx, event.ID)
+	}
+	return err
+}
+
+// tracker collects the IDs of the events recorded under a Track context
+type tracker struct {
+	mu       sync.Mutex
+	eventIDs []string
+}
+
+type trackerKey struct{}
+
+// Track returns a context under which Record notes the events it writes, so
+// that Flush publishes just those
+func Track(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trackerKey{}, &tracker{})
+}
+
+func track(ctx context.Context, eventID string) {
+	if t, ok := ctx.Value(trackerKey{}).(*tracker); ok {
+		t.mu.Lock()
+		t.eventIDs = append(t.eventIDs, eventID)
+		t.mu.Unlock()
+	}
+}
+
+// recorded returns the events noted under ctx since the last call. Events of
+// a transaction that rolled back are included but never found in the outbox.
+func recorded(ctx context.Context) []string {
+	t, ok := ctx.Value(trackerKey{}).(*tracker)
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	eventIDs := t.eventIDs
+	t.eventIDs = nil
+	return eventIDs
+}
+
+// MarkProcessed records that consumer handled the event with the given ID
+// and reports whether it is the first time. Call it in the transaction that
+// holds the consumer's own writes: if that rolls back, so does the mark, and
+// a redelivery is handled again. Events without an ID can't be told apart
+// and always count as new.
+func MarkProcessed(ctx context.Context, db *gorm.DB, consumer, eventID string) (bool, error) {
+	if eventID == "" {
+		return true, nil
+	}
+
+	result := db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ProcessedEvent{
+		Consumer:    consumer,
+		EventID:     eventID,
+		ProcessedAt: time.Now(),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Prune deletes the events published and the processed marks recorded before
+// the given time and returns how many events went
+func Prune(ctx context.Context, db *gorm.DB, before time.Time) (int64, error) {
+	result := db.WithContext(ctx).Where("published_at < ?", before).Delete(&models.OutboxEvent{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	err := db.WithContext(ctx).Where("processed_at < ?", before).Delete(&models.ProcessedEvent{}).Error
+	return result.RowsAffected, err
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/jobs"
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// relayBatch events are claimed at a time and relayLease keeps them from
+	// other relays while the batch is published one by one
+	relayBatch = 100
+	relayLease = time.Minute
+)
+
+// Relay publishes the events recorded in the outbox
+type Relay struct {
+	db     *gorm.DB
+	bus    *events.Bus
+	broker Broker
+}
+
+// NewRelay returns a relay publishing to bus and, unless it is nil, to broker
+func NewRelay(db *gorm.DB, bus *events.Bus, broker Broker) *Relay {
+	return &Relay{db: db, bus: bus, broker: broker}
+}
+
+// Flush publishes the events due at now in the order they were recorded and
+// returns how many went out. An event the broker refuses, or a subscriber
+// fails to handle, is left in the outbox and retried with jobs.Backoff.
+func (r *Relay) Flush(ctx context.Context, now time.Time) (int, error) {
+	return r.flush(ctx, now, nil)
+}
+
+// FlushEvents is Flush limited to the events with the given IDs
+func (r *Relay) FlushEvents(ctx context.Context, now time.Time, eventIDs []string) (int, error) {
+	if len(eventIDs) == 0 {
+		return 0, nil
+	}
+	return r.flush(ctx, now, eventIDs)
+}
+
+// flush publishes the due events, only those in eventIDs unless it is nil
+func (r *Relay) flush(ctx context.Context, now time.Time, eventIDs []string) (int, error) {
+	published := 0
+	for {
+		claimed, err := r.claim(ctx, now, eventIDs)
+		if err != nil || len(claimed) == 0 {
+			return published, err
+		}
+
+		for _, row := range claimed {
+			if err := r.publish(ctx, row); err != nil {
+				log.Printf("Failed to publish %s event %s: %v", row.Type, row.EventID, err)
+				r.fail(ctx, row, now, err)
+				continue
+			}
+			published++
+		}
+
+		if len(claimed) < relayBatch {
+			return published, nil
+		}
+	}
+}
+
+// claim locks up to relayBatch unpublished events due at now, only those in
+// eventIDs unless it is nil, and pushes them back by relayLease, so they are
+// handed out again only if this relay dies
+func (r *Relay) claim(ctx context.Context, now time.Time, eventIDs []string) ([]models.OutboxEvent, error) {
+	// Leases start from the actual time, as now may have been a while ago
+	leaseStart := time.Now()
+	if now.After(leaseStart) {
+		leaseStart = now
+	}
+
+	var claimed []models.OutboxEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND next_attempt_at <= ?", now)
+		if eventIDs != nil {
+			query = query.Where("event_id IN ?", eventIDs)
+		}
+		err := query.Order("id").Limit(relayBatch).Find(&claimed).Error
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(claimed))
+		for i, row := range claimed {
+			ids[i] = row.ID
+		}
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).
+			Update("next_attempt_at", leaseStart.Add(relayLease)).Error
+	})
+	return claimed, err
+}
+
+// publish hands a claimed event to the broker and then to the bus, and marks
+// it published
+func (r *Relay) publish(ctx context.Context, row models.OutboxEvent) error {
+	data, err := events.DecodeData(row.Type, row.Payload)
+	if err != nil {
+		return fmt.Errorf("decode payload: %w", err)
+	}
+	event := events.Event{
+		ID:         row.EventID,
+		Type:       row.Type,
+		ActorID:    row.ActorID,
+		OccurredAt: row.OccurredAt,
+		Data:       data,
+	}
+
+	if r.broker != nil {
+		if err := r.broker.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	if err := r.bus.Publish(ctx, event); err != nil {
+		return err
+	}
+
+	return r.db.WithContext(ctx).Model(&row).Updates(map[string]interface{}{
+		"attempts":     gorm.Expr("attempts + 1"),
+		"last_error":   "",
+		"published_at": time.Now(),
+	}).Error
+}
+
+// fail records a failed attempt and schedules the next one
+func (r *Relay) fail(ctx context.Context, row models.OutboxEvent, now time.Time, cause error) {
+	attempts := row.Attempts + 1
+	err := r.db.WithContext(ctx).Model(&row).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      cause.Error(),
+		"next_attempt_at": now.Add(jobs.Backoff(attempts)),
+	}).Error
+	if err != nil {
+		log.Printf("Failed to record the failure of event %s: %v", row.EventID, err)
+	}
+}
+
+// DefaultRelay is the relay Flush uses
+var DefaultRelay *Relay
+
+// Connect creates the process-wide relay publishing to bus and to the broker
+// selected by OUTBOX_BROKER (none or redis)
+func Connect(db *gorm.DB, bus *events.Bus) {
+	var broker Broker
+	switch os.Getenv("OUTBOX_BROKER") {
+	case "redis":
+		stream := os.Getenv("OUTBOX_STREAM")
+		if stream == "" {
+			stream = "freelance:events"
+		}
+		broker = NewRedisBroker(database.ConnectRedis(), stream)
+	}
+
+	DefaultRelay = NewRelay(db, bus, broker)
+}
+
+// Flush publishes the events recorded under ctx through DefaultRelay. Code
+// that records events flushes right after committing, so subscribers react
+// within the request. Only the request's own events go out here; the rest of
+// the backlog, and everything recorded outside a Track context, is left to
+// the worker's periodic relay.
+func Flush(ctx context.Context) {
+	eventIDs := recorded(ctx)
+	if DefaultRelay == nil || len(eventIDs) == 0 {
+		return
+	}
+
+	if _, err := DefaultRelay.FlushEvents(ctx, time.Now(), eventIDs); err != nil {
+		log.Printf("Failed to flush the outbox: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Package pagination pages through lists with keyset cursors. A page starts
+// right after the last row of the previous one instead of at an offset, so
+// rows added in the meantime don't shift pages and deep pages cost no more
+// than the first.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("invalid limit")
+)
+
+// Cursor is the position of a row in a list. Clients only ever see it
+// encoded and send it back as is.
+type Cursor struct {
+	Time time.Time `json:"t"`
+	ID   uint      `json:"id"`
+	// Score is set for lists ordered by a computed score first, like search results
+	Score *float64 `json:"s,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func Decode(s string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.ID == 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Page is a request for one page of a list
+type Page struct {
+	Limit int
+	// After is the position of the previous page's last row; nil for the first page
+	After *Cursor
+	// WithTotal asks for the number of rows in the whole list
+	WithTotal bool
+}
+
+// FromQuery reads the limit, cursor and total query parameters; the limit
+// defaults to DefaultLimit and is capped at MaxLimit
+func FromQuery(query url.Values) (Page, error) {
+	page := Page{Limit: DefaultLimit, WithTotal: query.Get("total") == "true"}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return page, ErrInvalidLimit
+		}
+		page.Limit = limit
+	}
+	if page.Limit > MaxLimit {
+		page.Limit = MaxLimit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := Decode(value)
+		if err != nil {
+			return page, err
+		}
+		page.After = &cursor
+	}
+	return page, nil
+}
+
+// Meta describes a page for the client
+type Meta struct {
+	// NextCursor fetches the following page; empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// Order is how a paged list is sorted, descending on every key: the optional
+// Score expression, then Time, then ID to break ties. Time and ID are column
+// names; Score may use ? placeholders bound to ScoreArgs.
+type Order struct {
+	Score     string
+	ScoreArgs []interface{}
+	Time      string
+	ID        string
+}
+
+// Apply restricts query to the page: the rows after the cursor in order,
+// plus one more so Finish can tell whether another page follows
+func (p Page) Apply(query *gorm.DB, order Order) *gorm.DB {
+	if order.Score == "" {
+		if p.After != nil {
+			query = query.Where(fmt.Sprintf("(%s, %s) < (?, ?)", order.Time, order.ID), p.After.Time, p.After.ID)
+		}
+		return query.Order(fmt.Sprintf("%s DESC, %s DESC", order.Time, order.ID)).Limit(p.Limit + 1)
+	}
+
+	if p.After != nil {
+		var score float64
+		if p.After.Score != nil {
+			score = *p.After.Score
+		}
+		args := append(append([]interface{}{}, order.ScoreArgs...), score, p.After.Time, p.After.ID)
+		query = query.Where(fmt.Sprintf("(%s, %s, %s) < (?, ?, ?)", order.Score, order.Time, order.ID), args...)
+	}
+	return query.
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                fmt.Sprintf("%s DESC, %s DESC, %s DESC", order.Score, order.Time, order.ID),
+			Vars:               order.ScoreArgs,
+			WithoutParentheses: true,
+		}}).
+		Limit(p.Limit + 1)
+}
+
+// Finish drops the extra row fetched by Apply and describes the page;
+// position returns the cursor of a row
+func Finish[T any](p Page, rows []T, position func(T) Cursor) ([]T, Meta) {
+	var meta Meta
+	if len(rows) > p.Limit {
+		rows = rows[:p.Limit]
+		meta.HasMore = true
+		meta.NextCursor = position(rows[len(rows)-1]).Encode()
+	}
+	return rows, meta
+}
+
+// Total counts the rows of query into meta when the page asks for it. query
+// should select the whole list, without the page's cursor and limit.
+func (p Page) Total(query *gorm.DB, meta *Meta) error {
+	if !p.WithTotal {
+		return nil
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return err
+	}
+	meta.Total = &total
+	return nil
+}
@@ -0,0 +1,79 @@
+package pagination
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	score := 0.4375
+	cursors := []Cursor{
+		{Time: time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC), ID: 42},
+		{Time: time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("CST", 8*3600)), ID: 7, Score: &score},
+	}
+
+	for _, want := range cursors {
+		got, err := Decode(want.Encode())
+		if err != nil {
+			t.Fatalf("decode %+v: %v", want, err)
+		}
+		if !got.Time.Equal(want.Time) || got.ID != want.ID {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+		if (got.Score == nil) != (want.Score == nil) || (got.Score != nil && *got.Score != *want.Score) {
+			t.Errorf("score: got %v, want %v", got.Score, want.Score)
+		}
+	}
+}
+
+func TestDecodeRejectsGarbage(t *testing.T) {
+	for _, value := range []string{"not a cursor", "e30", "eyJpZCI6MH0"} {
+		if _, err := Decode(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Decode(%q): expected ErrInvalidCursor, got %v", value, err)
+		}
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	page, err := FromQuery(url.Values{})
+	if err != nil || page.Limit != DefaultLimit || page.After != nil || page.WithTotal {
+		t.Errorf("unexpected default page %+v, %v", page, err)
+	}
+
+	page, err = FromQuery(url.Values{"limit": {"5000"}, "total": {"true"}})
+	if err != nil || page.Limit != MaxLimit || !page.WithTotal {
+		t.Errorf("expected the limit capped with a total, got %+v, %v", page, err)
+	}
+
+	for _, limit := range []string{"0", "-3", "ten"} {
+		if _, err := FromQuery(url.Values{"limit": {limit}}); !errors.Is(err, ErrInvalidLimit) {
+			t.Errorf("limit %q: expected ErrInvalidLimit, got %v", limit, err)
+		}
+	}
+
+	cursor := Cursor{Time: time.Now(), ID: 3}
+	page, err = FromQuery(url.Values{"cursor": {cursor.Encode()}})
+	if err != nil || page.After == nil || page.After.ID != 3 {
+		t.Errorf("expected the cursor to be read, got %+v, %v", page, err)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	position := func(id int) Cursor { return Cursor{ID: uint(id)} }
+	page := Page{Limit: 3}
+
+	rows, meta := Finish(page, []int{9, 8, 7, 6}, position)
+	if len(rows) != 3 || !meta.HasMore {
+		t.Fatalf("expected a full page with more to come, got %v %+v", rows, meta)
+	}
+	if next, err := Decode(meta.NextCursor); err != nil || next.ID != 7 {
+		t.Errorf("expected the next page to start after 7, got %+v, %v", next, err)
+	}
+
+	rows, meta = Finish(page, []int{5, 4}, position)
+	if len(rows) != 2 || meta.HasMore || meta.NextCursor != "" {
+		t.Errorf("expected the last page, got %v %+v", rows, meta)
+	}
+}
@@ -0,0 +1,214 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FakeProvider is a fully local payment gateway. It keeps intents in memory and,
+// like a real provider, reports outcomes through signed webhooks delivered
+// asynchronously after Delay.
+type FakeProvider struct {
+	secret string
+	delay  time.Duration
+
+	// Deliver sends a signed webhook; defaults to doing nothing
+	Deliver func(payload []byte, signature string) error
+
+	// FailAmounts makes intents for these amounts fail, to exercise error paths
+	FailAmounts map[int64]bool
+
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+	// refunds and payouts already made, by reference, so repeats are no-ops
+	refunds map[string]Refund
+	payouts map[string]Payout
+	seq     atomic.Int64
+	pending sync.WaitGroup
+}
+
+type fakeIntent struct {
+	Intent
+	Amount    int64
+	Reference string
+}
+
+// fakeEvent is the wire format of fake webhooks
+type fakeEvent struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	ObjectID  string `json:"object_id"`
+	Reference string `json:"reference"`
+	Amount    int64  `json:"amount"`
+}
+
+func NewFakeProvider(secret string, delay time.Duration) *FakeProvider {
+	return &FakeProvider{
+		secret:      secret,
+		delay:       delay,
+		FailAmounts: make(map[int64]bool),
+		intents:     make(map[string]*fakeIntent),
+		refunds:     make(map[string]Refund),
+		payouts:     make(map[string]Payout),
+	}
+}
+
+// HTTPDeliverer posts fake webhooks to url, as a real provider would
+func HTTPDeliverer(url string) func(payload []byte, signature string) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(payload []byte, signature string) error {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Fake-Signature", signature)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook endpoint returned %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) nextID(prefix string) string {
+	return fmt.Sprintf("%s_fake_%d", prefix, p.seq.Add(1))
+}
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, req IntentRequest) (Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.nextID("pi")
+	intent := &fakeIntent{
+		Intent:    Intent{ID: id, Status: "requires_confirmation", ClientSecret: id + "_secret"},
+		Amount:    req.Amount,
+		Reference: req.Reference,
+	}
+	p.intents[id] = intent
+	return intent.Intent, nil
+}
+
+func (p *FakeProvider) ConfirmIntent(ctx context.Context, intentID string) (Intent, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		p.mu.Unlock()
+		return Intent{}, ErrUnknownIntent
+	}
+	intent.Status = "processing"
+	result := intent.Intent
+	amount, reference := intent.Amount, intent.Reference
+	fail := p.FailAmounts[amount]
+	p.mu.Unlock()
+
+	// Settle later and report the outcome through a webhook
+	p.emitLater(func() fakeEvent {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		eventType := EventPaymentSucceeded
+		intent.Status = "succeeded"
+		if fail {
+			eventType = EventPaymentFailed
+			intent.Status = "failed"
+		}
+		return fakeEvent{ID: p.nextID("evt"), Type: eventType, ObjectID: intentID, Reference: reference, Amount: amount}
+	})
+
+	return result, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) (Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.intents[req.IntentID]; !ok {
+		return Refund{}, ErrUnknownIntent
+	}
+	if refund, ok := p.refunds[req.Reference]; ok {
+		return refund, nil
+	}
+
+	refund := Refund{ID: p.nextID("re"), Status: "pending"}
+	p.refunds[req.Reference] = refund
+	p.emitLater(func() fakeEvent {
+		return fakeEvent{ID: p.nextID("evt"), Type: EventRefundSucceeded, ObjectID: req.IntentID, Reference: req.Reference, Amount: req.Amount}
+	})
+	return refund, nil
+}
+
+func (p *FakeProvider) Payout(ctx context.Context, req PayoutRequest) (Payout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if payout, ok := p.payouts[req.Reference]; ok {
+		return payout, nil
+	}
+
+	payout := Payout{ID: p.nextID("po"), Status: "pending"}
+	p.payouts[req.Reference] = payout
+	p.emitLater(func() fakeEvent {
+		return fakeEvent{ID: p.nextID("evt"), Type: EventPayoutPaid, ObjectID: payout.ID, Reference: req.Reference, Amount: req.Amount}
+	})
+	return payout, nil
+}
+
+func (p *FakeProvider) VerifyWebhook(payload []byte, header http.Header) (WebhookEvent, error) {
+	if err := VerifySignature(p.secret, payload, header.Get("Fake-Signature"), time.Now()); err != nil {
+		return WebhookEvent{}, err
+	}
+
+	var event fakeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return WebhookEvent{}, err
+	}
+
+	return WebhookEvent{
+		ID:        event.ID,
+		Type:      event.Type,
+		ObjectID:  event.ObjectID,
+		Reference: event.Reference,
+		Amount:    event.Amount,
+	}, nil
+}
+
+// Wait blocks until every scheduled webhook has been delivered
+func (p *FakeProvider) Wait() {
+	p.pending.Wait()
+}
+
+func (p *FakeProvider) emitLater(build func() fakeEvent) {
+	p.pending.Add(1)
+	go func() {
+		defer p.pending.Done()
+		time.Sleep(p.delay)
+
+		payload, err := json.Marshal(build())
+		if err != nil {
+			return
+		}
+		if p.Deliver == nil {
+			return
+		}
+		if err := p.Deliver(payload, Sign(p.secret, payload, time.Now())); err != nil {
+			log.Printf("Fake payment provider failed to deliver webhook: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,129 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"freelance-platform/internal/appenv"
+)
+
+// Webhook event types understood by the platform, normalized across providers
+const (
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentFailed    = "payment.failed"
+	EventRefundSucceeded  = "refund.succeeded"
+	EventPayoutPaid       = "payout.paid"
+	EventPayoutFailed     = "payout.failed"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrUnknownIntent    = errors.New("unknown payment intent")
+)
+
+// IntentRequest asks the provider to prepare a charge
+type IntentRequest struct {
+	Amount    int64  // Amount in TWD
+	Currency  string // ISO code, e.g. TWD
+	Reference string // Our own reference, echoed back in webhooks
+}
+
+// Intent is a charge prepared or executed by the provider
+type Intent struct {
+	ID           string
+	Status       string // requires_confirmation, processing, succeeded, failed
+	ClientSecret string // Handed to the frontend when the provider confirms client-side
+}
+
+type RefundRequest struct {
+	IntentID  string
+	Amount    int64
+	Reference string
+}
+
+type Refund struct {
+	ID     string
+	Status string
+}
+
+// PayoutRequest sends money to a freelancer's account at the provider
+type PayoutRequest struct {
+	Amount      int64
+	Currency    string
+	Destination string // Provider account of the freelancer, e.g. a Stripe Connect account ID
+	Reference   string
+}
+
+type Payout struct {
+	ID     string
+	Status string
+}
+
+// WebhookEvent is a verified, provider-neutral notification
+type WebhookEvent struct {
+	ID        string // Provider event ID; processing is idempotent on it
+	Type      string
+	ObjectID  string // Intent, refund or payout ID the event is about
+	Reference string
+	Amount    int64
+}
+
+// PaymentProvider is implemented by every payment service provider we can work with.
+// Refund and Payout are retried by the worker and must do nothing for a
+// Reference they have already handled.
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (Intent, error)
+	ConfirmIntent(ctx context.Context, intentID string) (Intent, error)
+	Refund(ctx context.Context, req RefundRequest) (Refund, error)
+	Payout(ctx context.Context, req PayoutRequest) (Payout, error)
+	VerifyWebhook(payload []byte, header http.Header) (WebhookEvent, error)
+}
+
+var DefaultProvider PaymentProvider
+
+// DefaultFakeWebhookSecret is the secret older example configs shipped with;
+// anyone can sign fake webhooks with it, so it is refused
+const DefaultFakeWebhookSecret = "fake_webhook_secret"
+
+// Connect selects the provider through PAYMENT_PROVIDER (fake or stripe). It
+// refuses to start without a provider, and with the fake one in production:
+// its webhooks would let anyone fund escrow without paying.
+func Connect() {
+	switch provider := os.Getenv("PAYMENT_PROVIDER"); provider {
+	case "stripe":
+		secretKey := os.Getenv("STRIPE_SECRET_KEY")
+		webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+		if secretKey == "" || webhookSecret == "" {
+			log.Fatal("STRIPE_SECRET_KEY and STRIPE_WEBHOOK_SECRET are required for the stripe payment provider")
+		}
+		DefaultProvider = NewStripeProvider(secretKey, webhookSecret)
+	case "fake":
+		if appenv.IsProduction() {
+			log.Fatal("The fake payment provider cannot be used in production")
+		}
+		secret := os.Getenv("FAKE_PAYMENTS_WEBHOOK_SECRET")
+		if secret == "" || secret == DefaultFakeWebhookSecret {
+			log.Fatal("FAKE_PAYMENTS_WEBHOOK_SECRET must be set to a random value for the fake payment provider")
+		}
+		webhookURL := os.Getenv("FAKE_PAYMENTS_WEBHOOK_URL")
+		if webhookURL == "" {
+			port := os.Getenv("API_PORT")
+			if port == "" {
+				port = "8080"
+			}
+			webhookURL = "http://localhost:" + port + "/api/payments/webhook/fake"
+		}
+		fake := NewFakeProvider(secret, time.Second)
+		fake.Deliver = HTTPDeliverer(webhookURL)
+		DefaultProvider = fake
+	default:
+		log.Fatalf("PAYMENT_PROVIDER must be fake or stripe, got %q", provider)
+	}
+
+	log.Printf("Payment provider: %s", DefaultProvider.Name())
+}
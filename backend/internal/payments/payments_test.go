@@ -0,0 +1,79 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	now := time.Now()
+	payload := []byte(`{"id":"evt_1","type":"payment.succeeded","amount":1000}`)
+	header := Sign("secret", payload, now)
+
+	if err := VerifySignature("secret", payload, header, now); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+
+	cases := map[string]struct {
+		secret  string
+		payload []byte
+		header  string
+		now     time.Time
+	}{
+		"wrong secret":     {"other", payload, header, now},
+		"tampered payload": {"secret", []byte(`{"id":"evt_1","type":"payment.succeeded","amount":1}`), header, now},
+		"missing header":   {"secret", payload, "", now},
+		"no signature":     {"secret", payload, "t=" + header[2:12], now},
+		"replayed":         {"secret", payload, header, now.Add(signatureTolerance + time.Second)},
+		"from the future":  {"secret", payload, header, now.Add(-signatureTolerance - time.Second)},
+	}
+	for name, c := range cases {
+		if err := VerifySignature(c.secret, c.payload, c.header, c.now); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+}
+
+func TestFakeProviderRepeatsAreNoOps(t *testing.T) {
+	provider := NewFakeProvider("secret", 0)
+	delivered := make(chan WebhookEvent, 10)
+	provider.Deliver = func(payload []byte, signature string) error {
+		header := http.Header{}
+		header.Set("Fake-Signature", signature)
+		event, err := provider.VerifyWebhook(payload, header)
+		if err != nil {
+			return err
+		}
+		delivered <- event
+		return nil
+	}
+
+	ctx := context.Background()
+	intent, _ := provider.CreateIntent(ctx, IntentRequest{Amount: 1000, Currency: "TWD", Reference: "payment:1"})
+	refund := RefundRequest{IntentID: intent.ID, Amount: 1000, Reference: "payment:1"}
+	payout := PayoutRequest{Amount: 500, Currency: "TWD", Destination: "acct_1", Reference: "payout:1"}
+
+	first, _ := provider.Refund(ctx, refund)
+	again, _ := provider.Refund(ctx, refund)
+	if first.ID != again.ID {
+		t.Errorf("expected the repeated refund to return %s, got %s", first.ID, again.ID)
+	}
+	sent, _ := provider.Payout(ctx, payout)
+	resent, _ := provider.Payout(ctx, payout)
+	if sent.ID != resent.ID {
+		t.Errorf("expected the repeated payout to return %s, got %s", sent.ID, resent.ID)
+	}
+
+	provider.Wait()
+	close(delivered)
+	var types []string
+	for event := range delivered {
+		types = append(types, event.Type)
+	}
+	if len(types) != 2 {
+		t.Errorf("expected one refund and one payout webhook, got %v", types)
+	}
+}
@@ -0,0 +1,193 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stripeAPIBase = "https://api.stripe.com/v1"
+
+// StripeProvider talks to the Stripe REST API directly
+type StripeProvider struct {
+	secretKey     string
+	webhookSecret string
+	baseURL       string
+	client        *http.Client
+}
+
+func NewStripeProvider(secretKey, webhookSecret string) *StripeProvider {
+	return &StripeProvider{
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		baseURL:       stripeAPIBase,
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p *StripeProvider) Name() string {
+	return "stripe"
+}
+
+// Stripe expresses TWD in hundredths even though only whole dollars can be charged
+func toStripeAmount(amount int64) string {
+	return strconv.FormatInt(amount*100, 10)
+}
+
+func fromStripeAmount(amount int64) int64 {
+	return amount / 100
+}
+
+type stripeObject struct {
+	ID            string            `json:"id"`
+	Status        string            `json:"status"`
+	ClientSecret  string            `json:"client_secret"`
+	Amount        int64             `json:"amount"`
+	PaymentIntent string            `json:"payment_intent"`
+	Metadata      map[string]string `json:"metadata"`
+}
+
+type stripeError struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+func (p *StripeProvider) post(ctx context.Context, path string, form url.Values, idempotencyKey string) (stripeObject, error) {
+	var object stripeObject
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return object, err
+	}
+	req.SetBasicAuth(p.secretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return object, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr stripeError
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return object, fmt.Errorf("stripe %s: %s (%d)", path, apiErr.Error.Message, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&object)
+	return object, err
+}
+
+func (p *StripeProvider) CreateIntent(ctx context.Context, req IntentRequest) (Intent, error) {
+	form := url.Values{}
+	form.Set("amount", toStripeAmount(req.Amount))
+	form.Set("currency", strings.ToLower(req.Currency))
+	form.Set("automatic_payment_methods[enabled]", "true")
+	form.Set("metadata[reference]", req.Reference)
+
+	object, err := p.post(ctx, "/payment_intents", form, "intent:"+req.Reference)
+	if err != nil {
+		return Intent{}, err
+	}
+	return Intent{ID: object.ID, Status: normalizeStripeStatus(object.Status), ClientSecret: object.ClientSecret}, nil
+}
+
+func (p *StripeProvider) ConfirmIntent(ctx context.Context, intentID string) (Intent, error) {
+	object, err := p.post(ctx, "/payment_intents/"+url.PathEscape(intentID)+"/confirm", url.Values{}, "")
+	if err != nil {
+		return Intent{}, err
+	}
+	return Intent{ID: object.ID, Status: normalizeStripeStatus(object.Status)}, nil
+}
+
+func (p *StripeProvider) Refund(ctx context.Context, req RefundRequest) (Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", req.IntentID)
+	form.Set("amount", toStripeAmount(req.Amount))
+	form.Set("metadata[reference]", req.Reference)
+
+	object, err := p.post(ctx, "/refunds", form, "refund:"+req.Reference)
+	if err != nil {
+		return Refund{}, err
+	}
+	return Refund{ID: object.ID, Status: object.Status}, nil
+}
+
+func (p *StripeProvider) Payout(ctx context.Context, req PayoutRequest) (Payout, error) {
+	// Freelancers are paid through Connect transfers to their connected account
+	form := url.Values{}
+	form.Set("amount", toStripeAmount(req.Amount))
+	form.Set("currency", strings.ToLower(req.Currency))
+	form.Set("destination", req.Destination)
+	form.Set("metadata[reference]", req.Reference)
+
+	object, err := p.post(ctx, "/transfers", form, "payout:"+req.Reference)
+	if err != nil {
+		return Payout{}, err
+	}
+	return Payout{ID: object.ID, Status: "pending"}, nil
+}
+
+func (p *StripeProvider) VerifyWebhook(payload []byte, header http.Header) (WebhookEvent, error) {
+	if err := VerifySignature(p.webhookSecret, payload, header.Get("Stripe-Signature"), time.Now()); err != nil {
+		return WebhookEvent{}, err
+	}
+
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object stripeObject `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return WebhookEvent{}, err
+	}
+
+	object := event.Data.Object
+	normalized := WebhookEvent{
+		ID:        event.ID,
+		ObjectID:  object.ID,
+		Reference: object.Metadata["reference"],
+		Amount:    fromStripeAmount(object.Amount),
+	}
+
+	switch event.Type {
+	case "payment_intent.succeeded":
+		normalized.Type = EventPaymentSucceeded
+	case "payment_intent.payment_failed":
+		normalized.Type = EventPaymentFailed
+	case "charge.refunded":
+		normalized.Type = EventRefundSucceeded
+		normalized.ObjectID = object.PaymentIntent
+	case "transfer.created":
+		normalized.Type = EventPayoutPaid
+	case "transfer.reversed":
+		normalized.Type = EventPayoutFailed
+	}
+
+	return normalized, nil
+}
+
+func normalizeStripeStatus(status string) string {
+	switch status {
+	case "succeeded":
+		return "succeeded"
+	case "processing":
+		return "processing"
+	case "canceled":
+		return "failed"
+	default:
+		return "requires_confirmation"
+	}
+}
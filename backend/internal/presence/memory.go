@@ -0,0 +1,76 @@
+package presence
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps presence in process memory; suitable for tests and single-instance setups
+type MemoryStore struct {
+	mu       sync.Mutex
+	conns    map[uint]map[string]time.Time
+	lastSeen map[uint]time.Time
+	now      func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		conns:    make(map[uint]map[string]time.Time),
+		lastSeen: make(map[uint]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) Touch(ctx context.Context, userID uint, connID string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conns[userID] == nil {
+		s.conns[userID] = make(map[string]time.Time)
+	}
+	now := s.now()
+	s.conns[userID][connID] = now.Add(ttl)
+	s.lastSeen[userID] = now
+	return nil
+}
+
+func (s *MemoryStore) Remove(ctx context.Context, userID uint, connID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns[userID], connID)
+	if len(s.conns[userID]) == 0 {
+		delete(s.conns, userID)
+	}
+	s.lastSeen[userID] = s.now()
+	return nil
+}
+
+func (s *MemoryStore) Statuses(ctx context.Context, userIDs []uint) (map[uint]Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	statuses := make(map[uint]Status, len(userIDs))
+	for _, userID := range userIDs {
+		status := Status{UserID: userID}
+
+		// Drop expired connections while checking
+		for connID, expiresAt := range s.conns[userID] {
+			if expiresAt.After(now) {
+				status.Online = true
+			} else {
+				delete(s.conns[userID], connID)
+			}
+		}
+
+		if seen, ok := s.lastSeen[userID]; ok {
+			seen := seen
+			status.LastSeen = &seen
+		}
+		statuses[userID] = status
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,56 @@
+package presence
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"freelance-platform/internal/database"
+)
+
+// Status is what other users see about someone's presence
+type Status struct {
+	UserID   uint       `json:"user_id"`
+	Online   bool       `json:"online"`
+	LastSeen *time.Time `json:"last_seen"`
+}
+
+// Store tracks live connections per user. Every connection must be refreshed
+// within its TTL or it is considered gone, so crashed instances never leave
+// users stuck online.
+type Store interface {
+	// Touch marks a connection as alive until ttl from now
+	Touch(ctx context.Context, userID uint, connID string, ttl time.Duration) error
+	// Remove drops a connection and records the user's last-seen time
+	Remove(ctx context.Context, userID uint, connID string) error
+	// Statuses returns the presence of each requested user
+	Statuses(ctx context.Context, userIDs []uint) (map[uint]Status, error)
+}
+
+var DefaultStore Store
+
+// Connect selects the presence store through PRESENCE_STORE (memory or redis)
+func Connect() {
+	switch os.Getenv("PRESENCE_STORE") {
+	case "redis":
+		DefaultStore = NewRedisStore(database.ConnectRedis())
+	default:
+		DefaultStore = NewMemoryStore()
+	}
+
+	log.Println("Presence store ready")
+}
+
+// TTL returns how long a connection counts as online without a heartbeat, configured via PRESENCE_TTL_SECONDS
+func TTL() time.Duration {
+	seconds := 90
+	if value := os.Getenv("PRESENCE_TTL_SECONDS"); value != "" {
+		if s, err := strconv.Atoi(value); err == nil && s > 0 {
+			seconds = s
+		}
+	}
+
+	return time.Duration(seconds) * time.Second
+}
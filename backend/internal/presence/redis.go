@@ -0,0 +1,78 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// lastSeenRetention is how long a last-seen timestamp is kept after the user goes offline
+const lastSeenRetention = 30 * 24 * time.Hour
+
+// RedisStore shares presence between API instances. Each user has a sorted set of
+// connection IDs scored by their expiry, plus a last-seen timestamp key.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func connsKey(userID uint) string {
+	return fmt.Sprintf("presence:conns:%d", userID)
+}
+
+func lastSeenKey(userID uint) string {
+	return fmt.Sprintf("presence:last_seen:%d", userID)
+}
+
+func (s *RedisStore) Touch(ctx context.Context, userID uint, connID string, ttl time.Duration) error {
+	now := time.Now()
+
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, connsKey(userID), redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: connID})
+	pipe.Expire(ctx, connsKey(userID), ttl)
+	pipe.Set(ctx, lastSeenKey(userID), now.Unix(), lastSeenRetention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) Remove(ctx context.Context, userID uint, connID string) error {
+	pipe := s.client.TxPipeline()
+	pipe.ZRem(ctx, connsKey(userID), connID)
+	pipe.Set(ctx, lastSeenKey(userID), time.Now().Unix(), lastSeenRetention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) Statuses(ctx context.Context, userIDs []uint) (map[uint]Status, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	pipe := s.client.Pipeline()
+	counts := make(map[uint]*redis.IntCmd, len(userIDs))
+	seen := make(map[uint]*redis.StringCmd, len(userIDs))
+	for _, userID := range userIDs {
+		// Only connections that have not expired yet count
+		counts[userID] = pipe.ZCount(ctx, connsKey(userID), "("+now, "+inf")
+		seen[userID] = pipe.Get(ctx, lastSeenKey(userID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	statuses := make(map[uint]Status, len(userIDs))
+	for _, userID := range userIDs {
+		status := Status{UserID: userID, Online: counts[userID].Val() > 0}
+		if unix, err := seen[userID].Int64(); err == nil {
+			lastSeen := time.Unix(unix, 0)
+			status.LastSeen = &lastSeen
+		}
+		statuses[userID] = status
+	}
+
+	return statuses, nil
+}
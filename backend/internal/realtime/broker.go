@@ -0,0 +1,103 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Envelope is what travels through a broker: an event and the users it is addressed to
+type Envelope struct {
+	UserIDs []uint `json:"user_ids"`
+	Event   Event  `json:"event"`
+}
+
+// Broker fans envelopes out to every API instance subscribed to it
+type Broker interface {
+	Publish(ctx context.Context, envelope Envelope) error
+	Subscribe(ctx context.Context, handler func(Envelope)) error
+	Close() error
+}
+
+// MemoryBroker delivers envelopes to subscribers inside the current process only
+type MemoryBroker struct {
+	mu       sync.RWMutex
+	handlers []func(Envelope)
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, envelope Envelope) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, handler := range b.handlers {
+		handler(envelope)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, handler func(Envelope)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
+
+// RedisBroker uses Redis pub/sub so that every API instance receives every envelope
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+	pubsub  *redis.PubSub
+}
+
+func NewRedisBroker(client *redis.Client, channel string) *RedisBroker {
+	return &RedisBroker{client: client, channel: channel}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, envelope Envelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, payload).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, handler func(Envelope)) error {
+	b.pubsub = b.client.Subscribe(ctx, b.channel)
+
+	// Wait for the subscription to be confirmed before returning
+	if _, err := b.pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		for msg := range b.pubsub.Channel() {
+			var envelope Envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				log.Printf("Dropping malformed realtime envelope: %v", err)
+				continue
+			}
+			handler(envelope)
+		}
+	}()
+
+	return nil
+}
+
+func (b *RedisBroker) Close() error {
+	if b.pubsub == nil {
+		return nil
+	}
+	return b.pubsub.Close()
+}
@@ -0,0 +1,199 @@
+package realtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Time allowed to write a frame to the peer
+	writeWait = 10 * time.Second
+
+	// Time allowed to read the next pong from the peer
+	pongWait = 60 * time.Second
+
+	// Pings are sent slightly more often than pongWait
+	pingPeriod = (pongWait * 9) / 10
+
+	// Maximum size of a frame sent by the peer
+	maxMessageSize = 4096
+
+	// Number of pending outgoing frames before a slow client is dropped
+	sendBufferSize = 64
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     AllowedOrigin,
+}
+
+// AllowedOrigin reports whether a websocket request may be upgraded: browsers
+// must be on the frontend (FRONTEND_URL), while clients that send no Origin are
+// not browsers and authenticate with their own token
+func AllowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	frontend, err := url.Parse(frontendURL())
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(origin, frontend.Scheme+"://"+frontend.Host)
+}
+
+func frontendURL() string {
+	if value := os.Getenv("FRONTEND_URL"); value != "" {
+		return value
+	}
+	return "http://localhost:3000"
+}
+
+// Client is a single websocket connection belonging to a user
+type Client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	id     string
+	userID uint
+	send   chan []byte
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// ServeWS upgrades the request and streams events for the user until the connection closes
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, userID uint) error {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	client := &Client{
+		hub:    h,
+		conn:   conn,
+		id:     newConnID(),
+		userID: userID,
+		send:   make(chan []byte, sendBufferSize),
+	}
+
+	h.register(client)
+	if h.hooks.OnConnect != nil {
+		h.hooks.OnConnect(userID, client.id)
+	}
+
+	go client.writePump()
+	client.readPump()
+
+	return nil
+}
+
+// enqueue queues a frame for delivery, dropping the connection if it cannot keep up
+func (c *Client) enqueue(payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	select {
+	case c.send <- payload:
+	default:
+		c.closed = true
+		close(c.send)
+	}
+}
+
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// readPump keeps the connection alive, hands client events to the hooks and
+// notices when the peer goes away
+func (c *Client) readPump() {
+	hooks := c.hub.hooks
+	defer func() {
+		c.hub.unregister(c)
+		c.close()
+		c.conn.Close()
+		if hooks.OnDisconnect != nil {
+			hooks.OnDisconnect(c.userID, c.id)
+		}
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		if hooks.OnHeartbeat != nil {
+			hooks.OnHeartbeat(c.userID, c.id)
+		}
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, payload, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// Ignore anything that is not a well-formed event
+		var event Event
+		if err := json.Unmarshal(payload, &event); err != nil || event.Type == "" {
+			continue
+		}
+		if hooks.OnEvent != nil {
+			hooks.OnEvent(c.userID, event)
+		}
+	}
+}
+
+func newConnID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// writePump sends queued frames and periodic pings to the peer
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// Event is a single push message sent to websocket clients
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Hooks let the application react to connection lifecycle and client-sent events
+type Hooks struct {
+	// OnConnect runs after a connection is registered
+	OnConnect func(userID uint, connID string)
+	// OnHeartbeat runs every time the peer answers a ping
+	OnHeartbeat func(userID uint, connID string)
+	// OnDisconnect runs after a connection is gone
+	OnDisconnect func(userID uint, connID string)
+	// OnEvent runs for every well-formed event the client sends
+	OnEvent func(userID uint, event Event)
+}
+
+// Hub keeps track of the websocket connections open on this instance and
+// delivers envelopes coming from the broker to the users they target
+type Hub struct {
+	broker Broker
+	hooks  Hooks
+
+	mu      sync.RWMutex
+	clients map[uint]map[*Client]struct{}
+}
+
+// NewHub creates a hub and subscribes it to the broker
+func NewHub(broker Broker) (*Hub, error) {
+	h := &Hub{
+		broker:  broker,
+		clients: make(map[uint]map[*Client]struct{}),
+	}
+
+	if err := broker.Subscribe(context.Background(), h.deliver); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Publish sends an event to every connection of the given users, on any instance
+func (h *Hub) Publish(ctx context.Context, userIDs []uint, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return h.broker.Publish(ctx, Envelope{
+		UserIDs: userIDs,
+		Event:   Event{Type: eventType, Data: payload},
+	})
+}
+
+// SetHooks installs the lifecycle hooks; call it before serving connections
+func (h *Hub) SetHooks(hooks Hooks) {
+	h.hooks = hooks
+}
+
+// Close releases the broker subscription
+func (h *Hub) Close() error {
+	return h.broker.Close()
+}
+
+func (h *Hub) register(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.clients[client.userID] == nil {
+		h.clients[client.userID] = make(map[*Client]struct{})
+	}
+	h.clients[client.userID][client] = struct{}{}
+}
+
+func (h *Hub) unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if conns, ok := h.clients[client.userID]; ok {
+		delete(conns, client)
+		if len(conns) == 0 {
+			delete(h.clients, client.userID)
+		}
+	}
+}
+
+// deliver pushes an envelope to the local connections of its recipients
+func (h *Hub) deliver(envelope Envelope) {
+	payload, err := json.Marshal(envelope.Event)
+	if err != nil {
+		log.Printf("Failed to encode realtime event: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, userID := range envelope.UserIDs {
+		for client := range h.clients[userID] {
+			client.enqueue(payload)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"freelance-platform/internal/database"
+)
+
+// Event types pushed to connected users
+const (
+	EventMessageCreated = "message.created"
+	EventMessagesRead   = "messages.read"
+	EventUnreadCount    = "unread.count"
+	EventTyping         = "typing"
+	EventPresence       = "presence"
+
+	// Notification center events
+	EventNotification      = "notification"
+	EventNotificationCount = "notification.unread_count"
+)
+
+var DefaultHub *Hub
+
+// Connect creates the process-wide hub using the broker selected by REALTIME_BROKER (memory or redis)
+func Connect() {
+	var broker Broker
+	switch os.Getenv("REALTIME_BROKER") {
+	case "redis":
+		channel := os.Getenv("REALTIME_CHANNEL")
+		if channel == "" {
+			channel = "freelance:realtime"
+		}
+		broker = NewRedisBroker(database.ConnectRedis(), channel)
+	default:
+		broker = NewMemoryBroker()
+	}
+
+	hub, err := NewHub(broker)
+	if err != nil {
+		log.Fatal("Failed to start realtime hub:", err)
+	}
+
+	DefaultHub = hub
+	log.Println("Realtime hub started")
+}
+
+// Publish sends an event to the given users through the default hub. Delivery is
+// best effort: failures are logged and never fail the calling request.
+func Publish(userIDs []uint, eventType string, data interface{}) {
+	if DefaultHub == nil {
+		return
+	}
+
+	if err := DefaultHub.Publish(context.Background(), userIDs, eventType, data); err != nil {
+		log.Printf("Failed to publish %s event: %v", eventType, err)
+	}
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BidRepository interface {
+	Create(ctx context.Context, bid *models.Bid) error
+	// FindActive returns the freelancer's bid on a project that is neither withdrawn nor expired.
+	// A pending bid past its expiry is not active, even before ExpireStale has run.
+	FindActive(ctx context.Context, projectID, freelancerID uint) (models.Bid, error)
+	// ListByProject returns a page of a project's bids, newest first, optionally only those in status.
+	// A pending bid past its expiry counts as expired even before ExpireStale has run.
+	ListByProject(ctx context.Context, projectID uint, status string, page pagination.Page) ([]models.Bid, pagination.Meta, error)
+	// BidderIDs returns the freelancers with a bid on the project in one of the statuses
+	BidderIDs(ctx context.Context, projectID uint, statuses ...string) ([]uint, error)
+	// FindForUpdate loads a bid and locks it until the transaction ends
+	FindForUpdate(ctx context.Context, id uint) (models.Bid, error)
+	// LoadDetails reloads the bid together with its project and freelancer
+	LoadDetails(ctx context.Context, bid *models.Bid) error
+	UpdateStatus(ctx context.Context, bid *models.Bid, status string) error
+	// RejectCompeting rejects the other pending bids on the bid's project and
+	// returns the freelancers who placed them
+	RejectCompeting(ctx context.Context, bid models.Bid) ([]uint, error)
+	// Revise keeps the bid's current terms as a revision, then overwrites them
+	Revise(ctx context.Context, bid *models.Bid, amount int, proposal, timeline string) error
+	// ListRevisions returns the bid's previous versions, newest first
+	ListRevisions(ctx context.Context, bidID uint) ([]models.BidRevision, error)
+	// ExpireStale marks pending bids whose expiry is at or before now as expired
+	ExpireStale(ctx context.Context, now time.Time) (int64, error)
+}
+
+type bidRepository struct {
+	db *gorm.DB
+}
+
+func (r *bidRepository) Create(ctx context.Context, bid *models.Bid) error {
+	return r.db.WithContext(ctx).Create(bid).Error
+}
+
+func (r *bidRepository) FindActive(ctx context.Context, projectID, freelancerID uint) (models.Bid, error) {
+	var bid models.Bid
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND freelancer_id = ? AND status NOT IN ?", projectID, freelancerID, []string{"withdrawn", "expired"}).
+		Where("status <> ? OR expires_at IS NULL OR expires_at > ?", "pending", time.Now()).
+		First(&bid).Error
+	return bid, translate(err)
+}
+
+func (r *bidRepository) ListByProject(ctx context.Context, projectID uint, status string, page pagination.Page) ([]models.Bid, pagination.Meta, error) {
+	scope := func(query *gorm.DB) *gorm.DB {
+		query = query.Where("project_id = ?", projectID)
+		switch status {
+		case "":
+		case "pending":
+			query = query.Where("status = ? AND (expires_at IS NULL OR expires_at > ?)", status, time.Now())
+		case "expired":
+			query = query.Where("status = ? OR (status = ? AND expires_at <= ?)", status, "pending", time.Now())
+		default:
+			query = query.Where("status = ?", status)
+		}
+		return query
+	}
+
+	query := r.db.WithContext(ctx).Preload("Freelancer").
+		Preload("Revisions", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC") })
+
+	var bids []models.Bid
+	if err := page.Apply(scope(query), pagination.Order{Time: "created_at", ID: "id"}).Find(&bids).Error; err != nil {
+		return nil, pagination.Meta{}, err
+	}
+
+	bids, meta := pagination.Finish(page, bids, func(b models.Bid) pagination.Cursor {
+		return pagination.Cursor{Time: b.CreatedAt, ID: b.ID}
+	})
+	err := page.Total(scope(r.db.WithContext(ctx).Model(&models.Bid{})), &meta)
+	return bids, meta, err
+}
+
+func (r *bidRepository) BidderIDs(ctx context.Context, projectID uint, statuses ...string) ([]uint, error) {
+	var freelancerIDs []uint
+	err := r.db.WithContext(ctx).Model(&models.Bid{}).
+		Where("project_id = ? AND status IN ?", projectID, statuses).
+		Distinct().
+		Pluck("freelancer_id", &freelancerIDs).Error
+	return freelancerIDs, err
+}
+
+func (r *bidRepository) FindForUpdate(ctx context.Context, id uint) (models.Bid, error) {
+	var bid models.Bid
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&bid, id).Error
+	return bid, translate(err)
+}
+
+func (r *bidRepository) LoadDetails(ctx context.Context, bid *models.Bid) error {
+	return translate(r.db.WithContext(ctx).Preload("Project").Preload("Freelancer").First(bid, bid.ID).Error)
+}
+
+func (r *bidRepository) UpdateStatus(ctx context.Context, bid *models.Bid, status string) error {
+	return r.db.WithContext(ctx).Model(bid).Update("status", status).Error
+}
+
+func (r *bidRepository) RejectCompeting(ctx context.Context, bid models.Bid) ([]uint, error) {
+	competing := r.db.WithContext(ctx).Model(&models.Bid{}).
+		Where("project_id = ? AND id != ? AND status = ?", bid.ProjectID, bid.ID, "pending").
+		Session(&gorm.Session{})
+
+	var freelancerIDs []uint
+	if err := competing.Pluck("freelancer_id", &freelancerIDs).Error; err != nil {
+		return nil, err
+	}
+	return freelancerIDs, competing.Update("status", "rejected").Error
+}
+
+func (r *bidRepository) Revise(ctx context.Context, bid *models.Bid, amount int, proposal, timeline string) error {
+	revision := models.BidRevision{
+		BidID:    bid.ID,
+		Amount:   bid.Amount,
+		Proposal: bid.Proposal,
+		Timeline: bid.Timeline,
+	}
+	if err := r.db.WithContext(ctx).Create(&revision).Error; err != nil {
+		return err
+	}
+
+	return r.db.WithContext(ctx).Model(bid).Updates(map[string]interface{}{
+		"amount":   amount,
+		"proposal": proposal,
+		"timeline": timeline,
+	}).Error
+}
+
+func (r *bidRepository) ListRevisions(ctx context.Context, bidID uint) ([]models.BidRevision, error) {
+	var revisions []models.BidRevision
+	err := r.db.WithContext(ctx).Where("bid_id = ?", bidID).Order("created_at DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *bidRepository) ExpireStale(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Bid{}).
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", "pending", now).
+		Update("status", "expired")
+	return result.RowsAffected, result.Error
+}
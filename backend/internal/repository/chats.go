@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/pagination"
+
+	"gorm.io/gorm"
+)
+
+type ChatRepository interface {
+	// ListVisible returns a page of the chats the user takes part in and hasn't
+	// hidden, most recently active first
+	ListVisible(ctx context.Context, user models.User, page pagination.Page) ([]models.Chat, pagination.Meta, error)
+	// VisibleIDs returns the IDs of the chats ListVisible would return
+	VisibleIDs(ctx context.Context, user models.User) ([]uint, error)
+	FindByID(ctx context.Context, id uint) (models.Chat, error)
+	FindBetween(ctx context.Context, projectID, clientID, freelancerID uint) (models.Chat, error)
+	ListByProject(ctx context.Context, projectID uint) ([]models.Chat, error)
+	Create(ctx context.Context, chat *models.Chat) error
+	Save(ctx context.Context, chat *models.Chat) error
+	// Touch moves the chat's updated_at forward to at
+	Touch(ctx context.Context, chat *models.Chat, at time.Time) error
+	// LoadParties reloads the chat together with its project, client and freelancer
+	LoadParties(ctx context.Context, chat *models.Chat) error
+	// Delete removes the chat and all of its messages
+	Delete(ctx context.Context, chat *models.Chat) error
+}
+
+type chatRepository struct {
+	db *gorm.DB
+}
+
+// visibleTo scopes a chat query to the side of the chat the user is on
+func visibleTo(query *gorm.DB, user models.User) *gorm.DB {
+	if user.Role == "client" {
+		return query.Where("client_id = ? AND client_hidden = ?", user.ID, false)
+	}
+	return query.Where("freelancer_id = ? AND freelancer_hidden = ?", user.ID, false)
+}
+
+func (r *chatRepository) ListVisible(ctx context.Context, user models.User, page pagination.Page) ([]models.Chat, pagination.Meta, error) {
+	query := visibleTo(r.db.WithContext(ctx).Preload("Project").Preload("Client").Preload("Freelancer"), user)
+
+	// Chats are paged by last activity rather than creation, which is the
+	// order people expect their inbox in
+	var chats []models.Chat
+	if err := page.Apply(query, pagination.Order{Time: "updated_at", ID: "id"}).Find(&chats).Error; err != nil {
+		return nil, pagination.Meta{}, err
+	}
+
+	chats, meta := pagination.Finish(page, chats, func(c models.Chat) pagination.Cursor {
+		return pagination.Cursor{Time: c.UpdatedAt, ID: c.ID}
+	})
+	err := page.Total(visibleTo(r.db.WithContext(ctx).Model(&models.Chat{}), user), &meta)
+	return chats, meta, err
+}
+
+func (r *chatRepository) VisibleIDs(ctx context.Context, user models.User) ([]uint, error) {
+	var chatIDs []uint
+	err := visibleTo(r.db.WithContext(ctx).Model(&models.Chat{}), user).Pluck("id", &chatIDs).Error
+	return chatIDs, err
+}
+
+func (r *chatRepository) FindByID(ctx context.Context, id uint) (models.Chat, error) {
+	var chat models.Chat
+	err := r.db.WithContext(ctx).First(&chat, id).Error
+	return chat, translate(err)
+}
+
+func (r *chatRepository) FindBetween(ctx context.Context, projectID, clientID, freelancerID uint) (models.Chat, error) {
+	var chat models.Chat
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND client_id = ? AND freelancer_id = ?", projectID, clientID, freelancerID).
+		First(&chat).Error
+	return chat, translate(err)
+}
+
+func (r *chatRepository) ListByProject(ctx context.Context, projectID uint) ([]models.Chat, error) {
+	var chats []models.Chat
+	err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Find(&chats).Error
+	return chats, err
+}
+
+func (r *chatRepository) Create(ctx context.Context, chat *models.Chat) error {
+	return r.db.WithContext(ctx).Create(chat).Error
+}
+
+func (r *chatRepository) Save(ctx context.Context, chat *models.Chat) error {
+	return r.db.WithContext(ctx).Save(chat).Error
+}
+
+func (r *chatRepository) Touch(ctx context.Context, chat *models.Chat, at time.Time) error {
+	return r.db.WithContext(ctx).Model(chat).Update("updated_at", at).Error
+}
+
+func (r *chatRepository) LoadParties(ctx context.Context, chat *models.Chat) error {
+	return translate(r.db.WithContext(ctx).Preload("Project").Preload("Client").Preload("Freelancer").First(chat, chat.ID).Error)
+}
+
+func (r *chatRepository) Delete(ctx context.Context, chat *models.Chat) error {
+	if err := r.db.WithContext(ctx).Where("chat_id = ?", chat.ID).Delete(&models.Message{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Delete(chat).Error
+}
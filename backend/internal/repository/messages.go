@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/pagination"
+
+	"gorm.io/gorm"
+)
+
+type MessageRepository interface {
+	// ListByChat returns a page of a chat's messages with their senders. Pages
+	// go back in time from the newest message, but each page is oldest first.
+	ListByChat(ctx context.Context, chatID uint, page pagination.Page) ([]models.Message, pagination.Meta, error)
+	Create(ctx context.Context, message *models.Message) error
+	// LoadSender reloads the message together with its sender
+	LoadSender(ctx context.Context, message *models.Message) error
+	// CountUnread counts messages in the chats that the reader hasn't read and didn't send
+	CountUnread(ctx context.Context, chatIDs []uint, readerID uint) (int64, error)
+	// MarkRead marks every message in the chat not sent by the reader as read at
+	MarkRead(ctx context.Context, chatID, readerID uint, at time.Time) error
+}
+
+type messageRepository struct {
+	db *gorm.DB
+}
+
+func (r *messageRepository) ListByChat(ctx context.Context, chatID uint, page pagination.Page) ([]models.Message, pagination.Meta, error) {
+	query := r.db.WithContext(ctx).Preload("Sender").Where("chat_id = ?", chatID)
+
+	var messages []models.Message
+	if err := page.Apply(query, pagination.Order{Time: "created_at", ID: "id"}).Find(&messages).Error; err != nil {
+		return nil, pagination.Meta{}, err
+	}
+
+	messages, meta := pagination.Finish(page, messages, func(m models.Message) pagination.Cursor {
+		return pagination.Cursor{Time: m.CreatedAt, ID: m.ID}
+	})
+	slices.Reverse(messages)
+
+	err := page.Total(r.db.WithContext(ctx).Model(&models.Message{}).Where("chat_id = ?", chatID), &meta)
+	return messages, meta, err
+}
+
+func (r *messageRepository) Create(ctx context.Context, message *models.Message) error {
+	return r.db.WithContext(ctx).Create(message).Error
+}
+
+func (r *messageRepository) LoadSender(ctx context.Context, message *models.Message) error {
+	return translate(r.db.WithContext(ctx).Preload("Sender").First(message, message.ID).Error)
+}
+
+func (r *messageRepository) CountUnread(ctx context.Context, chatIDs []uint, readerID uint) (int64, error) {
+	var count int64
+	if len(chatIDs) == 0 {
+		return 0, nil
+	}
+	err := r.db.WithContext(ctx).Model(&models.Message{}).
+		Where("chat_id IN ? AND sender_id != ? AND read_at IS NULL", chatIDs, readerID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *messageRepository) MarkRead(ctx context.Context, chatID, readerID uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Message{}).
+		Where("chat_id = ? AND sender_id != ? AND read_at IS NULL", chatID, readerID).
+		Update("read_at", at).Error
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationPreferenceRepository interface {
+	// Channels returns the channels the user picked, by event type
+	Channels(ctx context.Context, userID uint) (map[string]string, error)
+	// SetChannels stores the channels for the given event types, leaving the others alone
+	SetChannels(ctx context.Context, userID uint, channels map[string]string) error
+	// UserIDsWithChannel returns the users who picked channel for an event type
+	UserIDsWithChannel(ctx context.Context, eventType, channel string) ([]uint, error)
+	// Settings returns the user's settings; users who never saved any get a zero value with only UserID set
+	Settings(ctx context.Context, userID uint) (models.NotificationSetting, error)
+	SaveSettings(ctx context.Context, settings *models.NotificationSetting) error
+	// MarkDigestSent records when the user's last digest went out
+	MarkDigestSent(ctx context.Context, userID uint, at time.Time) error
+}
+
+type notificationPreferenceRepository struct {
+	db *gorm.DB
+}
+
+func (r *notificationPreferenceRepository) Channels(ctx context.Context, userID uint) (map[string]string, error) {
+	var preferences []models.NotificationPreference
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+
+	channels := make(map[string]string, len(preferences))
+	for _, preference := range preferences {
+		channels[preference.EventType] = preference.Channel
+	}
+	return channels, nil
+}
+
+func (r *notificationPreferenceRepository) SetChannels(ctx context.Context, userID uint, channels map[string]string) error {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	preferences := make([]models.NotificationPreference, 0, len(channels))
+	for eventType, channel := range channels {
+		preferences = append(preferences, models.NotificationPreference{UserID: userID, EventType: eventType, Channel: channel})
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "event_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channel", "updated_at"}),
+	}).Create(&preferences).Error
+}
+
+func (r *notificationPreferenceRepository) UserIDsWithChannel(ctx context.Context, eventType, channel string) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.NotificationPreference{}).
+		Where("event_type = ? AND channel = ?", eventType, channel).
+		Order("user_id").
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func (r *notificationPreferenceRepository) Settings(ctx context.Context, userID uint) (models.NotificationSetting, error) {
+	settings := models.NotificationSetting{UserID: userID}
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&settings).Error
+	return settings, err
+}
+
+func (r *notificationPreferenceRepository) SaveSettings(ctx context.Context, settings *models.NotificationSetting) error {
+	return r.db.WithContext(ctx).Save(settings).Error
+}
+
+func (r *notificationPreferenceRepository) MarkDigestSent(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_digest_at"}),
+	}).Create(&models.NotificationSetting{UserID: userID, LastDigestAt: &at}).Error
+}
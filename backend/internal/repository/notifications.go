@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/pagination"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	Save(ctx context.Context, notification *models.Notification) error
+	// FindUnreadForChat returns the user's unread notification of a type about a chat
+	FindUnreadForChat(ctx context.Context, userID uint, notificationType string, chatID uint) (models.Notification, error)
+	// ListByUser returns a page of the user's notifications, newest first
+	ListByUser(ctx context.Context, userID uint, unreadOnly bool, page pagination.Page) ([]models.Notification, pagination.Meta, error)
+	// MarkRead marks one of the user's notifications as read at; other users' notifications are not found
+	MarkRead(ctx context.Context, userID, id uint, at time.Time) (models.Notification, error)
+	// MarkAllRead marks every unread notification of the user as read at and returns how many changed
+	MarkAllRead(ctx context.Context, userID uint, at time.Time) (int64, error)
+	// MarkChatRead marks the user's unread notifications of a type about a chat as read at and returns how many changed
+	MarkChatRead(ctx context.Context, userID uint, notificationType string, chatID uint, at time.Time) (int64, error)
+	CountUnread(ctx context.Context, userID uint) (int64, error)
+	// PendingEmailUserIDs returns the users with unread notifications still waiting to go out by email
+	PendingEmailUserIDs(ctx context.Context) ([]uint, error)
+	// ListPendingEmail returns the user's unread notifications still waiting to go out by email, oldest first
+	ListPendingEmail(ctx context.Context, userID uint) ([]models.Notification, error)
+	// MarkEmailed records that the notifications went out by email at
+	MarkEmailed(ctx context.Context, ids []uint, at time.Time) error
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func (r *notificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	return r.db.WithContext(ctx).Create(notification).Error
+}
+
+func (r *notificationRepository) Save(ctx context.Context, notification *models.Notification) error {
+	return r.db.WithContext(ctx).Save(notification).Error
+}
+
+func (r *notificationRepository) FindUnreadForChat(ctx context.Context, userID uint, notificationType string, chatID uint) (models.Notification, error) {
+	var notification models.Notification
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND type = ? AND chat_id = ? AND read_at IS NULL", userID, notificationType, chatID).
+		Order("created_at DESC").
+		First(&notification).Error
+	return notification, translate(err)
+}
+
+func (r *notificationRepository) ListByUser(ctx context.Context, userID uint, unreadOnly bool, page pagination.Page) ([]models.Notification, pagination.Meta, error) {
+	scope := func(query *gorm.DB) *gorm.DB {
+		query = query.Where("user_id = ?", userID)
+		if unreadOnly {
+			query = query.Where("read_at IS NULL")
+		}
+		return query
+	}
+
+	var notifications []models.Notification
+	query := page.Apply(scope(r.db.WithContext(ctx)), pagination.Order{Time: "created_at", ID: "id"})
+	if err := query.Find(&notifications).Error; err != nil {
+		return nil, pagination.Meta{}, err
+	}
+
+	notifications, meta := pagination.Finish(page, notifications, func(n models.Notification) pagination.Cursor {
+		return pagination.Cursor{Time: n.CreatedAt, ID: n.ID}
+	})
+	err := page.Total(scope(r.db.WithContext(ctx).Model(&models.Notification{})), &meta)
+	return notifications, meta, err
+}
+
+func (r *notificationRepository) MarkRead(ctx context.Context, userID, id uint, at time.Time) (models.Notification, error) {
+	var notification models.Notification
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&notification, id).Error; err != nil {
+		return notification, translate(err)
+	}
+
+	if notification.ReadAt != nil {
+		return notification, nil
+	}
+	notification.ReadAt = &at
+	err := r.db.WithContext(ctx).Model(&notification).Update("read_at", at).Error
+	return notification, err
+}
+
+func (r *notificationRepository) MarkAllRead(ctx context.Context, userID uint, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+func (r *notificationRepository) MarkChatRead(ctx context.Context, userID uint, notificationType string, chatID uint, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND type = ? AND chat_id = ? AND read_at IS NULL", userID, notificationType, chatID).
+		Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+func (r *notificationRepository) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// pendingEmail matches the rows covered by idx_notifications_email_pending
+const pendingEmail = "send_email AND emailed_at IS NULL AND read_at IS NULL"
+
+func (r *notificationRepository) PendingEmailUserIDs(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where(pendingEmail).
+		Distinct("user_id").
+		Order("user_id").
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func (r *notificationRepository) ListPendingEmail(ctx context.Context, userID uint) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where(pendingEmail).
+		Order("id").
+		Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *notificationRepository) MarkEmailed(ctx context.Context, ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.Notification{}).Where("id IN ?", ids).Update("emailed_at", at).Error
+}
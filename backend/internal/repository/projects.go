@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProjectFilter narrows a project listing; zero values mean no filter
+type ProjectFilter struct {
+	// IncludeClosed lists every project that isn't deleted instead of only open ones
+	IncludeClosed bool
+	Category      string
+	Location      string
+	Urgency       string
+	MinBudget     *int
+	MaxBudget     *int
+	Search        string
+}
+
+// FacetCount is how many projects have one value of a field
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// BudgetBucket is a budget range in TWD; Max is inclusive and nil for the top bucket
+type BudgetBucket struct {
+	Min int  `json:"min"`
+	Max *int `json:"max"`
+}
+
+type BudgetFacet struct {
+	BudgetBucket
+	Count int64 `json:"count"`
+}
+
+// BudgetBuckets are the ranges the budget facet counts; Min and Max can be
+// sent back as min_budget and max_budget
+var BudgetBuckets = []BudgetBucket{
+	{Min: 0, Max: intPtr(9999)},
+	{Min: 10000, Max: intPtr(49999)},
+	{Min: 50000, Max: intPtr(99999)},
+	{Min: 100000, Max: intPtr(199999)},
+	{Min: 200000},
+}
+
+// ProjectFacets counts the projects per filter value, each under all the
+// other active filters
+type ProjectFacets struct {
+	Categories []FacetCount  `json:"category"`
+	Locations  []FacetCount  `json:"location"`
+	Urgencies  []FacetCount  `json:"urgency"`
+	Budgets    []BudgetFacet `json:"budget"`
+}
+
+type ProjectRepository interface {
+	// List returns a page of the matching projects, newest first or, when
+	// searching, most relevant first
+	List(ctx context.Context, filter ProjectFilter, page pagination.Page) ([]models.Project, pagination.Meta, error)
+	// Facets counts the projects matching filter by category, location, urgency and budget bucket
+	Facets(ctx context.Context, filter ProjectFilter) (ProjectFacets, error)
+	FindByID(ctx context.Context, id uint) (models.Project, error)
+	// FindForUpdate loads a project and locks it until the transaction ends
+	FindForUpdate(ctx context.Context, id uint) (models.Project, error)
+	// FindWithBids loads the project with its client, freelancer and bids
+	FindWithBids(ctx context.Context, id uint) (models.Project, error)
+	Create(ctx context.Context, project *models.Project) error
+	Save(ctx context.Context, project *models.Project) error
+	// LoadParties reloads the project together with its client and freelancer
+	LoadParties(ctx context.Context, project *models.Project) error
+	HasContract(ctx context.Context, projectID uint) (bool, error)
+	// ListMatching returns open projects created after since that ask for any
+	// of the skills, newest first, leaving out the user's own projects
+	ListMatching(ctx context.Context, userID uint, skills []string, since time.Time, limit int) ([]models.Project, error)
+}
+
+type projectRepository struct {
+	db *gorm.DB
+}
+
+func (r *projectRepository) List(ctx context.Context, filter ProjectFilter, page pagination.Page) ([]models.Project, pagination.Meta, error) {
+	query := filtered(r.db.WithContext(ctx), filter).Preload("Client").Preload("Freelancer").Preload("Bids")
+
+	order := pagination.Order{Time: "projects.created_at", ID: "projects.id"}
+	if filter.Search != "" {
+		order.Score, order.ScoreArgs = searchRank, []interface{}{filter.Search}
+		query = query.Select("projects.*, "+searchRank+" AS search_rank", filter.Search)
+	}
+
+	var projects []models.Project
+	if err := page.Apply(query, order).Find(&projects).Error; err != nil {
+		return nil, pagination.Meta{}, err
+	}
+
+	projects, meta := pagination.Finish(page, projects, func(p models.Project) pagination.Cursor {
+		cursor := pagination.Cursor{Time: p.CreatedAt, ID: p.ID}
+		if filter.Search != "" {
+			cursor.Score = &p.SearchRank
+		}
+		return cursor
+	})
+	err := page.Total(filtered(r.db.WithContext(ctx).Model(&models.Project{}), filter), &meta)
+	return projects, meta, err
+}
+
+// searchRank scores a search match by relevance; it takes the search text as
+// its argument and needs the search_query joined by searchProjects
+const searchRank = "(ts_rank_cd(search_vector, search_query, 32) + 0.1 * word_similarity(?, title))::float8"
+
+// filtered restricts query to the projects matching filter
+func filtered(query *gorm.DB, filter ProjectFilter) *gorm.DB {
+	if filter.IncludeClosed {
+		query = query.Where("status != ?", "deleted")
+	} else {
+		query = query.Where("status = ?", "open")
+	}
+
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Location != "" {
+		query = query.Where("location = ?", filter.Location)
+	}
+	if filter.Urgency != "" {
+		query = query.Where("urgency = ?", filter.Urgency)
+	}
+
+	// A project matches a budget range when the two ranges overlap
+	if filter.MinBudget != nil {
+		query = query.Where("budget_max >= ?", *filter.MinBudget)
+	}
+	if filter.MaxBudget != nil {
+		query = query.Where("budget_min <= ?", *filter.MaxBudget)
+	}
+
+	if filter.Search != "" {
+		query = searchProjects(query, filter.Search)
+	}
+	return query
+}
+
+// searchProjects matches the full-text index maintained by the
+// projects_search_vector trigger; the query is available as search_query for
+// ranking. Terms the bigram index can't match, like a single Chinese character
+// or part of a word, are still found through the trigram indexes.
+func searchProjects(query *gorm.DB, search string) *gorm.DB {
+	pattern := "%" + escapeLike(search) + "%"
+	return query.
+		Joins("CROSS JOIN plainto_tsquery('simple', cjk_bigrams(?)) AS search_query", search).
+		Where("(search_vector @@ search_query OR title ILIKE ? OR description ILIKE ? OR skills ILIKE ?)",
+			pattern, pattern, pattern)
+}
+
+func (r *projectRepository) Facets(ctx context.Context, filter ProjectFilter) (ProjectFacets, error) {
+	var facets ProjectFacets
+
+	// Each facet ignores its own filter, so it lists the alternatives to the current choice
+	countBy := func(column string, without ProjectFilter, counts *[]FacetCount) error {
+		*counts = []FacetCount{}
+		return filtered(r.db.WithContext(ctx).Model(&models.Project{}), without).
+			Select(column + " AS value, count(*) AS count").
+			Group(column).
+			Order("count DESC, value").
+			Scan(counts).Error
+	}
+
+	withoutCategory := filter
+	withoutCategory.Category = ""
+	if err := countBy("category", withoutCategory, &facets.Categories); err != nil {
+		return facets, err
+	}
+
+	withoutLocation := filter
+	withoutLocation.Location = ""
+	if err := countBy("location", withoutLocation, &facets.Locations); err != nil {
+		return facets, err
+	}
+
+	withoutUrgency := filter
+	withoutUrgency.Urgency = ""
+	if err := countBy("urgency", withoutUrgency, &facets.Urgencies); err != nil {
+		return facets, err
+	}
+
+	withoutBudget := filter
+	withoutBudget.MinBudget, withoutBudget.MaxBudget = nil, nil
+
+	// One pass counts every bucket, with the same overlap rule as the budget filter
+	columns := make([]string, len(BudgetBuckets))
+	var args []interface{}
+	for i, bucket := range BudgetBuckets {
+		condition := "budget_max >= ?"
+		args = append(args, bucket.Min)
+		if bucket.Max != nil {
+			condition += " AND budget_min <= ?"
+			args = append(args, *bucket.Max)
+		}
+		columns[i] = fmt.Sprintf("count(*) FILTER (WHERE %s)", condition)
+	}
+
+	row := filtered(r.db.WithContext(ctx).Model(&models.Project{}), withoutBudget).
+		Select(strings.Join(columns, ", "), args...).
+		Row()
+	counts := make([]int64, len(BudgetBuckets))
+	targets := make([]interface{}, len(counts))
+	for i := range counts {
+		targets[i] = &counts[i]
+	}
+	if err := row.Scan(targets...); err != nil {
+		return facets, err
+	}
+
+	facets.Budgets = make([]BudgetFacet, len(BudgetBuckets))
+	for i, bucket := range BudgetBuckets {
+		facets.Budgets[i] = BudgetFacet{BudgetBucket: bucket, Count: counts[i]}
+	}
+	return facets, nil
+}
+
+// escapeLike makes the LIKE wildcards in s match literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *projectRepository) FindByID(ctx context.Context, id uint) (models.Project, error) {
+	var project models.Project
+	err := r.db.WithContext(ctx).First(&project, id).Error
+	return project, translate(err)
+}
+
+func (r *projectRepository) FindForUpdate(ctx context.Context, id uint) (models.Project, error) {
+	var project models.Project
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, id).Error
+	return project, translate(err)
+}
+
+func (r *projectRepository) FindWithBids(ctx context.Context, id uint) (models.Project, error) {
+	var project models.Project
+	err := r.db.WithContext(ctx).Preload("Client").Preload("Freelancer").Preload("Bids.Freelancer").First(&project, id).Error
+	return project, translate(err)
+}
+
+func (r *projectRepository) Create(ctx context.Context, project *models.Project) error {
+	return r.db.WithContext(ctx).Create(project).Error
+}
+
+func (r *projectRepository) Save(ctx context.Context, project *models.Project) error {
+	return r.db.WithContext(ctx).Save(project).Error
+}
+
+func (r *projectRepository) LoadParties(ctx context.Context, project *models.Project) error {
+	return translate(r.db.WithContext(ctx).Preload("Client").Preload("Freelancer").First(project, project.ID).Error)
+}
+
+func (r *projectRepository) HasContract(ctx context.Context, projectID uint) (bool, error) {
+	var contracts int64
+	err := r.db.WithContext(ctx).Model(&models.Contract{}).Where("project_id = ?", projectID).Count(&contracts).Error
+	return contracts > 0, err
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func (r *projectRepository) ListMatching(ctx context.Context, userID uint, skills []string, since time.Time, limit int) ([]models.Project, error) {
+	if len(skills) == 0 {
+		return nil, nil
+	}
+
+	conditions := r.db.Where("skills ILIKE ?", "%"+escapeLike(skills[0])+"%")
+	for _, skill := range skills[1:] {
+		conditions = conditions.Or("skills ILIKE ?", "%"+escapeLike(skill)+"%")
+	}
+
+	var projects []models.Project
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND client_id != ? AND created_at > ?", "open", userID, since).
+		Where(conditions).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&projects).Error
+	return projects, err
+}
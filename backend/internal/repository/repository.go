@@ -0,0 +1,64 @@
+// Package repository wraps database access for the core marketplace models
+// behind interfaces, so handlers and services can be handed either the shared
+// connection or a transaction.
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a lookup matches no row
+var ErrNotFound = errors.New("record not found")
+
+// Repositories bundles every repository bound to the same connection or transaction
+type Repositories struct {
+	Users         UserRepository
+	Projects      ProjectRepository
+	Bids          BidRepository
+	Chats         ChatRepository
+	Messages      MessageRepository
+	Notifications NotificationRepository
+	Preferences   NotificationPreferenceRepository
+	Webhooks      WebhookRepository
+
+	db *gorm.DB
+}
+
+// New returns GORM backed repositories using db
+func New(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:         &userRepository{db: db},
+		Projects:      &projectRepository{db: db},
+		Bids:          &bidRepository{db: db},
+		Chats:         &chatRepository{db: db},
+		Messages:      &messageRepository{db: db},
+		Notifications: &notificationRepository{db: db},
+		Preferences:   &notificationPreferenceRepository{db: db},
+		Webhooks:      &webhookRepository{db: db},
+		db:            db,
+	}
+}
+
+// DB exposes the underlying connection for code that has no repository yet
+func (r *Repositories) DB() *gorm.DB {
+	return r.db
+}
+
+// Transaction runs fn with repositories bound to a single transaction,
+// committing when fn returns nil
+func (r *Repositories) Transaction(ctx context.Context, fn func(tx *Repositories) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(New(tx))
+	})
+}
+
+// translate maps GORM's not-found error to ErrNotFound
+func translate(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
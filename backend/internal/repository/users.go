@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"freelance-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	FindByID(ctx context.Context, id uint) (models.User, error)
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+	return user, translate(err)
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	Save(ctx context.Context, webhook *models.Webhook) error
+	// Delete removes the webhook along with its deliveries
+	Delete(ctx context.Context, webhook *models.Webhook) error
+	// FindForUser returns one of the user's webhooks; other users' webhooks are not found
+	FindForUser(ctx context.Context, userID, id uint) (models.Webhook, error)
+	FindByIDs(ctx context.Context, ids []uint) ([]models.Webhook, error)
+	ListByUser(ctx context.Context, userID uint) ([]models.Webhook, error)
+	CountByUser(ctx context.Context, userID uint) (int64, error)
+	// ListSubscribed returns the active webhooks of the users subscribed to an event type
+	ListSubscribed(ctx context.Context, userIDs []uint, eventType string) ([]models.Webhook, error)
+
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// ClaimDue locks up to limit pending deliveries of active webhooks that are
+	// due at now and pushes them back by lease, so other dispatchers skip them
+	// while they are being attempted
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// RecordAttempt logs an attempt and saves the delivery's new state together
+	RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt) error
+	// ListDeliveries returns a page of a webhook's deliveries, newest first; an empty status matches all
+	ListDeliveries(ctx context.Context, webhookID uint, status string, page pagination.Page) ([]models.WebhookDelivery, pagination.Meta, error)
+	// FindDelivery returns a delivery of the webhook with its attempts, oldest first
+	FindDelivery(ctx context.Context, webhookID, id uint) (models.WebhookDelivery, error)
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *webhookRepository) Save(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Delete(webhook).Error
+}
+
+func (r *webhookRepository) FindForUser(ctx context.Context, userID, id uint) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&webhook, id).Error
+	return webhook, translate(err)
+}
+
+func (r *webhookRepository) FindByIDs(ctx context.Context, ids []uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if len(ids) == 0 {
+		return webhooks, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepository) ListByUser(ctx context.Context, userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Webhook{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *webhookRepository) ListSubscribed(ctx context.Context, userIDs []uint, eventType string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if len(userIDs) == 0 {
+		return webhooks, nil
+	}
+
+	subscribed, err := json.Marshal([]string{eventType})
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.WithContext(ctx).
+		Where("active AND user_id IN ? AND events @> ?::jsonb", userIDs, string(subscribed)).
+		Order("id").
+		Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *webhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Where("webhook_id IN (?)", tx.Model(&models.Webhook{}).Select("id").Where("active")).
+			Order("next_attempt_at, id").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+			deliveries[i].NextAttemptAt = now.Add(lease)
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return deliveries, err
+}
+
+func (r *webhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		attempt.DeliveryID = delivery.ID
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		return tx.Omit("AttemptLog").Save(delivery).Error
+	})
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, webhookID uint, status string, page pagination.Page) ([]models.WebhookDelivery, pagination.Meta, error) {
+	scope := func(query *gorm.DB) *gorm.DB {
+		query = query.Where("webhook_id = ?", webhookID)
+		if status != "" {
+			query = query.Where("status = ?", status)
+		}
+		return query
+	}
+
+	var deliveries []models.WebhookDelivery
+	query := page.Apply(scope(r.db.WithContext(ctx)), pagination.Order{Time: "created_at", ID: "id"})
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, pagination.Meta{}, err
+	}
+
+	deliveries, meta := pagination.Finish(page, deliveries, func(d models.WebhookDelivery) pagination.Cursor {
+		return pagination.Cursor{Time: d.CreatedAt, ID: d.ID}
+	})
+	err := page.Total(scope(r.db.WithContext(ctx).Model(&models.WebhookDelivery{})), &meta)
+	return deliveries, meta, err
+}
+
+func (r *webhookRepository) FindDelivery(ctx context.Context, webhookID, id uint) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Preload("AttemptLog", func(query *gorm.DB) *gorm.DB { return query.Order("id") }).
+		Where("webhook_id = ?", webhookID).
+		First(&delivery, id).Error
+	return delivery, translate(err)
+}
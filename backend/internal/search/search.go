@@ -0,0 +1,150 @@
+// Package search builds the highlighted snippets shown with search results.
+// Matching and ranking happen in Postgres (see migrations/0003_project_search);
+// this only has to find the searched words again in the returned text.
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+	ellipsis  = "…"
+)
+
+// Terms splits a search query into the words to highlight. A run of Chinese
+// characters also yields its bigrams, since that is how the database matches
+// it and the whole run need not appear in the text.
+func Terms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		terms = append(terms, field)
+
+		var run []rune
+		flush := func() {
+			if len(run) > 2 {
+				for i := 0; i+1 < len(run); i++ {
+					terms = append(terms, string(run[i:i+2]))
+				}
+			}
+			run = run[:0]
+		}
+		for _, r := range field {
+			if unicode.Is(unicode.Han, r) {
+				run = append(run, r)
+			} else {
+				flush()
+			}
+		}
+		flush()
+	}
+	return terms
+}
+
+// Highlight HTML-escapes text and wraps every occurrence of a term in <mark>
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	return mark(runes, matches(runes, terms))
+}
+
+// Snippet is Highlight for long text: it keeps about width characters around
+// the first match, or the start of the text when nothing matches
+func Snippet(text string, terms []string, width int) string {
+	runes := []rune(text)
+	spans := matches(runes, terms)
+	if len(runes) <= width {
+		return mark(runes, spans)
+	}
+
+	start := 0
+	if len(spans) > 0 {
+		start = spans[0].start - width/3
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+		start = end - width
+	}
+
+	var window []span
+	for _, s := range spans {
+		if s.start >= start && s.end <= end {
+			window = append(window, span{s.start - start, s.end - start})
+		}
+	}
+
+	snippet := mark(runes[start:end], window)
+	if start > 0 {
+		snippet = ellipsis + snippet
+	}
+	if end < len(runes) {
+		snippet += ellipsis
+	}
+	return snippet
+}
+
+type span struct {
+	start, end int
+}
+
+// matches finds the case-insensitive occurrences of terms in text, merged
+// into sorted, non-overlapping rune ranges
+func matches(text []rune, terms []string) []span {
+	folded := fold(text)
+
+	var spans []span
+	for _, term := range terms {
+		needle := fold([]rune(term))
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(folded); i++ {
+			if string(folded[i:i+len(needle)]) == string(needle) {
+				spans = append(spans, span{i, i + len(needle)})
+			}
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	merged := spans[:0]
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end {
+			if s.end > merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+func fold(text []rune) []rune {
+	folded := make([]rune, len(text))
+	for i, r := range text {
+		folded[i] = unicode.ToLower(r)
+	}
+	return folded
+}
+
+func mark(text []rune, spans []span) string {
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		b.WriteString(html.EscapeString(string(text[last:s.start])))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(string(text[s.start:s.end])))
+		b.WriteString(markClose)
+		last = s.end
+	}
+	b.WriteString(html.EscapeString(string(text[last:])))
+	return b.String()
+}
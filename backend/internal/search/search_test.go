@@ -0,0 +1,68 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"React", []string{"React"}},
+		{"  go   api ", []string{"go", "api"}},
+		{"設計", []string{"設計"}},
+		{"網站設計", []string{"網站設計", "網站", "站設", "設計"}},
+		{"React網頁", []string{"React網頁"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Terms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		query string
+		want  string
+	}{
+		{"Build a React app", "react", "Build a <mark>React</mark> app"},
+		{"公司網站設計與架設", "網站設計", "公司<mark>網站設計</mark>與架設"},
+		// The bigrams match even when the whole run doesn't appear
+		{"設計一個網站", "網站設計", "<mark>設計</mark>一個<mark>網站</mark>"},
+		{"Go & <b>Vue</b>", "vue", "Go &amp; &lt;b&gt;<mark>Vue</mark>&lt;/b&gt;"},
+		{"Logo design", "python", "Logo design"},
+	}
+
+	for _, tt := range tests {
+		if got := Highlight(tt.text, Terms(tt.query)); got != tt.want {
+			t.Errorf("Highlight(%q, %q) = %q, want %q", tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("前言", 50) + "需要熟悉 Kubernetes 部署" + strings.Repeat("結尾", 50)
+
+	got := Snippet(text, Terms("kubernetes"), 40)
+	if !strings.Contains(got, "<mark>Kubernetes</mark>") {
+		t.Errorf("snippet lost the match: %q", got)
+	}
+	if !strings.HasPrefix(got, ellipsis) || !strings.HasSuffix(got, ellipsis) {
+		t.Errorf("expected ellipses on both sides: %q", got)
+	}
+
+	if got := Snippet(text, Terms("python"), 10); got != strings.Repeat("前言", 5)+ellipsis {
+		t.Errorf("expected the start of the text without a match, got %q", got)
+	}
+
+	if got := Snippet("short", Terms("short"), 40); got != "<mark>short</mark>" {
+		t.Errorf("expected short text to be returned whole, got %q", got)
+	}
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"freelance-platform/internal/models"
+)
+
+func TestAdminListsClampPaging(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.createUser("admin")
+	for i := 0; i < 25; i++ {
+		s.createUser("freelancer")
+	}
+
+	var listed struct {
+		Users []models.User `json:"users"`
+		Total int64         `json:"total"`
+	}
+	s.as(admin, http.MethodGet, "/api/admin/users?page=-2&limit=100000", nil).expect(http.StatusOK).decode(&listed)
+	if len(listed.Users) != 20 || listed.Total != 26 {
+		t.Errorf("expected the first 20 of 26 users, got %d of %d", len(listed.Users), listed.Total)
+	}
+
+	s.as(admin, http.MethodGet, "/api/admin/actions?page=0&limit=-1", nil).expect(http.StatusOK)
+}
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"freelance-platform/internal/audit"
+	"freelance-platform/internal/auth"
+	"freelance-platform/internal/models"
+)
+
+type sessionResponse struct {
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	User         models.User `json:"user"`
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := newTestServer(t)
+
+	var registered sessionResponse
+	s.do(http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email":    "client@example.com",
+		"password": testPassword,
+		"name":     "Client",
+		"role":     "client",
+	}).expect(http.StatusCreated).decode(&registered)
+
+	if registered.Token == "" || registered.RefreshToken == "" {
+		t.Fatalf("register returned no session: %+v", registered)
+	}
+	if registered.User.Role != "client" {
+		t.Errorf("expected role client, got %q", registered.User.Role)
+	}
+
+	var me struct {
+		User models.User `json:"user"`
+	}
+	s.do(http.MethodGet, "/api/auth/me", registered.Token, nil).expect(http.StatusOK).decode(&me)
+	if me.User.Email != "client@example.com" {
+		t.Errorf("expected /me to return the new user, got %q", me.User.Email)
+	}
+
+	// The same address cannot register twice
+	s.do(http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email":    "client@example.com",
+		"password": testPassword,
+	}).expect(http.StatusConflict)
+
+	var loggedIn sessionResponse
+	s.do(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    "client@example.com",
+		"password": testPassword,
+	}).expect(http.StatusOK).decode(&loggedIn)
+	if loggedIn.Token == "" {
+		t.Fatal("login returned no token")
+	}
+
+	s.do(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    "client@example.com",
+		"password": "wrong-password",
+	}).expect(http.StatusUnauthorized)
+}
+
+func TestRegisterRejectsInvalidRole(t *testing.T) {
+	s := newTestServer(t)
+
+	s.do(http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email":    "admin@example.com",
+		"password": testPassword,
+		"role":     "admin",
+	}).expect(http.StatusBadRequest)
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	s := newTestServer(t)
+
+	s.do(http.MethodGet, "/api/auth/me", "", nil).expect(http.StatusUnauthorized)
+	s.do(http.MethodGet, "/api/auth/me", "not-a-jwt", nil).expect(http.StatusUnauthorized)
+	s.do(http.MethodGet, "/api/chats", "", nil).expect(http.StatusUnauthorized)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s := newTestServer(t)
+
+	var registered sessionResponse
+	s.do(http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email":    "new@example.com",
+		"password": testPassword,
+		"role":     "client",
+	}).expect(http.StatusCreated).decode(&registered)
+
+	// Unverified accounts cannot post projects yet
+	project := map[string]interface{}{
+		"title":      "Landing page",
+		"budget_min": 10000,
+		"budget_max": 20000,
+		"category":   "程式開發",
+		"location":   "Remote",
+	}
+	s.do(http.MethodPost, "/api/projects", registered.Token, project).expect(http.StatusForbidden)
+
+	s.runJobs()
+	message, ok := s.mail.Last("new@example.com")
+	if !ok {
+		t.Fatal("no verification email was sent")
+	}
+	token := verificationToken(t, message.Text)
+
+	s.do(http.MethodPost, "/api/auth/verify-email", "", map[string]string{"token": token}).expect(http.StatusOK)
+	s.do(http.MethodPost, "/api/projects", registered.Token, project).expect(http.StatusCreated)
+
+	// Tokens are single use
+	s.do(http.MethodPost, "/api/auth/verify-email", "", map[string]string{"token": token}).expect(http.StatusBadRequest)
+}
+
+// verificationToken pulls the token query parameter out of the link in an email body
+func verificationToken(t *testing.T, body string) string {
+	t.Helper()
+
+	for _, field := range strings.Fields(body) {
+		link, err := url.Parse(field)
+		if err != nil || link.Query().Get("token") == "" {
+			continue
+		}
+		return link.Query().Get("token")
+	}
+	t.Fatalf("no token link in %q", body)
+	return ""
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser("freelancer")
+
+	var login sessionResponse
+	s.do(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    user.Email,
+		"password": testPassword,
+	}).expect(http.StatusOK).decode(&login)
+
+	var rotated sessionResponse
+	s.do(http.MethodPost, "/api/auth/refresh", "", map[string]string{"refresh_token": login.RefreshToken}).
+		expect(http.StatusOK).decode(&rotated)
+	if rotated.RefreshToken == "" || rotated.RefreshToken == login.RefreshToken {
+		t.Fatalf("refresh did not rotate the token: %+v", rotated)
+	}
+
+	// Replaying the old refresh token revokes the whole session
+	s.do(http.MethodPost, "/api/auth/refresh", "", map[string]string{"refresh_token": login.RefreshToken}).
+		expect(http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/auth/refresh", "", map[string]string{"refresh_token": rotated.RefreshToken}).
+		expect(http.StatusUnauthorized)
+	s.do(http.MethodGet, "/api/auth/me", rotated.Token, nil).expect(http.StatusUnauthorized)
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser("client")
+
+	var login sessionResponse
+	s.do(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    user.Email,
+		"password": testPassword,
+	}).expect(http.StatusOK).decode(&login)
+
+	s.do(http.MethodPost, "/api/auth/logout", login.Token, nil).expect(http.StatusOK)
+
+	s.do(http.MethodGet, "/api/auth/me", login.Token, nil).expect(http.StatusUnauthorized)
+	s.do(http.MethodPost, "/api/auth/refresh", "", map[string]string{"refresh_token": login.RefreshToken}).
+		expect(http.StatusUnauthorized)
+}
+
+func TestSuspendedUserIsLockedOut(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser("freelancer")
+	token := s.tokenFor(user)
+
+	s.db.Model(&user).Update("status", "banned")
+
+	s.do(http.MethodGet, "/api/auth/me", token, nil).expect(http.StatusForbidden)
+	s.do(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    user.Email,
+		"password": testPassword,
+	}).expect(http.StatusForbidden)
+}
+
+// enableTwoFactor turns on 2FA for user and returns its TOTP secret and one recovery code
+func (s *testServer) enableTwoFactor(user models.User) (string, string) {
+	s.t.Helper()
+
+	secret := auth.NewTOTPSecret()
+	s.db.Model(&user).Updates(map[string]interface{}{"two_factor_enabled": true, "totp_secret": secret})
+
+	code := auth.NewRecoveryCodes(1)[0]
+	if err := s.db.Create(&models.RecoveryCode{UserID: user.ID, CodeHash: auth.HashToken(code)}).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	return secret, code
+}
+
+// mfaToken logs user in with the password and returns the token for the second step
+func (s *testServer) mfaToken(user models.User) string {
+	s.t.Helper()
+
+	var pending struct {
+		MFAToken string `json:"mfa_token"`
+	}
+	s.do(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    user.Email,
+		"password": testPassword,
+	}).expect(http.StatusOK).decode(&pending)
+	if pending.MFAToken == "" {
+		s.t.Fatal("expected login to ask for the second factor")
+	}
+	return pending.MFAToken
+}
+
+func TestTwoFactorLoginLocksOutAfterRepeatedFailures(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser("freelancer")
+	secret, recovery := s.enableTwoFactor(user)
+
+	secondStep := func(token, code string) *testResponse {
+		return s.do(http.MethodPost, "/api/auth/login/2fa", "", map[string]string{"mfa_token": token, "code": code})
+	}
+
+	token := s.mfaToken(user)
+	for i := 1; i < 5; i++ {
+		secondStep(token, "000000").expect(http.StatusUnauthorized)
+	}
+	secondStep(token, "aaaa-bbbb-cccc").expect(http.StatusTooManyRequests)
+
+	// The token is burned and the account rejects every code for a while
+	code, err := auth.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondStep(token, code).expect(http.StatusUnauthorized)
+	secondStep(s.mfaToken(user), code).expect(http.StatusTooManyRequests)
+	secondStep(s.mfaToken(user), recovery).expect(http.StatusTooManyRequests)
+
+	s.db.Model(&user).Update("second_factor_locked_until", time.Now().Add(-time.Second))
+	secondStep(s.mfaToken(user), recovery).expect(http.StatusOK)
+
+	s.reload(&user, user.ID)
+	if user.SecondFactorFailures != 0 {
+		t.Errorf("expected a successful login to reset the failures, got %d", user.SecondFactorFailures)
+	}
+}
+
+// auditTrail records audit entries for the rest of the test and returns a
+// function listing them, oldest first
+func (s *testServer) auditTrail() func() []models.AuditLog {
+	logger := audit.NewLogger(s.db, 16)
+	audit.DefaultLogger = logger
+	s.t.Cleanup(func() {
+		logger.Close()
+		audit.DefaultLogger = nil
+	})
+
+	return func() []models.AuditLog {
+		s.t.Helper()
+
+		logger.Flush()
+		var entries []models.AuditLog
+		if err := s.db.Order("id").Find(&entries).Error; err != nil {
+			s.t.Fatalf("list audit log: %v", err)
+		}
+		return entries
+	}
+}
+
+func TestSecurityChangesAreAudited(t *testing.T) {
+	s := newTestServer(t)
+	entries := s.auditTrail()
+	user := s.createUser("freelancer")
+	secret, _ := s.enableTwoFactor(user)
+
+	code, err := auth.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.as(user, http.MethodPost, "/api/auth/2fa/disable", map[string]string{"password": testPassword, "code": code}).expect(http.StatusOK)
+
+	var session sessionResponse
+	s.do(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    user.Email,
+		"password": testPassword,
+	}).expect(http.StatusOK).decode(&session)
+	s.do(http.MethodPost, "/api/auth/logout-all", session.Token, nil).expect(http.StatusOK)
+
+	var actions []string
+	for _, entry := range entries() {
+		if entry.ActorID == nil || *entry.ActorID != user.ID || entry.TargetID != user.ID {
+			t.Errorf("expected %s by and about the user, got %+v", entry.Action, entry)
+		}
+		actions = append(actions, entry.Action)
+	}
+	if strings.Join(actions, ",") != "user.2fa_disable,session.revoke_all" {
+		t.Errorf("expected the 2FA change and the sign-out audited, got %v", actions)
+	}
+}
+
+func TestWebSocketRejectsOtherOrigins(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser("freelancer")
+	t.Setenv("FRONTEND_URL", "https://app.example.com")
+
+	req := httptest.NewRequest(http.MethodGet, "/ws/chat?token="+url.QueryEscape(s.tokenFor(user)), nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("expected a websocket from another site refused, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"freelance-platform/internal/models"
+)
+
+func bidRequest(projectID uint, amount int) map[string]interface{} {
+	return map[string]interface{}{
+		"project_id": projectID,
+		"amount":     amount,
+		"proposal":   "I have built many of these",
+		"timeline":   "3 weeks",
+	}
+}
+
+func TestPlaceBid(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+
+	var created struct {
+		Bid models.Bid `json:"bid"`
+	}
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).
+		expect(http.StatusCreated).decode(&created)
+
+	if created.Bid.Status != "pending" || created.Bid.ExpiresAt == nil {
+		t.Errorf("expected a pending bid with an expiry, got %+v", created.Bid)
+	}
+
+	// One active bid per freelancer and project
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 25000)).expect(http.StatusConflict)
+}
+
+func TestPlaceBidRules(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+
+	// Only freelancers bid
+	s.as(client, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).expect(http.StatusForbidden)
+
+	// The amount has to fall inside the project budget
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 5000)).expect(http.StatusBadRequest)
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 90000)).expect(http.StatusBadRequest)
+
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(9999, 20000)).expect(http.StatusNotFound)
+
+	closed := s.createProject(client)
+	s.db.Model(&closed).Update("status", "in_progress")
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(closed.ID, 20000)).expect(http.StatusBadRequest)
+}
+
+func TestAcceptBid(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	otherClient := s.createUser("client")
+	winner := s.createUser("freelancer")
+	loser := s.createUser("freelancer")
+	project := s.createProject(client)
+	winning := s.createBid(winner, project, 20000)
+	losing := s.createBid(loser, project, 30000)
+	chat := s.createChat(project, loser)
+	url := fmt.Sprintf("/api/bids/%d/accept", winning.ID)
+
+	// Only the project owner decides
+	s.as(otherClient, http.MethodPut, url, nil).expect(http.StatusForbidden)
+	s.as(winner, http.MethodPut, url, nil).expect(http.StatusForbidden)
+
+	s.as(client, http.MethodPut, url, nil).expect(http.StatusOK)
+
+	s.reload(&project, project.ID)
+	if project.Status != "in_progress" || project.FreelancerID == nil || *project.FreelancerID != winner.ID {
+		t.Errorf("expected the project to start with the winner, got %+v", project)
+	}
+
+	s.reload(&losing, losing.ID)
+	if losing.Status != "rejected" {
+		t.Errorf("expected the competing bid to be rejected, got %q", losing.Status)
+	}
+
+	var contract models.Contract
+	if err := s.db.Preload("Milestones").Where("project_id = ?", project.ID).First(&contract).Error; err != nil {
+		t.Fatalf("expected a contract: %v", err)
+	}
+	if contract.Amount != winning.Amount || len(contract.Milestones) != 1 {
+		t.Errorf("unexpected contract: %+v", contract)
+	}
+
+	var notices int64
+	s.db.Model(&models.Message{}).Where("chat_id = ? AND type = ?", chat.ID, "system").Count(&notices)
+	if notices != 1 {
+		t.Errorf("expected the losing freelancer to be told, got %d messages", notices)
+	}
+
+	// The project no longer takes bids
+	s.as(client, http.MethodPut, fmt.Sprintf("/api/bids/%d/accept", losing.ID), nil).expect(http.StatusBadRequest)
+}
+
+func TestRejectBid(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	bid := s.createBid(freelancer, project, 20000)
+	url := fmt.Sprintf("/api/bids/%d/reject", bid.ID)
+
+	s.as(freelancer, http.MethodPut, url, nil).expect(http.StatusForbidden)
+	s.as(client, http.MethodPut, url, nil).expect(http.StatusOK)
+	s.as(client, http.MethodPut, url, nil).expect(http.StatusConflict)
+}
+
+func TestExpiredBidCannotBeAccepted(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	bid := s.createBid(freelancer, project, 20000)
+	s.db.Model(&bid).Update("expires_at", time.Now().Add(-time.Hour))
+
+	s.as(client, http.MethodPut, fmt.Sprintf("/api/bids/%d/accept", bid.ID), nil).expect(http.StatusConflict)
+
+	var listed struct {
+		Bids []models.Bid `json:"bids"`
+	}
+	s.as(client, http.MethodGet, fmt.Sprintf("/api/projects/%d/bids?status=expired", project.ID), nil).
+		expect(http.StatusOK).decode(&listed)
+	if len(listed.Bids) != 1 || listed.Bids[0].Status != "expired" {
+		t.Errorf("expected the bid to be listed as expired, got %+v", listed.Bids)
+	}
+
+	s.as(client, http.MethodGet, fmt.Sprintf("/api/projects/%d/bids?status=pending", project.ID), nil).
+		expect(http.StatusOK).decode(&listed)
+	if len(listed.Bids) != 0 {
+		t.Errorf("expected no pending bids, got %+v", listed.Bids)
+	}
+
+	// Reading never writes; the worker expires the bid
+	s.reload(&bid, bid.ID)
+	if bid.Status != "pending" {
+		t.Errorf("expected listing to leave the bid to the worker, got %q", bid.Status)
+	}
+
+	// ...but the freelancer can bid again without waiting for it
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 21000)).expect(http.StatusCreated)
+}
+
+func TestUpdateBid(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	other := s.createUser("freelancer")
+	project := s.createProject(client)
+	bid := s.createBid(freelancer, project, 20000)
+	url := fmt.Sprintf("/api/bids/%d", bid.ID)
+	revision := map[string]interface{}{"amount": 22000, "proposal": "Updated", "timeline": "2 weeks"}
+
+	s.as(other, http.MethodPut, url, revision).expect(http.StatusForbidden)
+	s.as(client, http.MethodPut, url, revision).expect(http.StatusForbidden)
+	s.as(freelancer, http.MethodPut, url, revision).expect(http.StatusOK)
+
+	// Both sides can read the history, nobody else can
+	var history struct {
+		Revisions []models.BidRevision `json:"revisions"`
+	}
+	s.as(client, http.MethodGet, url+"/revisions", nil).expect(http.StatusOK).decode(&history)
+	if len(history.Revisions) != 1 || history.Revisions[0].Amount != 20000 {
+		t.Errorf("expected the original version as a revision, got %+v", history.Revisions)
+	}
+	s.as(other, http.MethodGet, url+"/revisions", nil).expect(http.StatusForbidden)
+}
+
+func TestWithdrawBid(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	bid := s.createBid(freelancer, project, 20000)
+	url := fmt.Sprintf("/api/bids/%d/withdraw", bid.ID)
+
+	s.as(client, http.MethodPut, url, nil).expect(http.StatusForbidden)
+	s.as(freelancer, http.MethodPut, url, nil).expect(http.StatusOK)
+	s.as(freelancer, http.MethodPut, url, nil).expect(http.StatusConflict)
+
+	// A withdrawn bid can be replaced with a new one
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 21000)).expect(http.StatusCreated)
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/services"
+)
+
+type unreadResponse struct {
+	UnreadCount int64 `json:"unread_count"`
+}
+
+func TestCreateChat(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	request := map[string]uint{"project_id": project.ID, "freelancer_id": freelancer.ID}
+
+	var first, second struct {
+		Chat models.Chat `json:"chat"`
+	}
+	s.as(client, http.MethodPost, "/api/chats", request).expect(http.StatusCreated).decode(&first)
+
+	// Opening the same conversation again returns the existing chat
+	s.as(client, http.MethodPost, "/api/chats", request).expect(http.StatusOK).decode(&second)
+	if first.Chat.ID == 0 || first.Chat.ID != second.Chat.ID {
+		t.Errorf("expected the same chat twice, got %d and %d", first.Chat.ID, second.Chat.ID)
+	}
+}
+
+func TestCreateChatPermissions(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	otherClient := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+
+	// Freelancers don't start chats
+	s.as(freelancer, http.MethodPost, "/api/chats", map[string]uint{"project_id": project.ID, "freelancer_id": freelancer.ID}).
+		expect(http.StatusForbidden)
+
+	// Clients only start chats about their own projects
+	s.as(otherClient, http.MethodPost, "/api/chats", map[string]uint{"project_id": project.ID, "freelancer_id": freelancer.ID}).
+		expect(http.StatusForbidden)
+
+	// ...and only with freelancers
+	s.as(client, http.MethodPost, "/api/chats", map[string]uint{"project_id": project.ID, "freelancer_id": otherClient.ID}).
+		expect(http.StatusBadRequest)
+	s.as(client, http.MethodPost, "/api/chats", map[string]uint{"project_id": project.ID, "freelancer_id": 9999}).
+		expect(http.StatusNotFound)
+}
+
+func TestSendAndReadMessages(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	chat := s.createChat(project, freelancer)
+
+	var sent struct {
+		Message models.Message `json:"message"`
+	}
+	s.as(client, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": "Hello"}).
+		expect(http.StatusCreated).decode(&sent)
+	if sent.Message.Type != "text" || sent.Message.Sender.ID != client.ID {
+		t.Errorf("unexpected message: %+v", sent.Message)
+	}
+
+	var unread unreadResponse
+	s.as(freelancer, http.MethodGet, "/api/messages/unread-count", nil).expect(http.StatusOK).decode(&unread)
+	if unread.UnreadCount != 1 {
+		t.Errorf("expected 1 unread message, got %d", unread.UnreadCount)
+	}
+
+	var history struct {
+		Messages []models.Message `json:"messages"`
+	}
+	s.as(freelancer, http.MethodGet, fmt.Sprintf("/api/chats/%d/messages", chat.ID), nil).
+		expect(http.StatusOK).decode(&history)
+	if len(history.Messages) != 1 || history.Messages[0].Content != "Hello" {
+		t.Fatalf("expected the message in the history, got %+v", history.Messages)
+	}
+
+	s.as(freelancer, http.MethodPut, fmt.Sprintf("/api/chats/%d/read", chat.ID), nil).expect(http.StatusOK)
+	s.as(freelancer, http.MethodGet, "/api/messages/unread-count", nil).expect(http.StatusOK).decode(&unread)
+	if unread.UnreadCount != 0 {
+		t.Errorf("expected no unread messages after reading, got %d", unread.UnreadCount)
+	}
+}
+
+func TestChatIsPrivateToParticipants(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	outsider := s.createUser("freelancer")
+	project := s.createProject(client)
+	chat := s.createChat(project, freelancer)
+
+	s.as(outsider, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": "Hi"}).
+		expect(http.StatusForbidden)
+	s.as(outsider, http.MethodGet, fmt.Sprintf("/api/chats/%d/messages", chat.ID), nil).expect(http.StatusForbidden)
+	s.as(outsider, http.MethodPut, fmt.Sprintf("/api/chats/%d/read", chat.ID), nil).expect(http.StatusForbidden)
+	s.as(outsider, http.MethodDelete, fmt.Sprintf("/api/chats/%d", chat.ID), nil).expect(http.StatusForbidden)
+
+	s.as(freelancer, http.MethodGet, "/api/chats/9999/messages", nil).expect(http.StatusNotFound)
+}
+
+func TestHiddenMessagesAreRedacted(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	chat := s.createChat(project, freelancer)
+
+	s.as(freelancer, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": "spam"}).
+		expect(http.StatusCreated)
+	s.db.Model(&models.Message{}).Where("chat_id = ?", chat.ID).Update("hidden_at", s.db.NowFunc())
+
+	var history struct {
+		Messages []models.Message `json:"messages"`
+	}
+	s.as(client, http.MethodGet, fmt.Sprintf("/api/chats/%d/messages", chat.ID), nil).
+		expect(http.StatusOK).decode(&history)
+	if len(history.Messages) != 1 || history.Messages[0].Content == "spam" {
+		t.Errorf("expected the hidden message to be redacted, got %+v", history.Messages)
+	}
+}
+
+func TestDeleteChatHidesItPerParticipant(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	chat := s.createChat(project, freelancer)
+	s.as(client, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": "Hello"}).
+		expect(http.StatusCreated)
+
+	var listed struct {
+		Chats []services.ChatSummary `json:"chats"`
+	}
+
+	s.as(client, http.MethodDelete, fmt.Sprintf("/api/chats/%d", chat.ID), nil).expect(http.StatusOK)
+
+	s.as(client, http.MethodGet, "/api/chats", nil).expect(http.StatusOK).decode(&listed)
+	if len(listed.Chats) != 0 {
+		t.Errorf("expected the chat to be hidden for the client, got %d chats", len(listed.Chats))
+	}
+	s.as(client, http.MethodGet, fmt.Sprintf("/api/chats/%d/messages", chat.ID), nil).expect(http.StatusNotFound)
+
+	s.as(freelancer, http.MethodGet, "/api/chats", nil).expect(http.StatusOK).decode(&listed)
+	if len(listed.Chats) != 1 || listed.Chats[0].UnreadCount != 1 {
+		t.Errorf("expected the freelancer to still see the chat with 1 unread, got %+v", listed.Chats)
+	}
+
+	// Writing into a hidden chat brings it back for the writer
+	s.as(client, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": "Still there?"}).
+		expect(http.StatusCreated)
+	s.as(client, http.MethodGet, "/api/chats", nil).expect(http.StatusOK).decode(&listed)
+	if len(listed.Chats) != 1 {
+		t.Errorf("expected the chat to reappear for the client, got %d chats", len(listed.Chats))
+	}
+
+	// Once both sides delete it, it is gone
+	s.as(client, http.MethodDelete, fmt.Sprintf("/api/chats/%d", chat.ID), nil).expect(http.StatusOK)
+	s.as(freelancer, http.MethodDelete, fmt.Sprintf("/api/chats/%d", chat.ID), nil).expect(http.StatusOK)
+
+	var remaining int64
+	s.db.Model(&models.Message{}).Where("chat_id = ?", chat.ID).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("expected the messages to be deleted, %d remain", remaining)
+	}
+}
+
+func TestPaginateMessagesBackwards(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	chat := s.createChat(project, freelancer)
+
+	for i := 1; i <= 5; i++ {
+		s.as(client, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": fmt.Sprint(i)}).
+			expect(http.StatusCreated)
+	}
+
+	type page struct {
+		Messages   []models.Message `json:"messages"`
+		Pagination struct {
+			NextCursor string `json:"next_cursor"`
+			HasMore    bool   `json:"has_more"`
+		} `json:"pagination"`
+	}
+	contents := func(p page) string {
+		var s []string
+		for _, m := range p.Messages {
+			s = append(s, m.Content)
+		}
+		return strings.Join(s, ",")
+	}
+	path := fmt.Sprintf("/api/chats/%d/messages?limit=2", chat.ID)
+
+	// The first page is the newest messages, in reading order
+	var newest page
+	s.as(freelancer, http.MethodGet, path, nil).expect(http.StatusOK).decode(&newest)
+	if got := contents(newest); got != "4,5" || !newest.Pagination.HasMore {
+		t.Fatalf("expected 4,5 with more before, got %q %+v", got, newest.Pagination)
+	}
+
+	var older page
+	s.as(freelancer, http.MethodGet, path+"&cursor="+newest.Pagination.NextCursor, nil).expect(http.StatusOK).decode(&older)
+	if got := contents(older); got != "2,3" {
+		t.Fatalf("expected 2,3, got %q", got)
+	}
+
+	var oldest page
+	s.as(freelancer, http.MethodGet, path+"&cursor="+older.Pagination.NextCursor, nil).expect(http.StatusOK).decode(&oldest)
+	if got := contents(oldest); got != "1" || oldest.Pagination.HasMore {
+		t.Errorf("expected only 1 and no more, got %q %+v", got, oldest.Pagination)
+	}
+}
@@ -0,0 +1,394 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"freelance-platform/internal/auth"
+	"freelance-platform/internal/database"
+	"freelance-platform/internal/handlers"
+	"freelance-platform/internal/jobs"
+	"freelance-platform/internal/mailer"
+	"freelance-platform/internal/migrate"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/migrations"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The suite runs against TEST_DATABASE_URL when it is set, otherwise against
+// an embedded Postgres started for the test binary. Either way every run gets
+// its own schema, migrated from scratch and dropped afterwards, and each test
+// starts from empty tables.
+
+const testPassword = "password123"
+
+var testEnv struct {
+	once     sync.Once
+	dsn      string
+	db       *gorm.DB
+	admin    *gorm.DB
+	schema   string
+	embedded *embeddedpostgres.EmbeddedPostgres
+	err      error
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("JWT_SECRET", "test-secret")
+
+	code := m.Run()
+
+	if testEnv.admin != nil {
+		testEnv.admin.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", testEnv.schema))
+	}
+	if testEnv.embedded != nil {
+		testEnv.embedded.Stop()
+	}
+	os.Exit(code)
+}
+
+// setupDatabase opens a connection to a freshly migrated, throwaway schema
+func setupDatabase() error {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		var err error
+		if dsn, err = startEmbeddedPostgres(); err != nil {
+			return fmt.Errorf("start embedded postgres (set TEST_DATABASE_URL to use an existing server): %w", err)
+		}
+	}
+
+	testEnv.dsn = dsn
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return err
+	}
+	testEnv.admin = admin
+
+	testEnv.schema = "test_" + randomSuffix()
+	db, err := openSchema(testEnv.schema)
+	if err != nil {
+		return err
+	}
+
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if _, err := migrate.New(sqlDB, loaded).Up(context.Background()); err != nil {
+		return err
+	}
+
+	testEnv.db = db
+	return nil
+}
+
+// openSchema creates schema and returns a connection whose every pooled
+// connection resolves unqualified names in it; extensions live in public
+func openSchema(schema string) (*gorm.DB, error) {
+	if err := testEnv.admin.Exec(fmt.Sprintf("CREATE SCHEMA %s", schema)).Error; err != nil {
+		return nil, err
+	}
+
+	schemaDSN, err := url.Parse(testEnv.dsn)
+	if err != nil {
+		return nil, err
+	}
+	query := schemaDSN.Query()
+	query.Set("search_path", schema+",public")
+	schemaDSN.RawQuery = query.Encode()
+
+	return gorm.Open(postgres.Open(schemaDSN.String()), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+}
+
+func startEmbeddedPostgres() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	runtimeDir, err := os.MkdirTemp("", "freelance-test-pg")
+	if err != nil {
+		return "", err
+	}
+
+	config := embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V15).
+		Port(port).
+		Database("freelance_platform_test").
+		RuntimePath(runtimeDir).
+		StartTimeout(time.Minute).
+		Logger(&bytes.Buffer{})
+
+	pg := embeddedpostgres.NewDatabase(config)
+	if err := pg.Start(); err != nil {
+		return "", err
+	}
+	testEnv.embedded = pg
+
+	return config.GetConnectionURL() + "?sslmode=disable", nil
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// testServer is the API router wired to the test database
+type testServer struct {
+	t      *testing.T
+	db     *gorm.DB
+	svc    Services
+	router *gin.Engine
+	worker *jobs.Worker
+	mail   *mailer.CaptureMailer
+	seq    int
+}
+
+// testDatabase returns the migrated test database. Without one the test fails
+// when a database was asked for (TEST_DATABASE_URL) or on CI, and is only
+// skipped on a local run where the embedded Postgres could not start.
+func testDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	testEnv.once.Do(func() { testEnv.err = setupDatabase() })
+	if testEnv.err != nil {
+		if os.Getenv("TEST_DATABASE_URL") != "" || os.Getenv("CI") != "" {
+			t.Fatalf("no test database: %v", testEnv.err)
+		}
+		t.Skipf("no test database: %v", testEnv.err)
+	}
+	return testEnv.db
+}
+
+// newTestServer empties the database and returns a router using it
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	db := testDatabase(t)
+
+	var tables []string
+	if err := db.Raw("SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'").
+		Scan(&tables).Error; err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	for _, table := range tables {
+		if err := db.Exec(fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE", table)).Error; err != nil {
+			t.Fatalf("truncate %s: %v", table, err)
+		}
+	}
+
+	// Handlers that have no injected dependencies yet use the package singletons
+	capture := mailer.NewCaptureMailer()
+	database.DB = db
+	auth.DefaultDenylist = auth.NewMemoryDenylist()
+	mailer.DefaultMailer = capture
+
+	svc := NewServices(db, nil)
+	outbox.DefaultRelay = outbox.NewRelay(db, svc.Events, nil)
+
+	router := NewRouter(Handlers{
+		Projects:      handlers.NewProjectHandler(svc.Projects),
+		Chats:         handlers.NewChatHandler(svc.Chats, nil),
+		Notifications: handlers.NewNotificationHandler(svc.Notifications),
+		Webhooks:      handlers.NewWebhookHandler(svc.Webhooks),
+		Contracts:     handlers.NewContractHandler(db),
+		Payments:      handlers.NewPaymentHandler(db),
+	})
+
+	return &testServer{
+		t:      t,
+		db:     db,
+		svc:    svc,
+		router: router,
+		worker: NewWorker(db, svc, outbox.DefaultRelay),
+		mail:   capture,
+	}
+}
+
+// Fixtures
+
+// createUser inserts a user with a verified email and the password testPassword
+func (s *testServer) createUser(role string) models.User {
+	s.t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	s.seq++
+	now := time.Now()
+	user := models.User{
+		Email:           fmt.Sprintf("%s%d@example.com", role, s.seq),
+		Password:        string(hash),
+		Name:            fmt.Sprintf("%s %d", role, s.seq),
+		Role:            role,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		s.t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createProject inserts an open project with a 10,000 to 50,000 TWD budget
+func (s *testServer) createProject(client models.User) models.Project {
+	s.t.Helper()
+
+	s.seq++
+	project := models.Project{
+		Title:     fmt.Sprintf("Project %d", s.seq),
+		BudgetMin: 10000,
+		BudgetMax: 50000,
+		Currency:  "TWD",
+		Category:  "程式開發",
+		Location:  "Remote",
+		Urgency:   "一般",
+		Status:    "open",
+		ClientID:  client.ID,
+	}
+	if err := s.db.Create(&project).Error; err != nil {
+		s.t.Fatalf("create project: %v", err)
+	}
+	return project
+}
+
+// createBid inserts a pending bid that expires in a day
+func (s *testServer) createBid(freelancer models.User, project models.Project, amount int) models.Bid {
+	s.t.Helper()
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	bid := models.Bid{
+		ProjectID:    project.ID,
+		FreelancerID: freelancer.ID,
+		Amount:       amount,
+		Proposal:     "I can do this",
+		Timeline:     "2 weeks",
+		Status:       "pending",
+		ExpiresAt:    &expiresAt,
+	}
+	if err := s.db.Create(&bid).Error; err != nil {
+		s.t.Fatalf("create bid: %v", err)
+	}
+	return bid
+}
+
+func (s *testServer) createChat(project models.Project, freelancer models.User) models.Chat {
+	s.t.Helper()
+
+	chat := models.Chat{ProjectID: project.ID, ClientID: project.ClientID, FreelancerID: freelancer.ID}
+	if err := s.db.Create(&chat).Error; err != nil {
+		s.t.Fatalf("create chat: %v", err)
+	}
+	return chat
+}
+
+// reload reads the current row of a model back from the database
+func (s *testServer) reload(model interface{}, id uint) {
+	s.t.Helper()
+
+	if err := s.db.First(model, id).Error; err != nil {
+		s.t.Fatalf("reload %T %d: %v", model, id, err)
+	}
+}
+
+// runJobs works off the queued jobs that are due, as the worker would
+func (s *testServer) runJobs() {
+	s.t.Helper()
+
+	if _, err := s.worker.Work(context.Background(), time.Now()); err != nil {
+		s.t.Fatalf("run jobs: %v", err)
+	}
+}
+
+// Requests
+
+// tokenFor returns an access token for a new session of user
+func (s *testServer) tokenFor(user models.User) string {
+	s.t.Helper()
+
+	token, _, err := auth.IssueAccessToken(user.ID, auth.RandomID())
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return token
+}
+
+type testResponse struct {
+	t *testing.T
+	*httptest.ResponseRecorder
+}
+
+// do sends a request through the router; body is encoded as JSON unless nil
+// and token, when not empty, is sent as a bearer token
+func (s *testServer) do(method, path, token string, body interface{}) *testResponse {
+	s.t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, req)
+	return &testResponse{t: s.t, ResponseRecorder: recorder}
+}
+
+// as sends a request authenticated as user
+func (s *testServer) as(user models.User, method, path string, body interface{}) *testResponse {
+	s.t.Helper()
+	return s.do(method, path, s.tokenFor(user), body)
+}
+
+// expect fails the test unless the response has the given status
+func (r *testResponse) expect(status int) *testResponse {
+	r.t.Helper()
+
+	if r.Code != status {
+		r.t.Fatalf("expected status %d, got %d: %s", status, r.Code, r.Body.String())
+	}
+	return r
+}
+
+// decode unmarshals the JSON body into v
+func (r *testResponse) decode(v interface{}) {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		r.t.Fatalf("decode %q: %v", r.Body.String(), err)
+	}
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"freelance-platform/internal/jobs"
+	"freelance-platform/internal/models"
+)
+
+type testPayload struct {
+	Value string `json:"value"`
+}
+
+var testJob = jobs.Definition[testPayload]{Name: "test.job", MaxAttempts: 3, Timeout: time.Minute}
+
+// testWorker is a worker that runs testJob with fn
+func (s *testServer) testWorker(fn func(ctx context.Context, payload testPayload) error) *jobs.Worker {
+	w := jobs.NewWorker(s.db)
+	jobs.Handle(w, testJob, fn)
+	return w
+}
+
+func (s *testServer) enqueueTestJob(value string, opts jobs.Options) models.Job {
+	s.t.Helper()
+
+	job, err := testJob.Enqueue(context.Background(), s.db, testPayload{Value: value}, opts)
+	if err != nil {
+		s.t.Fatalf("enqueue: %v", err)
+	}
+	return job
+}
+
+func (s *testServer) work(w *jobs.Worker, now time.Time) int {
+	s.t.Helper()
+
+	ran, err := w.Work(context.Background(), now)
+	if err != nil {
+		s.t.Fatalf("work: %v", err)
+	}
+	return ran
+}
+
+func TestJobRunsWhenDue(t *testing.T) {
+	s := newTestServer(t)
+	var got []string
+	w := s.testWorker(func(ctx context.Context, payload testPayload) error {
+		got = append(got, payload.Value)
+		return nil
+	})
+
+	now := time.Now()
+	s.enqueueTestJob("now", jobs.Options{})
+	later := s.enqueueTestJob("later", jobs.Options{RunAt: now.Add(time.Hour)})
+
+	if ran := s.work(w, now); ran != 1 || len(got) != 1 || got[0] != "now" {
+		t.Fatalf("expected only the due job to run, ran %d: %v", ran, got)
+	}
+	if ran := s.work(w, now.Add(time.Hour)); ran != 1 || got[1] != "later" {
+		t.Fatalf("expected the scheduled job to run once due, ran %d: %v", ran, got)
+	}
+
+	s.reload(&later, later.ID)
+	if later.Status != models.JobSucceeded || later.Attempts != 1 || later.FinishedAt == nil || later.LockedBy != "" {
+		t.Errorf("expected a finished, unlocked job, got %+v", later)
+	}
+	if ran := s.work(w, now.Add(2*time.Hour)); ran != 0 {
+		t.Errorf("expected nothing left to run, ran %d", ran)
+	}
+}
+
+func TestJobUniqueKey(t *testing.T) {
+	s := newTestServer(t)
+	w := s.testWorker(func(ctx context.Context, payload testPayload) error { return nil })
+
+	first := s.enqueueTestJob("a", jobs.Options{UniqueKey: "user:1"})
+	second := s.enqueueTestJob("b", jobs.Options{UniqueKey: "user:1"})
+	if first.ID == 0 || second.ID != first.ID {
+		t.Fatalf("expected the queued job back, got %d and %d", first.ID, second.ID)
+	}
+	other := s.enqueueTestJob("c", jobs.Options{UniqueKey: "user:2"})
+	if other.ID == first.ID {
+		t.Fatal("expected another key to queue its own job")
+	}
+
+	s.work(w, time.Now())
+
+	// Once done, the key is free again
+	again := s.enqueueTestJob("d", jobs.Options{UniqueKey: "user:1"})
+	if again.ID == first.ID || again.ID == 0 {
+		t.Errorf("expected a new job once the first finished, got %d", again.ID)
+	}
+}
+
+func TestJobRetriesThenDies(t *testing.T) {
+	s := newTestServer(t)
+	calls := 0
+	w := s.testWorker(func(ctx context.Context, payload testPayload) error {
+		calls++
+		if payload.Value == "panic" {
+			panic("boom")
+		}
+		if payload.Value == "permanent" {
+			return jobs.Permanent(errors.New("bad input"))
+		}
+		return errors.New("smtp unavailable")
+	})
+
+	job := s.enqueueTestJob("flaky", jobs.Options{})
+	now := time.Now()
+	s.work(w, now)
+
+	s.reload(&job, job.ID)
+	if job.Status != models.JobQueued || job.Attempts != 1 || job.LastError != "smtp unavailable" {
+		t.Fatalf("expected the job queued for a retry, got %+v", job)
+	}
+	if !job.RunAt.After(now.Add(jobs.Backoff(1) - time.Second)) {
+		t.Errorf("expected the retry to back off, runs at %v", job.RunAt)
+	}
+	if ran := s.work(w, now.Add(time.Second)); ran != 0 {
+		t.Fatalf("expected no retry before the backoff, ran %d", ran)
+	}
+
+	for attempt := 1; attempt < testJob.MaxAttempts; attempt++ {
+		now = now.Add(jobs.Backoff(attempt))
+		s.work(w, now)
+	}
+	s.reload(&job, job.ID)
+	if job.Status != models.JobDead || job.Attempts != testJob.MaxAttempts || calls != testJob.MaxAttempts {
+		t.Fatalf("expected the job dead after %d attempts, got %+v after %d calls", testJob.MaxAttempts, job, calls)
+	}
+
+	for _, value := range []string{"permanent", "panic"} {
+		job := s.enqueueTestJob(value, jobs.Options{})
+		s.work(w, time.Now())
+		s.reload(&job, job.ID)
+		if value == "permanent" && (job.Status != models.JobDead || job.Attempts != 1) {
+			t.Errorf("expected a permanent failure to skip retries, got %+v", job)
+		}
+		if value == "panic" && (job.Status != models.JobQueued || job.LastError != "panic: boom") {
+			t.Errorf("expected a panic to count as a failed attempt, got %+v", job)
+		}
+	}
+}
+
+func TestJobVisibilityTimeout(t *testing.T) {
+	s := newTestServer(t)
+	ran := 0
+	w := s.testWorker(func(ctx context.Context, payload testPayload) error {
+		ran++
+		return nil
+	})
+
+	// Two jobs whose worker died mid-run, one of them on its last attempt
+	now := time.Now()
+	expired := now.Add(-time.Second)
+	crashed := s.enqueueTestJob("crashed", jobs.Options{})
+	exhausted := s.enqueueTestJob("exhausted", jobs.Options{})
+	s.db.Model(&crashed).Updates(map[string]interface{}{"status": models.JobRunning, "attempts": 1, "locked_by": "gone", "locked_until": expired})
+	s.db.Model(&exhausted).Updates(map[string]interface{}{"status": models.JobRunning, "attempts": 3, "locked_by": "gone", "locked_until": expired})
+
+	// A job still within its timeout is left to its worker
+	busy := s.enqueueTestJob("busy", jobs.Options{})
+	s.db.Model(&busy).Updates(map[string]interface{}{"status": models.JobRunning, "attempts": 1, "locked_by": "alive", "locked_until": now.Add(time.Minute)})
+
+	s.work(w, now)
+	if ran != 1 {
+		t.Fatalf("expected only the crashed job to run again, ran %d", ran)
+	}
+
+	s.reload(&crashed, crashed.ID)
+	if crashed.Status != models.JobSucceeded || crashed.Attempts != 2 {
+		t.Errorf("expected the crashed job taken over, got %+v", crashed)
+	}
+	s.reload(&exhausted, exhausted.ID)
+	if exhausted.Status != models.JobDead || exhausted.LastError != "timed out" {
+		t.Errorf("expected the exhausted job dead, got %+v", exhausted)
+	}
+	s.reload(&busy, busy.ID)
+	if busy.Status != models.JobRunning || busy.LockedBy != "alive" {
+		t.Errorf("expected the busy job untouched, got %+v", busy)
+	}
+}
+
+func TestPeriodicJobRunsOncePerInterval(t *testing.T) {
+	s := newTestServer(t)
+	ran := 0
+	newWorker := func() *jobs.Worker {
+		w := s.testWorker(func(ctx context.Context, payload testPayload) error {
+			ran++
+			return nil
+		})
+		jobs.Every(w, testJob, time.Minute, testPayload{Value: "tick"})
+		return w
+	}
+	first, second := newWorker(), newWorker()
+
+	start := time.Now().Truncate(time.Minute)
+	for _, at := range []time.Time{start.Add(time.Second), start.Add(30 * time.Second)} {
+		for _, w := range []*jobs.Worker{first, second} {
+			if err := w.Schedule(context.Background(), at); err != nil {
+				t.Fatal(err)
+			}
+			s.work(w, at)
+		}
+	}
+	if ran != 1 {
+		t.Fatalf("expected one run within the interval, got %d", ran)
+	}
+
+	next := start.Add(time.Minute)
+	if err := second.Schedule(context.Background(), next); err != nil {
+		t.Fatal(err)
+	}
+	s.work(first, next)
+	if ran != 2 {
+		t.Errorf("expected another run in the next interval, got %d", ran)
+	}
+}
+
+func TestAccountEmailsAreQueued(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser("client")
+
+	for i := 0; i < 2; i++ {
+		s.do(http.MethodPost, "/api/auth/forgot-password", "", map[string]string{"email": user.Email}).expect(http.StatusOK)
+	}
+	if _, ok := s.mail.Last(user.Email); ok {
+		t.Fatal("expected the email to wait for the worker")
+	}
+
+	var queued int64
+	s.db.Model(&models.Job{}).Where("type = ?", "email.reset_password").Count(&queued)
+	if queued != 1 {
+		t.Fatalf("expected repeated requests to queue one email, got %d", queued)
+	}
+
+	s.runJobs()
+	message, ok := s.mail.Last(user.Email)
+	if !ok {
+		t.Fatal("expected the worker to send the reset email")
+	}
+	verificationToken(t, message.Text)
+}
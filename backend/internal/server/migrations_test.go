@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"freelance-platform/internal/auth"
+	"freelance-platform/internal/handlers"
+	"freelance-platform/internal/jobs"
+	"freelance-platform/internal/migrate"
+	"freelance-platform/internal/models"
+	"freelance-platform/migrations"
+
+	"gorm.io/gorm"
+)
+
+// The models as they were when AutoMigrate still managed the schema, before
+// any of the columns the SQL migrations know about were added
+
+type legacyUser struct {
+	ID                uint   `gorm:"primaryKey"`
+	Email             string `gorm:"uniqueIndex;not null"`
+	Password          string `gorm:"not null"`
+	Name              string `gorm:"not null"`
+	Avatar            string
+	Bio               string
+	Skills            string
+	Role              string  `gorm:"default:freelancer"`
+	Rating            float64 `gorm:"default:0"`
+	CompletedProjects int     `gorm:"default:0"`
+	Profession        string
+	Experience        string
+	Portfolio         string
+	HourlyRate        int
+	Available         bool `gorm:"default:true"`
+	City              string
+	Website           string
+	LinkedIn          string
+	GitHub            string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyUser) TableName() string { return "users" }
+
+type legacyProject struct {
+	ID           uint   `gorm:"primaryKey"`
+	Title        string `gorm:"not null"`
+	Description  string `gorm:"type:text"`
+	BudgetMin    int    `gorm:"not null"`
+	BudgetMax    int    `gorm:"not null"`
+	Currency     string `gorm:"default:TWD"`
+	Category     string `gorm:"not null"`
+	Location     string `gorm:"not null"`
+	Skills       string
+	Requirements string
+	Urgency      string `gorm:"default:一般"`
+	Status       string `gorm:"default:open"`
+	ClientID     uint   `gorm:"not null"`
+	FreelancerID *uint
+	Deadline     *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyProject) TableName() string { return "projects" }
+
+type legacyBid struct {
+	ID           uint   `gorm:"primaryKey"`
+	ProjectID    uint   `gorm:"not null"`
+	FreelancerID uint   `gorm:"not null"`
+	Amount       int    `gorm:"not null"`
+	Proposal     string `gorm:"type:text"`
+	Timeline     string
+	Status       string `gorm:"default:pending"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyBid) TableName() string { return "bids" }
+
+type legacyChat struct {
+	ID               uint `gorm:"primaryKey"`
+	ProjectID        uint `gorm:"not null"`
+	ClientID         uint `gorm:"not null"`
+	FreelancerID     uint `gorm:"not null"`
+	ClientHidden     bool `gorm:"default:false"`
+	FreelancerHidden bool `gorm:"default:false"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyChat) TableName() string { return "chats" }
+
+type legacyMessage struct {
+	ID        uint   `gorm:"primaryKey"`
+	ChatID    uint   `gorm:"not null"`
+	SenderID  uint   `gorm:"not null"`
+	Content   string `gorm:"type:text;not null"`
+	Type      string `gorm:"default:text"`
+	FileURL   string
+	ReadAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyMessage) TableName() string { return "messages" }
+
+func TestMigrationsUpgradeAutoMigrateDatabase(t *testing.T) {
+	testDatabase(t)
+
+	schema := "legacy_" + randomSuffix()
+	db, err := openSchema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { testEnv.admin.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schema)) })
+
+	if err := db.AutoMigrate(&legacyUser{}, &legacyProject{}, &legacyBid{}, &legacyChat{}, &legacyMessage{}); err != nil {
+		t.Fatalf("create the legacy schema: %v", err)
+	}
+	existing := legacyUser{Email: "old@example.com", Password: "x", Name: "Old", Role: "client"}
+	if err := db.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrate.New(sqlDB, loaded).Up(context.Background()); err != nil {
+		t.Fatalf("migrate the legacy schema: %v", err)
+	}
+
+	// Every column the current models map has to be there
+	for _, model := range []interface{}{
+		&models.User{}, &models.Project{}, &models.Bid{}, &models.Chat{}, &models.Message{}, &models.Milestone{},
+	} {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(model, field.DBName) {
+				t.Errorf("%s.%s is missing", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+	for table, index := range map[string]string{"users": "idx_users_status", "bids": "idx_bids_expires_at"} {
+		if !db.Migrator().HasIndex(table, index) {
+			t.Errorf("index %s is missing", index)
+		}
+	}
+
+	var user models.User
+	if err := db.First(&user, existing.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.Status != "active" || user.TwoFactorEnabled {
+		t.Errorf("expected the existing user active without 2FA, got status %q and 2FA %v", user.Status, user.TwoFactorEnabled)
+	}
+	if user.EmailVerifiedAt == nil || !user.EmailVerifiedAt.Equal(user.CreatedAt) {
+		t.Errorf("expected the existing user verified since signing up, got %v", user.EmailVerifiedAt)
+	}
+}
+
+func TestEmailVerificationBackfillSkipsNewAccounts(t *testing.T) {
+	testDatabase(t)
+
+	schema := "backfill_" + randomSuffix()
+	db, err := openSchema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { testEnv.admin.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schema)) })
+
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := migrate.New(sqlDB, loaded)
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(email string) models.User {
+		user := models.User{Email: email, Password: "x", Name: email, Role: "client"}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	existing := create("old@example.com")
+	linked := create("linked@example.com")
+	queued := create("queued@example.com")
+	db.Create(&models.UserToken{UserID: linked.ID, Purpose: auth.PurposeVerifyEmail, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)})
+	if _, err := handlers.VerificationEmailJob.Enqueue(context.Background(), db, handlers.AccountEmail{UserID: queued.ID}, jobs.Options{
+		UniqueKey: fmt.Sprintf("%s:%d", handlers.VerificationEmailJob.Name, queued.ID),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, user := range []models.User{existing, linked, queued} {
+		db.First(&user, user.ID)
+		if verified := user.EmailVerifiedAt != nil; verified != (user.ID == existing.ID) {
+			t.Errorf("%s: expected verified to be %v", user.Email, !verified)
+		}
+	}
+}
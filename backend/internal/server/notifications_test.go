@@ -0,0 +1,339 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/services"
+)
+
+type notificationList struct {
+	Notifications []models.Notification `json:"notifications"`
+}
+
+// notificationsFor returns every notification of user, newest first
+func (s *testServer) notificationsFor(user models.User) []models.Notification {
+	s.t.Helper()
+
+	var list notificationList
+	s.as(user, http.MethodGet, "/api/notifications", nil).expect(http.StatusOK).decode(&list)
+	return list.Notifications
+}
+
+func TestBidNotifiesClient(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).expect(http.StatusCreated)
+
+	notifications := s.notificationsFor(client)
+	if len(notifications) != 1 || notifications[0].Type != "bid.created" {
+		t.Fatalf("expected one bid notification, got %+v", notifications)
+	}
+	if notifications[0].ProjectID == nil || *notifications[0].ProjectID != project.ID {
+		t.Errorf("expected the notification to link the project, got %+v", notifications[0])
+	}
+	if len(s.notificationsFor(freelancer)) != 0 {
+		t.Error("the bidder should not be notified of their own bid")
+	}
+}
+
+func TestAcceptBidNotifiesBidders(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	winner := s.createUser("freelancer")
+	loser := s.createUser("freelancer")
+	project := s.createProject(client)
+	bid := s.createBid(winner, project, 20000)
+	s.createBid(loser, project, 30000)
+
+	s.as(client, http.MethodPut, fmt.Sprintf("/api/bids/%d/accept", bid.ID), nil).expect(http.StatusOK)
+
+	if notifications := s.notificationsFor(winner); len(notifications) != 1 || notifications[0].Type != "bid.accepted" {
+		t.Errorf("expected the winner to hear about it, got %+v", notifications)
+	}
+	if notifications := s.notificationsFor(loser); len(notifications) != 1 || notifications[0].Type != "bid.rejected" {
+		t.Errorf("expected the other bidder to hear about it, got %+v", notifications)
+	}
+}
+
+func TestStatusChangeNotifiesBidders(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	s.createBid(freelancer, project, 20000)
+
+	s.as(client, http.MethodPut, fmt.Sprintf("/api/projects/%d/status", project.ID), map[string]string{"status": "cancelled"}).
+		expect(http.StatusOK)
+	s.as(client, http.MethodDelete, fmt.Sprintf("/api/projects/%d", project.ID), nil).expect(http.StatusOK)
+
+	notifications := s.notificationsFor(freelancer)
+	if len(notifications) != 2 {
+		t.Fatalf("expected a notification per change, got %+v", notifications)
+	}
+	for _, notification := range notifications {
+		if notification.Type != "project.status_changed" {
+			t.Errorf("unexpected notification %+v", notification)
+		}
+	}
+	if !strings.Contains(notifications[0].Body, "刪除") || !strings.Contains(notifications[1].Body, "已取消") {
+		t.Errorf("expected the newest change first, got %q then %q", notifications[0].Body, notifications[1].Body)
+	}
+
+	// The client made the changes and isn't told about them
+	if len(s.notificationsFor(client)) != 0 {
+		t.Error("the client should not be notified of their own changes")
+	}
+}
+
+func TestMessageNotificationsCollapsePerChat(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	chat := s.createChat(s.createProject(client), freelancer)
+
+	for _, content := range []string{"您好", "請問何時可以開始？"} {
+		s.as(client, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": content}).
+			expect(http.StatusCreated)
+	}
+
+	notifications := s.notificationsFor(freelancer)
+	if len(notifications) != 1 || notifications[0].Body != "請問何時可以開始？" {
+		t.Fatalf("expected one notification quoting the latest message, got %+v", notifications)
+	}
+
+	// Once read, the next message starts a new notification
+	s.as(freelancer, http.MethodPut, fmt.Sprintf("/api/notifications/%d/read", notifications[0].ID), nil).
+		expect(http.StatusOK)
+	s.as(client, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": "在嗎？"}).
+		expect(http.StatusCreated)
+
+	if notifications := s.notificationsFor(freelancer); len(notifications) != 2 {
+		t.Errorf("expected a second notification, got %+v", notifications)
+	}
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	otherClient := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	for _, project := range []models.Project{s.createProject(client), s.createProject(client)} {
+		s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).expect(http.StatusCreated)
+	}
+
+	var unread unreadResponse
+	s.as(client, http.MethodGet, "/api/notifications/unread-count", nil).expect(http.StatusOK).decode(&unread)
+	if unread.UnreadCount != 2 {
+		t.Fatalf("expected 2 unread notifications, got %d", unread.UnreadCount)
+	}
+
+	// Only the recipient can mark a notification read
+	notifications := s.notificationsFor(client)
+	path := fmt.Sprintf("/api/notifications/%d/read", notifications[0].ID)
+	s.as(otherClient, http.MethodPut, path, nil).expect(http.StatusNotFound)
+
+	var read struct {
+		Notification models.Notification `json:"notification"`
+	}
+	s.as(client, http.MethodPut, path, nil).expect(http.StatusOK).decode(&read)
+	if read.Notification.ReadAt == nil {
+		t.Errorf("expected the notification to be read, got %+v", read.Notification)
+	}
+
+	var list notificationList
+	s.as(client, http.MethodGet, "/api/notifications?unread=true", nil).expect(http.StatusOK).decode(&list)
+	if len(list.Notifications) != 1 || list.Notifications[0].ID != notifications[1].ID {
+		t.Errorf("expected only the other notification to be unread, got %+v", list.Notifications)
+	}
+
+	var all struct {
+		Updated int64 `json:"updated"`
+	}
+	s.as(client, http.MethodPut, "/api/notifications/read-all", nil).expect(http.StatusOK).decode(&all)
+	if all.Updated != 1 {
+		t.Errorf("expected 1 notification marked read, got %d", all.Updated)
+	}
+
+	s.as(client, http.MethodGet, "/api/notifications/unread-count", nil).expect(http.StatusOK).decode(&unread)
+	if unread.UnreadCount != 0 {
+		t.Errorf("expected no unread notifications, got %d", unread.UnreadCount)
+	}
+}
+
+// sendEmails runs the notification email job as if it were now
+func (s *testServer) sendEmails(now time.Time) int {
+	s.t.Helper()
+
+	sent, err := s.svc.Notifications.SendEmails(context.Background(), now)
+	if err != nil {
+		s.t.Fatalf("send emails: %v", err)
+	}
+	return sent
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+
+	var current struct {
+		Preferences services.NotificationPreferences `json:"preferences"`
+	}
+	s.as(client, http.MethodGet, "/api/notifications/preferences", nil).expect(http.StatusOK).decode(&current)
+	if current.Preferences.Channels["bid.created"] != "in_app" || current.Preferences.Channels["project.matched"] != "none" ||
+		current.Preferences.QuietHours != nil || current.Preferences.Locale != "zh-TW" {
+		t.Errorf("unexpected defaults %+v", current.Preferences)
+	}
+
+	for _, invalid := range []map[string]interface{}{
+		{"channels": map[string]string{"bid.created": "sms"}},
+		{"channels": map[string]string{"bid.teleported": "email"}},
+		{"channels": map[string]string{"project.matched": "in_app"}},
+		{"quiet_hours": map[string]string{"start": "22:00", "end": "22:00"}},
+		{"quiet_hours": map[string]string{"start": "late", "end": "08:00"}},
+		{"locale": "fr"},
+	} {
+		s.as(client, http.MethodPut, "/api/notifications/preferences", invalid).expect(http.StatusBadRequest)
+	}
+
+	s.as(client, http.MethodPut, "/api/notifications/preferences", map[string]interface{}{
+		"channels":    map[string]string{"bid.created": "none"},
+		"quiet_hours": map[string]string{"start": "22:00", "end": "08:00"},
+		"locale":      "en",
+	}).expect(http.StatusOK).decode(&current)
+	if current.Preferences.Channels["bid.created"] != "none" || current.Preferences.Channels["bid.accepted"] != "in_app" ||
+		current.Preferences.QuietHours == nil || current.Preferences.QuietHours.Start != "22:00" || current.Preferences.Locale != "en" {
+		t.Errorf("unexpected preferences after update %+v", current.Preferences)
+	}
+
+	// Muted types don't reach the notification center
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(s.createProject(client).ID, 20000)).expect(http.StatusCreated)
+	if notifications := s.notificationsFor(client); len(notifications) != 0 {
+		t.Errorf("expected no notifications, got %+v", notifications)
+	}
+
+	// Quiet hours are turned off with an empty window
+	s.as(client, http.MethodPut, "/api/notifications/preferences", map[string]interface{}{
+		"quiet_hours": map[string]string{"start": "", "end": ""},
+	}).expect(http.StatusOK).decode(&current)
+	if current.Preferences.QuietHours != nil || current.Preferences.Locale != "en" {
+		t.Errorf("expected only the quiet hours to be cleared, got %+v", current.Preferences)
+	}
+}
+
+func TestEmailNotifications(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+
+	s.as(client, http.MethodPut, "/api/notifications/preferences", map[string]interface{}{
+		"channels": map[string]string{"bid.created": "email"},
+	}).expect(http.StatusOK)
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).expect(http.StatusCreated)
+
+	// Nothing goes out during quiet hours
+	taipei := time.FixedZone("Asia/Taipei", 8*60*60)
+	now := time.Date(2024, 6, 3, 23, 30, 0, 0, taipei)
+	s.as(client, http.MethodPut, "/api/notifications/preferences", map[string]interface{}{
+		"quiet_hours": map[string]string{"start": "22:00", "end": "08:00"},
+	}).expect(http.StatusOK)
+	if sent := s.sendEmails(now); sent != 0 {
+		t.Fatalf("expected no email during quiet hours, sent %d", sent)
+	}
+
+	// ...and is sent once they end, only once
+	morning := now.Add(9 * time.Hour)
+	if sent := s.sendEmails(morning); sent != 1 {
+		t.Fatalf("expected one email after quiet hours, sent %d", sent)
+	}
+	message, ok := s.mail.Last(client.Email)
+	if !ok || !strings.Contains(message.Subject, project.Title) || !strings.Contains(message.Text, freelancer.Name) {
+		t.Errorf("unexpected email %+v", message)
+	}
+	if sent := s.sendEmails(morning.Add(time.Minute)); sent != 0 {
+		t.Errorf("expected the email not to be sent twice, sent %d", sent)
+	}
+}
+
+func TestNotificationDigest(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	chat := s.createChat(s.createProject(client), freelancer)
+	if err := s.db.Model(&freelancer).Update("skills", `["React", "Go"]`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s.as(freelancer, http.MethodPut, "/api/notifications/preferences", map[string]interface{}{
+		"channels": map[string]string{"message.received": "email", "project.matched": "email"},
+		"locale":   "en",
+	}).expect(http.StatusOK)
+
+	s.as(client, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": "Can you start Monday?"}).
+		expect(http.StatusCreated)
+	matching := s.createProject(client)
+	s.db.Model(&matching).Update("skills", `["React"]`)
+	other := s.createProject(client)
+	s.db.Model(&other).Update("skills", `["Photoshop"]`)
+
+	now := time.Now()
+	if sent := s.sendEmails(now); sent != 1 {
+		t.Fatalf("expected a single digest, sent %d", sent)
+	}
+	message, _ := s.mail.Last(freelancer.Email)
+	if !strings.Contains(message.Subject, "digest") {
+		t.Errorf("expected an English digest, got %q", message.Subject)
+	}
+	for _, want := range []string{"Can you start Monday?", client.Name, matching.Title} {
+		if !strings.Contains(message.Text, want) || !strings.Contains(message.HTML, want) {
+			t.Errorf("expected the digest to mention %q, got %s", want, message.Text)
+		}
+	}
+	if strings.Contains(message.Text, other.Title) {
+		t.Errorf("expected projects without matching skills to be left out, got %s", message.Text)
+	}
+
+	// Another message waits for the next digest
+	s.as(client, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": "Hello?"}).
+		expect(http.StatusCreated)
+	if sent := s.sendEmails(now.Add(time.Hour)); sent != 0 {
+		t.Errorf("expected no digest before the interval passes, sent %d", sent)
+	}
+	if sent := s.sendEmails(now.Add(25 * time.Hour)); sent != 1 {
+		t.Errorf("expected the next digest, sent %d", sent)
+	}
+}
+
+func TestReadChatIsLeftOutOfDigest(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	chat := s.createChat(s.createProject(client), freelancer)
+
+	s.as(freelancer, http.MethodPut, "/api/notifications/preferences", map[string]interface{}{
+		"channels": map[string]string{"message.received": "email"},
+	}).expect(http.StatusOK)
+	s.as(client, http.MethodPost, "/api/messages", map[string]interface{}{"chat_id": chat.ID, "content": "Can you start Monday?"}).
+		expect(http.StatusCreated)
+
+	// Reading the chat settles its notification before the digest goes out
+	s.as(freelancer, http.MethodPut, fmt.Sprintf("/api/chats/%d/read", chat.ID), nil).expect(http.StatusOK)
+	var unread unreadResponse
+	s.as(freelancer, http.MethodGet, "/api/notifications/unread-count", nil).expect(http.StatusOK).decode(&unread)
+	if unread.UnreadCount != 0 {
+		t.Errorf("expected no unread notifications after reading the chat, got %d", unread.UnreadCount)
+	}
+	if sent := s.sendEmails(time.Now()); sent != 0 {
+		t.Errorf("expected no digest for a chat already read, sent %d", sent)
+	}
+}
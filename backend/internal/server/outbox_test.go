@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/jobs"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+)
+
+// testBroker records the events published to it, or refuses them with err
+type testBroker struct {
+	err       error
+	published []events.Event
+}
+
+func (b *testBroker) Publish(ctx context.Context, event events.Event) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.published = append(b.published, event)
+	return nil
+}
+
+// withoutFlush leaves recorded events in the outbox, as if the API died right after committing them
+func (s *testServer) withoutFlush() {
+	relay := outbox.DefaultRelay
+	outbox.DefaultRelay = nil
+	s.t.Cleanup(func() { outbox.DefaultRelay = relay })
+}
+
+func (s *testServer) outboxEvents(eventType string) []models.OutboxEvent {
+	s.t.Helper()
+
+	var rows []models.OutboxEvent
+	if err := s.db.Where("type = ?", eventType).Order("id").Find(&rows).Error; err != nil {
+		s.t.Fatalf("list outbox events: %v", err)
+	}
+	return rows
+}
+
+func TestDeleteProjectRollsBackWithItsEvent(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(owner)
+	chat := s.createChat(project, freelancer)
+	url := fmt.Sprintf("/api/projects/%d", project.ID)
+
+	// Make writing the event fail after the messages and the project were saved
+	s.db.Exec(`ALTER TABLE outbox_events ADD CONSTRAINT chk_test_no_status_events CHECK (type <> 'project.status_changed')`)
+	s.as(owner, http.MethodDelete, url, nil).expect(http.StatusInternalServerError)
+
+	s.reload(&project, project.ID)
+	var messages int64
+	s.db.Model(&models.Message{}).Where("chat_id = ?", chat.ID).Count(&messages)
+	if project.Status != "open" || messages != 0 {
+		t.Fatalf("expected nothing to change, got status %s and %d messages", project.Status, messages)
+	}
+
+	s.db.Exec(`ALTER TABLE outbox_events DROP CONSTRAINT chk_test_no_status_events`)
+	s.as(owner, http.MethodDelete, url, nil).expect(http.StatusOK)
+
+	rows := s.outboxEvents(events.ProjectStatusChanged)
+	if len(rows) != 1 || rows[0].PublishedAt == nil || rows[0].ActorID != owner.ID {
+		t.Fatalf("expected one published event by the owner, got %+v", rows)
+	}
+}
+
+func TestOutboxEventsSurviveACrash(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+
+	s.withoutFlush()
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).expect(http.StatusCreated)
+
+	rows := s.outboxEvents(events.BidCreated)
+	if len(rows) != 1 || rows[0].PublishedAt != nil || rows[0].EventID == "" {
+		t.Fatalf("expected the event waiting in the outbox, got %+v", rows)
+	}
+	if notifications := s.notificationsFor(client); len(notifications) != 0 {
+		t.Fatalf("expected no notification before the event is relayed, got %+v", notifications)
+	}
+
+	// The worker's relay picks it up
+	if err := s.worker.Schedule(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s.runJobs()
+
+	if notifications := s.notificationsFor(client); len(notifications) != 1 || notifications[0].Type != events.BidCreated {
+		t.Fatalf("expected the relayed event to notify the client, got %+v", notifications)
+	}
+	s.reload(&rows[0], rows[0].ID)
+	if rows[0].PublishedAt == nil || rows[0].Attempts != 1 {
+		t.Errorf("expected the event marked published, got %+v", rows[0])
+	}
+}
+
+func TestRequestsFlushOnlyTheirOwnEvents(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	other := s.createUser("freelancer")
+
+	// An event left behind by a request that died before flushing
+	relay := outbox.DefaultRelay
+	outbox.DefaultRelay = nil
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(s.createProject(client).ID, 20000)).expect(http.StatusCreated)
+	outbox.DefaultRelay = relay
+
+	s.as(other, http.MethodPost, "/api/bids", bidRequest(s.createProject(client).ID, 20000)).expect(http.StatusCreated)
+
+	rows := s.outboxEvents(events.BidCreated)
+	if len(rows) != 2 || rows[0].PublishedAt != nil || rows[1].PublishedAt == nil {
+		t.Fatalf("expected only the second request's event published, got %+v", rows)
+	}
+
+	// The backlog waits for the worker's relay
+	if err := s.worker.Schedule(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s.runJobs()
+	s.reload(&rows[0], rows[0].ID)
+	if rows[0].PublishedAt == nil {
+		t.Errorf("expected the worker to publish the backlog, got %+v", rows[0])
+	}
+}
+
+func TestOutboxRedeliveryIsSkipped(t *testing.T) {
+	s := newTestServer(t)
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	created := s.createWebhook(client, receiver.URL, "bid.created")
+
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).expect(http.StatusCreated)
+
+	// A relay that died before marking the event published sends it again
+	row := s.outboxEvents(events.BidCreated)[0]
+	s.db.Model(&row).Update("published_at", nil)
+	if published, err := outbox.DefaultRelay.Flush(context.Background(), time.Now()); err != nil || published != 1 {
+		t.Fatalf("expected the event published again, got %d: %v", published, err)
+	}
+
+	if notifications := s.notificationsFor(client); len(notifications) != 1 {
+		t.Errorf("expected the client notified once, got %+v", notifications)
+	}
+	deliveries := s.webhookDeliveries(client, created.Webhook.ID)
+	if len(deliveries) != 1 || deliveries[0].EventID != row.EventID {
+		t.Errorf("expected one delivery carrying the event ID, got %+v", deliveries)
+	}
+}
+
+func TestOutboxRetriesRefusedEvents(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+
+	s.withoutFlush()
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).expect(http.StatusCreated)
+
+	broker := &testBroker{err: errors.New("broker unavailable")}
+	relay := outbox.NewRelay(s.db, s.svc.Events, broker)
+	now := time.Now()
+	if published, err := relay.Flush(context.Background(), now); err != nil || published != 0 {
+		t.Fatalf("expected nothing published, got %d: %v", published, err)
+	}
+
+	row := s.outboxEvents(events.BidCreated)[0]
+	if row.PublishedAt != nil || row.Attempts != 1 || row.LastError != "broker unavailable" {
+		t.Fatalf("expected the failure recorded, got %+v", row)
+	}
+	if !row.NextAttemptAt.After(now.Add(jobs.Backoff(1) - time.Second)) {
+		t.Errorf("expected the retry to back off, next attempt at %v", row.NextAttemptAt)
+	}
+	if notifications := s.notificationsFor(client); len(notifications) != 0 {
+		t.Fatalf("expected subscribers to wait for the broker, got %+v", notifications)
+	}
+
+	broker.err = nil
+	if published, _ := relay.Flush(context.Background(), now.Add(time.Second)); published != 0 {
+		t.Fatalf("expected no retry before the backoff, published %d", published)
+	}
+	if published, err := relay.Flush(context.Background(), now.Add(jobs.Backoff(1))); err != nil || published != 1 {
+		t.Fatalf("expected the retry to go out, got %d: %v", published, err)
+	}
+	if len(broker.published) != 1 || broker.published[0].ID != row.EventID {
+		t.Errorf("expected the broker to get the event with its ID, got %+v", broker.published)
+	}
+	if data, ok := broker.published[0].Data.(events.BidData); !ok || data.ProjectID != project.ID {
+		t.Errorf("expected the typed payload back, got %#v", broker.published[0].Data)
+	}
+	if notifications := s.notificationsFor(client); len(notifications) != 1 {
+		t.Errorf("expected the client notified once published, got %+v", notifications)
+	}
+}
+
+func TestOutboxRetriesEventsSubscribersFailed(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+
+	// A subscriber that can't handle the event the first time
+	failures := 1
+	s.svc.Events.Subscribe(func(ctx context.Context, event events.Event) error {
+		if failures > 0 {
+			failures--
+			return errors.New("search index unavailable")
+		}
+		return nil
+	}, events.BidCreated)
+
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).expect(http.StatusCreated)
+
+	row := s.outboxEvents(events.BidCreated)[0]
+	if row.PublishedAt != nil || row.Attempts != 1 || row.LastError != "search index unavailable" {
+		t.Fatalf("expected the event kept for a retry, got %+v", row)
+	}
+
+	if published, err := outbox.DefaultRelay.Flush(context.Background(), time.Now().Add(jobs.Backoff(1))); err != nil || published != 1 {
+		t.Fatalf("expected the retry to go out, got %d: %v", published, err)
+	}
+	s.reload(&row, row.ID)
+	if row.PublishedAt == nil {
+		t.Errorf("expected the event marked published, got %+v", row)
+	}
+	// The notification center handled it the first time and skips the repeat
+	if notifications := s.notificationsFor(client); len(notifications) != 1 {
+		t.Errorf("expected the client notified once, got %+v", notifications)
+	}
+}
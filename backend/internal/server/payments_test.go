@@ -0,0 +1,466 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"freelance-platform/internal/handlers"
+	"freelance-platform/internal/ledger"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/payments"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testPaymentSecret = "test-payment-secret"
+
+// testGateway is a fake payment provider that holds its webhooks until the
+// test delivers them
+type testGateway struct {
+	*payments.FakeProvider
+	s *testServer
+
+	mu       sync.Mutex
+	webhooks []testWebhook
+}
+
+type testWebhook struct {
+	payload   []byte
+	signature string
+}
+
+// usePayments makes a testGateway the payment provider for the test
+func (s *testServer) usePayments() *testGateway {
+	g := &testGateway{FakeProvider: payments.NewFakeProvider(testPaymentSecret, 0), s: s}
+	g.Deliver = func(payload []byte, signature string) error {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.webhooks = append(g.webhooks, testWebhook{payload: payload, signature: signature})
+		return nil
+	}
+
+	previous := payments.DefaultProvider
+	payments.DefaultProvider = g.FakeProvider
+	s.t.Cleanup(func() { payments.DefaultProvider = previous })
+	return g
+}
+
+// take returns the webhooks the provider has sent since the last call
+func (g *testGateway) take() []testWebhook {
+	g.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	webhooks := g.webhooks
+	g.webhooks = nil
+	return webhooks
+}
+
+// deliverAll posts the webhooks the provider has sent, expecting each accepted
+func (g *testGateway) deliverAll() {
+	g.s.t.Helper()
+
+	for _, webhook := range g.take() {
+		g.s.postWebhook(webhook.payload, webhook.signature).expect(http.StatusOK)
+	}
+}
+
+// signed builds a webhook the provider could have sent
+func (g *testGateway) signed(id, eventType, objectID, reference string, amount int64) testWebhook {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":        id,
+		"type":      eventType,
+		"object_id": objectID,
+		"reference": reference,
+		"amount":    amount,
+	})
+	if err != nil {
+		g.s.t.Fatal(err)
+	}
+	return testWebhook{payload: payload, signature: payments.Sign(testPaymentSecret, payload, time.Now())}
+}
+
+func (s *testServer) postWebhook(payload []byte, signature string) *testResponse {
+	s.t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/payments/webhook/fake", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Fake-Signature", signature)
+
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, req)
+	return &testResponse{t: s.t, ResponseRecorder: recorder}
+}
+
+// createContract inserts a project in progress with an active contract of a
+// single unfunded milestone worth amount
+func (s *testServer) createContract(client, freelancer models.User, amount int) (models.Project, models.Milestone) {
+	s.t.Helper()
+
+	project := s.createProject(client)
+	s.db.Model(&project).Update("status", "in_progress")
+	bid := s.createBid(freelancer, project, amount)
+
+	contract := models.Contract{
+		ProjectID:    project.ID,
+		BidID:        bid.ID,
+		ClientID:     client.ID,
+		FreelancerID: freelancer.ID,
+		Amount:       amount,
+		Status:       "active",
+	}
+	if err := s.db.Create(&contract).Error; err != nil {
+		s.t.Fatalf("create contract: %v", err)
+	}
+
+	milestone := models.Milestone{ContractID: contract.ID, Position: 1, Title: "Delivery", Amount: amount}
+	if err := s.db.Create(&milestone).Error; err != nil {
+		s.t.Fatalf("create milestone: %v", err)
+	}
+	return project, milestone
+}
+
+// startPayment funds a milestone through the API and confirms the payment;
+// the provider's webhook is left with g
+func (s *testServer) startPayment(client models.User, milestone models.Milestone) models.Payment {
+	s.t.Helper()
+
+	var funded struct {
+		Payment models.Payment `json:"payment"`
+	}
+	s.as(client, http.MethodPost, fmt.Sprintf("/api/milestones/%d/fund", milestone.ID), nil).
+		expect(http.StatusCreated).decode(&funded)
+	s.as(client, http.MethodPost, fmt.Sprintf("/api/payments/%d/confirm", funded.Payment.ID), nil).
+		expect(http.StatusOK)
+	return funded.Payment
+}
+
+// expectBalancedLedger fails the test if the ledger doesn't reconcile
+func (s *testServer) expectBalancedLedger() {
+	s.t.Helper()
+
+	issues, err := ledger.Reconcile(s.db)
+	if err != nil {
+		s.t.Fatalf("reconcile: %v", err)
+	}
+	if len(issues) > 0 {
+		s.t.Fatalf("expected a balanced ledger, got %+v", issues)
+	}
+}
+
+func (s *testServer) countEntries(kind string) int64 {
+	s.t.Helper()
+
+	var count int64
+	s.db.Model(&models.JournalEntry{}).Where("kind = ?", kind).Count(&count)
+	return count
+}
+
+func TestPaymentWebhookFundsEscrowOnce(t *testing.T) {
+	s := newTestServer(t)
+	g := s.usePayments()
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	_, milestone := s.createContract(client, freelancer, 30000)
+
+	payment := s.startPayment(client, milestone)
+	webhooks := g.take()
+	if len(webhooks) != 1 {
+		t.Fatalf("expected one webhook, got %d", len(webhooks))
+	}
+	webhook := webhooks[0]
+
+	// Anything not signed with our secret is turned away
+	tampered := bytes.Replace(webhook.payload, []byte("30000"), []byte("1"), 1)
+	s.postWebhook(tampered, webhook.signature).expect(http.StatusBadRequest)
+	s.postWebhook(webhook.payload, "").expect(http.StatusBadRequest)
+	forged := payments.Sign(payments.DefaultFakeWebhookSecret, webhook.payload, time.Now())
+	s.postWebhook(webhook.payload, forged).expect(http.StatusBadRequest)
+
+	s.postWebhook(webhook.payload, webhook.signature).expect(http.StatusOK)
+	s.postWebhook(webhook.payload, webhook.signature).expect(http.StatusOK)
+
+	s.reload(&payment, payment.ID)
+	s.reload(&milestone, milestone.ID)
+	if payment.Status != "succeeded" || milestone.EscrowStatus != "funded" || milestone.FundedAt == nil {
+		t.Fatalf("expected the milestone funded, got payment %s and escrow %s", payment.Status, milestone.EscrowStatus)
+	}
+	if funds := s.countEntries("fund"); funds != 1 {
+		t.Errorf("expected one funding entry, got %d", funds)
+	}
+	var events int64
+	s.db.Model(&models.PaymentEvent{}).Count(&events)
+	if events != 1 {
+		t.Errorf("expected the event recorded once, got %d", events)
+	}
+	s.expectBalancedLedger()
+}
+
+func TestPaymentWebhookIgnoresStaleEvents(t *testing.T) {
+	s := newTestServer(t)
+	g := s.usePayments()
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	_, milestone := s.createContract(client, freelancer, 30000)
+
+	payment := s.startPayment(client, milestone)
+	g.deliverAll()
+	s.reload(&payment, payment.ID)
+
+	// A failure or refund the provider reports out of order leaves the settled charge alone
+	reference := fmt.Sprintf("payment:%d", payment.ID)
+	for i, eventType := range []string{payments.EventPaymentFailed, payments.EventRefundSucceeded} {
+		stale := g.signed(fmt.Sprintf("evt_stale_%d", i), eventType, payment.ProviderIntentID, reference, 30000)
+		s.postWebhook(stale.payload, stale.signature).expect(http.StatusOK)
+	}
+
+	s.reload(&payment, payment.ID)
+	s.reload(&milestone, milestone.ID)
+	if payment.Status != "succeeded" || milestone.EscrowStatus != "funded" {
+		t.Fatalf("expected the funded payment untouched, got payment %s and escrow %s", payment.Status, milestone.EscrowStatus)
+	}
+	s.expectBalancedLedger()
+}
+
+func TestPaymentWebhookBeforeThePaymentIsRecorded(t *testing.T) {
+	s := newTestServer(t)
+	g := s.usePayments()
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	_, milestone := s.createContract(client, freelancer, 20000)
+
+	// Nothing matches yet: the provider has to try again later
+	unknown := g.signed("evt_early", payments.EventPaymentSucceeded, "pi_early", "payment:999", 20000)
+	s.postWebhook(unknown.payload, unknown.signature).expect(http.StatusServiceUnavailable)
+	var events int64
+	s.db.Model(&models.PaymentEvent{}).Count(&events)
+	if events != 0 {
+		t.Fatalf("expected the event not to be recorded, got %d", events)
+	}
+
+	// The payment exists but the API has not stored the intent ID yet
+	payment := models.Payment{
+		MilestoneID: milestone.ID,
+		ClientID:    client.ID,
+		Provider:    "fake",
+		Amount:      20000,
+		Currency:    "TWD",
+		Status:      "processing",
+	}
+	if err := s.db.Create(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	early := g.signed("evt_early", payments.EventPaymentSucceeded, "pi_early", fmt.Sprintf("payment:%d", payment.ID), 20000)
+	s.postWebhook(early.payload, early.signature).expect(http.StatusOK)
+
+	s.reload(&payment, payment.ID)
+	s.reload(&milestone, milestone.ID)
+	if payment.ProviderIntentID != "pi_early" || payment.Status != "succeeded" || milestone.EscrowStatus != "funded" {
+		t.Fatalf("expected the payment matched by its reference, got %+v and escrow %s", payment, milestone.EscrowStatus)
+	}
+	s.expectBalancedLedger()
+}
+
+func TestSecondPaymentForAMilestoneIsRefunded(t *testing.T) {
+	s := newTestServer(t)
+	g := s.usePayments()
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	_, milestone := s.createContract(client, freelancer, 25000)
+
+	first := s.startPayment(client, milestone)
+	g.deliverAll()
+
+	// A second charge for the same milestone, say from another tab
+	intent, err := g.CreateIntent(context.Background(), payments.IntentRequest{Amount: 25000, Currency: "TWD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := models.Payment{
+		MilestoneID:      milestone.ID,
+		ClientID:         client.ID,
+		Provider:         "fake",
+		ProviderIntentID: intent.ID,
+		Amount:           25000,
+		Currency:         "TWD",
+		Status:           "processing",
+	}
+	if err := s.db.Create(&second).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.ConfirmIntent(context.Background(), intent.ID); err != nil {
+		t.Fatal(err)
+	}
+	g.deliverAll()
+
+	s.reload(&first, first.ID)
+	s.reload(&second, second.ID)
+	if first.Status != "succeeded" || second.Status != "refund_pending" {
+		t.Fatalf("expected the second charge to be refunded, got %s and %s", first.Status, second.Status)
+	}
+	if funds := s.countEntries("fund"); funds != 1 {
+		t.Fatalf("expected the milestone funded once, got %d entries", funds)
+	}
+	if webhooks := g.take(); len(webhooks) != 0 {
+		t.Fatalf("expected the provider not to be called before the job runs, got %d webhooks", len(webhooks))
+	}
+
+	s.runJobs()
+	// A job that died after calling the provider runs again without a second refund
+	if err := handlers.NewPaymentHandler(s.db).RefundPayment(context.Background(), handlers.MoneyTransfer{ID: second.ID}); err != nil {
+		t.Fatal(err)
+	}
+	webhooks := g.take()
+	if len(webhooks) != 1 {
+		t.Fatalf("expected one refund, got %d webhooks", len(webhooks))
+	}
+	s.postWebhook(webhooks[0].payload, webhooks[0].signature).expect(http.StatusOK)
+
+	s.reload(&second, second.ID)
+	if second.Status != "refunded" {
+		t.Errorf("expected the second charge refunded, got %s", second.Status)
+	}
+	s.expectBalancedLedger()
+}
+
+func TestCancellingAFundedProjectRefundsAfterCommit(t *testing.T) {
+	s := newTestServer(t)
+	g := s.usePayments()
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project, milestone := s.createContract(client, freelancer, 40000)
+
+	payment := s.startPayment(client, milestone)
+	g.deliverAll()
+
+	s.as(client, http.MethodPut, fmt.Sprintf("/api/projects/%d/status", project.ID), gin.H{"status": "cancelled"}).
+		expect(http.StatusOK)
+
+	s.reload(&payment, payment.ID)
+	s.reload(&milestone, milestone.ID)
+	if payment.Status != "refund_pending" || milestone.EscrowStatus != "refunded" {
+		t.Fatalf("expected the refund recorded, got payment %s and escrow %s", payment.Status, milestone.EscrowStatus)
+	}
+	if webhooks := g.take(); len(webhooks) != 0 {
+		t.Fatalf("expected the provider not to be called within the request, got %d webhooks", len(webhooks))
+	}
+
+	s.runJobs()
+	g.deliverAll()
+
+	s.reload(&payment, payment.ID)
+	if payment.Status != "refunded" {
+		t.Errorf("expected the payment refunded, got %s", payment.Status)
+	}
+	s.expectBalancedLedger()
+}
+
+func TestPayoutIsSentAfterCommit(t *testing.T) {
+	s := newTestServer(t)
+	g := s.usePayments()
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	_, milestone := s.createContract(client, freelancer, 10000)
+
+	s.startPayment(client, milestone)
+	g.deliverAll()
+	s.as(freelancer, http.MethodPost, fmt.Sprintf("/api/milestones/%d/submit", milestone.ID), gin.H{"note": "Done"}).
+		expect(http.StatusOK)
+	s.as(client, http.MethodPost, fmt.Sprintf("/api/milestones/%d/approve", milestone.ID), nil).
+		expect(http.StatusOK)
+
+	// 10,000 less the 10% fee
+	request := gin.H{"amount": 9000, "destination": "acct_test"}
+	s.as(freelancer, http.MethodPost, "/api/payouts", gin.H{"amount": 9001, "destination": "acct_test"}).
+		expect(http.StatusBadRequest)
+
+	var created struct {
+		Payout models.Payout `json:"payout"`
+	}
+	s.as(freelancer, http.MethodPost, "/api/payouts", request).expect(http.StatusCreated).decode(&created)
+	payout := created.Payout
+	if payout.Status != "pending" || payout.ProviderPayoutID != "" {
+		t.Fatalf("expected a pending payout not yet sent, got %+v", payout)
+	}
+	if webhooks := g.take(); len(webhooks) != 0 {
+		t.Fatalf("expected the provider not to be called within the request, got %d webhooks", len(webhooks))
+	}
+	s.as(freelancer, http.MethodPost, "/api/payouts", request).expect(http.StatusBadRequest)
+
+	s.runJobs()
+	s.reload(&payout, payout.ID)
+	if payout.ProviderPayoutID == "" {
+		t.Fatalf("expected the job to send the payout, got %+v", payout)
+	}
+
+	g.deliverAll()
+	s.reload(&payout, payout.ID)
+	if payout.Status != "paid" {
+		t.Errorf("expected the payout paid, got %s", payout.Status)
+	}
+	s.expectBalancedLedger()
+}
+
+func TestMilestonePlanIsFrozenWhilePaymentIsInFlight(t *testing.T) {
+	s := newTestServer(t)
+	g := s.usePayments()
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	_, milestone := s.createContract(client, freelancer, 30000)
+
+	payment := s.startPayment(client, milestone)
+	plan := gin.H{"milestones": []gin.H{{"title": "First half", "amount": 15000}, {"title": "Second half", "amount": 15000}}}
+	url := fmt.Sprintf("/api/contracts/%d/milestones", milestone.ContractID)
+	s.as(client, http.MethodPut, url, plan).expect(http.StatusConflict)
+
+	// A plan replaced anyway (e.g. before this check existed) gets the charge refunded
+	s.db.Delete(&milestone)
+	g.deliverAll()
+
+	s.reload(&payment, payment.ID)
+	if payment.Status != "refund_pending" {
+		t.Fatalf("expected the orphaned charge to be refunded, got %s", payment.Status)
+	}
+	s.runJobs()
+	g.deliverAll()
+
+	s.reload(&payment, payment.ID)
+	if payment.Status != "refunded" || s.countEntries("fund") != 0 {
+		t.Errorf("expected the charge refunded without funding escrow, got %s", payment.Status)
+	}
+	s.expectBalancedLedger()
+}
+
+func TestDeletingAProjectUnderContractRefundsEscrow(t *testing.T) {
+	s := newTestServer(t)
+	g := s.usePayments()
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project, milestone := s.createContract(client, freelancer, 20000)
+
+	payment := s.startPayment(client, milestone)
+	g.deliverAll()
+
+	s.as(client, http.MethodDelete, fmt.Sprintf("/api/projects/%d", project.ID), nil).expect(http.StatusOK)
+
+	var contract models.Contract
+	s.reload(&contract, milestone.ContractID)
+	s.reload(&payment, payment.ID)
+	s.reload(&milestone, milestone.ID)
+	if contract.Status != "cancelled" || payment.Status != "refund_pending" || milestone.EscrowStatus != "refunded" {
+		t.Fatalf("expected the contract cancelled and refunded, got contract %s, payment %s, escrow %s",
+			contract.Status, payment.Status, milestone.EscrowStatus)
+	}
+
+	// The freelancer can no longer deliver on it
+	s.as(freelancer, http.MethodPost, fmt.Sprintf("/api/milestones/%d/submit", milestone.ID), gin.H{"note": "done"}).
+		expect(http.StatusBadRequest)
+	s.expectBalancedLedger()
+}
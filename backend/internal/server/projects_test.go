@@ -0,0 +1,366 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/repository"
+)
+
+func projectRequest(title string) map[string]interface{} {
+	return map[string]interface{}{
+		"title":       title,
+		"description": "Build a marketing site",
+		"budget_min":  10000,
+		"budget_max":  30000,
+		"category":    "程式開發",
+		"location":    "台北市",
+	}
+}
+
+func TestCreateProject(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+
+	var created struct {
+		Project models.Project `json:"project"`
+	}
+	s.as(client, http.MethodPost, "/api/projects", projectRequest("Website")).
+		expect(http.StatusCreated).decode(&created)
+
+	if created.Project.ClientID != client.ID || created.Project.Status != "open" {
+		t.Errorf("unexpected project: %+v", created.Project)
+	}
+	if created.Project.Urgency != "一般" || created.Project.Currency != "TWD" {
+		t.Errorf("expected defaults for urgency and currency, got %q %q", created.Project.Urgency, created.Project.Currency)
+	}
+	if created.Project.Client.ID != client.ID {
+		t.Error("expected the client to be loaded")
+	}
+}
+
+func TestCreateProjectValidation(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+
+	s.do(http.MethodPost, "/api/projects", "", projectRequest("Website")).expect(http.StatusUnauthorized)
+
+	invalid := projectRequest("Website")
+	invalid["budget_min"] = 50000
+	s.as(client, http.MethodPost, "/api/projects", invalid).expect(http.StatusBadRequest)
+
+	delete(invalid, "title")
+	s.as(client, http.MethodPost, "/api/projects", invalid).expect(http.StatusBadRequest)
+}
+
+func TestListProjectsShowsOnlyOpenProjects(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+
+	open := s.createProject(client)
+	closed := s.createProject(client)
+	s.db.Model(&closed).Update("status", "cancelled")
+
+	var listed struct {
+		Projects []models.Project `json:"projects"`
+	}
+	s.do(http.MethodGet, "/api/projects", "", nil).expect(http.StatusOK).decode(&listed)
+
+	if len(listed.Projects) != 1 || listed.Projects[0].ID != open.ID {
+		t.Fatalf("expected only project %d, got %+v", open.ID, listed.Projects)
+	}
+
+	// Filters narrow the list further
+	s.do(http.MethodGet, "/api/projects?min_budget=60000", "", nil).expect(http.StatusOK).decode(&listed)
+	if len(listed.Projects) != 0 {
+		t.Errorf("expected no project above the budget range, got %d", len(listed.Projects))
+	}
+}
+
+func TestUpdateProjectOwnership(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.createUser("client")
+	other := s.createUser("client")
+	project := s.createProject(owner)
+	url := fmt.Sprintf("/api/projects/%d", project.ID)
+
+	s.as(other, http.MethodPut, url, projectRequest("Hijacked")).expect(http.StatusForbidden)
+
+	var updated struct {
+		Project models.Project `json:"project"`
+	}
+	s.as(owner, http.MethodPut, url, projectRequest("Renamed")).expect(http.StatusOK).decode(&updated)
+	if updated.Project.Title != "Renamed" {
+		t.Errorf("expected the title to change, got %q", updated.Project.Title)
+	}
+
+	s.as(owner, http.MethodPut, "/api/projects/9999", projectRequest("Missing")).expect(http.StatusNotFound)
+}
+
+func TestUpdateProjectStatus(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.createUser("client")
+	other := s.createUser("client")
+	project := s.createProject(owner)
+	url := fmt.Sprintf("/api/projects/%d/status", project.ID)
+
+	s.as(other, http.MethodPut, url, map[string]string{"status": "cancelled"}).expect(http.StatusForbidden)
+	s.as(owner, http.MethodPut, url, map[string]string{"status": "archived"}).expect(http.StatusBadRequest)
+	s.as(owner, http.MethodPut, url, map[string]string{"status": "cancelled"}).expect(http.StatusOK)
+
+	s.reload(&project, project.ID)
+	if project.Status != "cancelled" {
+		t.Errorf("expected cancelled, got %q", project.Status)
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.createUser("client")
+	other := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(owner)
+	chat := s.createChat(project, freelancer)
+	url := fmt.Sprintf("/api/projects/%d", project.ID)
+
+	s.as(other, http.MethodDelete, url, nil).expect(http.StatusForbidden)
+	s.as(owner, http.MethodDelete, url, nil).expect(http.StatusOK)
+
+	// Deleted projects are gone for everyone, and can't be deleted twice
+	s.do(http.MethodGet, url, "", nil).expect(http.StatusGone)
+	s.as(owner, http.MethodDelete, url, nil).expect(http.StatusBadRequest)
+
+	// People talking about the project are told about it
+	var messages int64
+	s.db.Model(&models.Message{}).Where("chat_id = ? AND type = ?", chat.ID, "system").Count(&messages)
+	if messages != 1 {
+		t.Errorf("expected one system message, got %d", messages)
+	}
+}
+
+func TestProjectBidsAreOwnerOnly(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(owner)
+	s.createBid(freelancer, project, 20000)
+	url := fmt.Sprintf("/api/projects/%d/bids", project.ID)
+
+	s.as(freelancer, http.MethodGet, url, nil).expect(http.StatusForbidden)
+	s.as(owner, http.MethodGet, url+"?status=bogus", nil).expect(http.StatusBadRequest)
+
+	var listed struct {
+		Bids []models.Bid `json:"bids"`
+	}
+	s.as(owner, http.MethodGet, url, nil).expect(http.StatusOK).decode(&listed)
+	if len(listed.Bids) != 1 || listed.Bids[0].FreelancerID != freelancer.ID {
+		t.Fatalf("expected the freelancer's bid, got %+v", listed.Bids)
+	}
+}
+
+func TestSearchProjects(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+
+	inDescription := s.createProject(client)
+	s.db.Model(&inDescription).Updates(map[string]interface{}{
+		"title":       "品牌形象更新",
+		"description": "公司官網改版，包含網站設計與首頁文案",
+	})
+	inTitle := s.createProject(client)
+	s.db.Model(&inTitle).Updates(map[string]interface{}{
+		"title":       "電商網站設計",
+		"description": "使用 React 與 Go 開發",
+	})
+	unrelated := s.createProject(client)
+	s.db.Model(&unrelated).Updates(map[string]interface{}{"title": "Logo 設計"})
+
+	var listed struct {
+		Projects []models.Project `json:"projects"`
+	}
+	s.do(http.MethodGet, "/api/projects?search="+url.QueryEscape("網站設計"), "", nil).
+		expect(http.StatusOK).decode(&listed)
+
+	// Title matches outrank description matches
+	if len(listed.Projects) != 2 || listed.Projects[0].ID != inTitle.ID || listed.Projects[1].ID != inDescription.ID {
+		t.Fatalf("expected projects %d then %d, got %+v", inTitle.ID, inDescription.ID, listed.Projects)
+	}
+	if listed.Projects[0].Highlight == nil || listed.Projects[0].Highlight.Title != "電商<mark>網站設計</mark>" {
+		t.Errorf("unexpected highlight: %+v", listed.Projects[0].Highlight)
+	}
+	if listed.Projects[0].SearchRank <= listed.Projects[1].SearchRank {
+		t.Errorf("expected a higher rank for the title match, got %v and %v",
+			listed.Projects[0].SearchRank, listed.Projects[1].SearchRank)
+	}
+
+	// Latin words match case-insensitively
+	s.do(http.MethodGet, "/api/projects?search=react", "", nil).expect(http.StatusOK).decode(&listed)
+	if len(listed.Projects) != 1 || listed.Projects[0].ID != inTitle.ID {
+		t.Errorf("expected only project %d, got %+v", inTitle.ID, listed.Projects)
+	}
+
+	// A single character falls back to substring matching
+	s.do(http.MethodGet, "/api/projects?search="+url.QueryEscape("商"), "", nil).expect(http.StatusOK).decode(&listed)
+	if len(listed.Projects) != 1 || listed.Projects[0].ID != inTitle.ID {
+		t.Errorf("expected only project %d, got %+v", inTitle.ID, listed.Projects)
+	}
+}
+
+func TestProjectFacets(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+
+	s.createProject(client) // 程式開發, Remote, 一般, 10,000 to 50,000
+	design := s.createProject(client)
+	s.db.Model(&design).Updates(map[string]interface{}{"category": "商業設計", "location": "台北市"})
+	urgent := s.createProject(client)
+	s.db.Model(&urgent).Updates(map[string]interface{}{
+		"location": "台北市", "urgency": "急件", "budget_min": 60000, "budget_max": 80000,
+	})
+	cancelled := s.createProject(client)
+	s.db.Model(&cancelled).Update("status", "cancelled")
+
+	var listed struct {
+		Projects []models.Project          `json:"projects"`
+		Facets   *repository.ProjectFacets `json:"facets"`
+	}
+	s.do(http.MethodGet, "/api/projects", "", nil).expect(http.StatusOK).decode(&listed)
+	if listed.Facets != nil {
+		t.Error("expected no facets unless asked for")
+	}
+
+	s.do(http.MethodGet, "/api/projects?facets=true&category="+url.QueryEscape("程式開發"), "", nil).
+		expect(http.StatusOK).decode(&listed)
+	if len(listed.Projects) != 2 || listed.Facets == nil {
+		t.Fatalf("expected 2 projects with facets, got %d projects and %+v", len(listed.Projects), listed.Facets)
+	}
+
+	// The category facet ignores the category filter, the others respect it
+	counts := func(facet []repository.FacetCount) map[string]int64 {
+		m := make(map[string]int64)
+		for _, c := range facet {
+			m[c.Value] = c.Count
+		}
+		return m
+	}
+	if got := counts(listed.Facets.Categories); got["程式開發"] != 2 || got["商業設計"] != 1 || len(got) != 2 {
+		t.Errorf("unexpected category counts: %v", got)
+	}
+	if got := counts(listed.Facets.Locations); got["Remote"] != 1 || got["台北市"] != 1 || len(got) != 2 {
+		t.Errorf("unexpected location counts: %v", got)
+	}
+	if got := counts(listed.Facets.Urgencies); got["一般"] != 1 || got["急件"] != 1 || len(got) != 2 {
+		t.Errorf("unexpected urgency counts: %v", got)
+	}
+
+	// Budget buckets count every project whose range overlaps them
+	want := []int64{0, 1, 2, 0, 0}
+	if len(listed.Facets.Budgets) != len(want) {
+		t.Fatalf("expected %d budget buckets, got %+v", len(want), listed.Facets.Budgets)
+	}
+	for i, bucket := range listed.Facets.Budgets {
+		if bucket.Count != want[i] {
+			t.Errorf("budget bucket %d+: expected %d, got %d", bucket.Min, want[i], bucket.Count)
+		}
+	}
+}
+
+type pageMeta struct {
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+	Total      *int64 `json:"total"`
+}
+
+func TestPaginateProjects(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+
+	var created []uint
+	for i := 0; i < 5; i++ {
+		created = append(created, s.createProject(client).ID)
+	}
+
+	var seen []uint
+	path := "/api/projects?limit=2&total=true"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("too many pages")
+		}
+
+		var listed struct {
+			Projects   []models.Project `json:"projects"`
+			Pagination pageMeta         `json:"pagination"`
+		}
+		s.do(http.MethodGet, path, "", nil).expect(http.StatusOK).decode(&listed)
+		if listed.Pagination.Total == nil || *listed.Pagination.Total != 5 {
+			t.Errorf("expected a total of 5, got %v", listed.Pagination.Total)
+		}
+		for _, project := range listed.Projects {
+			seen = append(seen, project.ID)
+		}
+
+		if !listed.Pagination.HasMore {
+			break
+		}
+		path = "/api/projects?limit=2&total=true&cursor=" + listed.Pagination.NextCursor
+	}
+
+	// Newest first, each project exactly once
+	want := []uint{created[4], created[3], created[2], created[1], created[0]}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, seen)
+	}
+
+	s.do(http.MethodGet, "/api/projects?cursor=bogus", "", nil).expect(http.StatusBadRequest)
+	s.do(http.MethodGet, "/api/projects?limit=0", "", nil).expect(http.StatusBadRequest)
+}
+
+func TestPaginateSearchResults(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+
+	titles := []string{"網站設計", "網站設計與網站設計維護", "企業網站設計"}
+	for _, title := range titles {
+		project := s.createProject(client)
+		s.db.Model(&project).Update("title", title)
+	}
+
+	var all struct {
+		Projects []models.Project `json:"projects"`
+	}
+	query := "/api/projects?search=" + url.QueryEscape("網站設計")
+	s.do(http.MethodGet, query, "", nil).expect(http.StatusOK).decode(&all)
+	if len(all.Projects) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(all.Projects))
+	}
+
+	// Paging one at a time gives the same ranking
+	var paged []uint
+	path := query + "&limit=1"
+	for len(paged) < 4 {
+		var listed struct {
+			Projects   []models.Project `json:"projects"`
+			Pagination pageMeta         `json:"pagination"`
+		}
+		s.do(http.MethodGet, path, "", nil).expect(http.StatusOK).decode(&listed)
+		for _, project := range listed.Projects {
+			paged = append(paged, project.ID)
+		}
+		if !listed.Pagination.HasMore {
+			break
+		}
+		path = query + "&limit=1&cursor=" + listed.Pagination.NextCursor
+	}
+
+	for i, project := range all.Projects {
+		if i >= len(paged) || paged[i] != project.ID {
+			t.Fatalf("expected pages to follow the ranking %v, got %v", all.Projects, paged)
+		}
+	}
+	if len(paged) != 3 {
+		t.Errorf("expected 3 paged results, got %v", paged)
+	}
+}
@@ -0,0 +1,205 @@
+// Package server assembles the HTTP API: it builds the services on top of the
+// repositories and registers every route on a gin engine.
+package server
+
+import (
+	"freelance-platform/internal/appenv"
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/handlers"
+	"freelance-platform/internal/middleware"
+	"freelance-platform/internal/repository"
+	"freelance-platform/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Services holds the service layer shared by the API and background jobs
+type Services struct {
+	Projects      *services.ProjectService
+	Chats         *services.ChatService
+	Notifications *services.NotificationService
+	Webhooks      *services.WebhookService
+
+	// Events is the bus the services are subscribed to; hand it to the outbox relay
+	Events *events.Bus
+}
+
+// NewServices builds the services on top of db; publish may be nil to disable realtime events
+func NewServices(db *gorm.DB, publish services.Publisher) Services {
+	repos := repository.New(db)
+	svc := Services{
+		Projects:      services.NewProjectService(repos, handlers.ApplyProjectStatus),
+		Chats:         services.NewChatService(repos, publish),
+		Notifications: services.NewNotificationService(repos, publish),
+		Webhooks:      services.NewWebhookService(repos),
+		Events:        events.NewBus(),
+	}
+	svc.Notifications.Subscribe(svc.Events)
+	svc.Webhooks.Subscribe(svc.Events)
+	return svc
+}
+
+// Handlers are the handlers built with their dependencies by the caller; the
+// remaining endpoints are still package functions on database.DB
+type Handlers struct {
+	Projects      *handlers.ProjectHandler
+	Chats         *handlers.ChatHandler
+	Notifications *handlers.NotificationHandler
+	Webhooks      *handlers.WebhookHandler
+	Contracts     *handlers.ContractHandler
+	Payments      *handlers.PaymentHandler
+}
+
+// NewRouter returns a gin engine with middleware and all API routes registered
+func NewRouter(h Handlers) *gin.Engine {
+	// gin's default logger would print query strings, including websocket tokens
+	r := gin.New()
+
+	// Add middleware
+	r.Use(gin.Recovery())
+	r.Use(middleware.CORS())
+	r.Use(middleware.Logger())
+	r.Use(middleware.TrackEvents())
+
+	// Setup routes
+	r.GET("/ws/chat", middleware.RequireWebSocketAuth(), handlers.ChatWebSocket)
+
+	api := r.Group("/api")
+	{
+		auth := api.Group("/auth")
+		{
+			auth.POST("/register", handlers.Register)
+			auth.POST("/login", handlers.Login)
+			auth.POST("/login/2fa", handlers.LoginTwoFactor)
+			auth.POST("/refresh", handlers.RefreshSession)
+			auth.POST("/verify-email", handlers.VerifyEmail)
+			auth.POST("/verify-email/resend", middleware.RequireAuth(), handlers.ResendVerificationEmail)
+			auth.POST("/forgot-password", handlers.ForgotPassword)
+			auth.POST("/reset-password", handlers.ResetPassword)
+			auth.POST("/logout", middleware.RequireAuth(), handlers.Logout)
+			auth.POST("/logout-all", middleware.RequireAuth(), handlers.LogoutAll)
+			auth.GET("/me", middleware.RequireAuth(), handlers.GetCurrentUser)
+			auth.PUT("/profile", middleware.RequireAuth(), handlers.UpdateProfile)
+			auth.GET("/2fa", middleware.RequireAuth(), handlers.GetTwoFactorStatus)
+			auth.POST("/2fa/setup", middleware.RequireAuth(), handlers.SetupTwoFactor)
+			auth.POST("/2fa/confirm", middleware.RequireAuth(), handlers.ConfirmTwoFactor)
+			auth.POST("/2fa/disable", middleware.RequireAuth(), handlers.DisableTwoFactor)
+			auth.POST("/2fa/recovery-codes", middleware.RequireAuth(), handlers.RegenerateRecoveryCodes)
+		}
+
+		projects := api.Group("/projects")
+		{
+			projects.GET("", h.Projects.GetProjects)
+			projects.POST("", middleware.RequireAuth(), middleware.RequireVerifiedEmail(), h.Projects.CreateProject)
+			projects.GET("/:id", h.Projects.GetProject)
+			projects.PUT("/:id", middleware.RequireAuth(), h.Projects.UpdateProject)
+			projects.PUT("/:id/status", middleware.RequireAuth(), h.Projects.UpdateProjectStatus)
+			projects.DELETE("/:id", middleware.RequireAuth(), h.Projects.DeleteProject)
+			projects.GET("/:id/bids", middleware.RequireAuth(), h.Projects.GetProjectBids)
+			projects.POST("/:id/reviews", middleware.RequireAuth(), handlers.CreateReview)
+			projects.GET("/:id/reviews", middleware.RequireAuth(), handlers.GetProjectReviews)
+		}
+
+		users := api.Group("/users")
+		{
+			users.GET("/:id/reviews", handlers.GetUserReviews)
+		}
+
+		bids := api.Group("/bids")
+		{
+			bids.POST("", middleware.RequireAuth(), middleware.RequireVerifiedEmail(), h.Projects.CreateBid)
+			bids.PUT("/:id", middleware.RequireAuth(), h.Projects.UpdateBid)
+			bids.PUT("/:id/withdraw", middleware.RequireAuth(), h.Projects.WithdrawBid)
+			bids.GET("/:id/revisions", middleware.RequireAuth(), h.Projects.GetBidRevisions)
+			bids.PUT("/:id/accept", middleware.RequireAuth(), h.Projects.AcceptBid)
+			bids.PUT("/:id/reject", middleware.RequireAuth(), h.Projects.RejectBid)
+		}
+
+		contracts := api.Group("/contracts")
+		{
+			contracts.GET("", middleware.RequireAuth(), h.Contracts.GetContracts)
+			contracts.GET("/:id", middleware.RequireAuth(), h.Contracts.GetContract)
+			contracts.PUT("/:id/milestones", middleware.RequireAuth(), h.Contracts.UpdateMilestones)
+		}
+
+		milestones := api.Group("/milestones")
+		{
+			milestones.POST("/:id/submit", middleware.RequireAuth(), h.Contracts.SubmitMilestone)
+			milestones.POST("/:id/approve", middleware.RequireAuth(), h.Contracts.ApproveMilestone)
+			milestones.POST("/:id/request-changes", middleware.RequireAuth(), h.Contracts.RequestMilestoneChanges)
+			milestones.POST("/:id/fund", middleware.RequireAuth(), h.Payments.FundMilestone)
+		}
+
+		paymentRoutes := api.Group("/payments")
+		{
+			paymentRoutes.POST("/:id/confirm", middleware.RequireAuth(), h.Payments.ConfirmPayment)
+			paymentRoutes.POST("/webhook/stripe", h.Payments.PaymentWebhook("stripe"))
+			// The local fake gateway never takes webhooks in production
+			if !appenv.IsProduction() {
+				paymentRoutes.POST("/webhook/fake", h.Payments.PaymentWebhook("fake"))
+			}
+		}
+
+		api.GET("/wallet", middleware.RequireAuth(), h.Payments.GetWallet)
+		api.POST("/payouts", middleware.RequireAuth(), h.Payments.CreatePayout)
+
+		chats := api.Group("/chats")
+		{
+			chats.GET("", middleware.RequireAuth(), h.Chats.GetChats)
+			chats.POST("", middleware.RequireAuth(), h.Chats.CreateChat)
+			chats.GET("/:id/messages", middleware.RequireAuth(), h.Chats.GetChatMessages)
+			chats.PUT("/:id/read", middleware.RequireAuth(), h.Chats.MarkMessagesAsRead)
+			chats.POST("/:id/attachments", middleware.RequireAuth(), h.Chats.UploadAttachment)
+			chats.DELETE("/:id", middleware.RequireAuth(), h.Chats.DeleteChat)
+		}
+
+		messages := api.Group("/messages")
+		{
+			messages.POST("", middleware.RequireAuth(), h.Chats.SendMessage)
+			messages.GET("/unread-count", middleware.RequireAuth(), h.Chats.GetUnreadCount)
+		}
+
+		api.GET("/attachments/:id", middleware.RequireAuth(), h.Chats.DownloadAttachment)
+
+		notifications := api.Group("/notifications", middleware.RequireAuth())
+		{
+			notifications.GET("", h.Notifications.GetNotifications)
+			notifications.GET("/unread-count", h.Notifications.GetUnreadCount)
+			notifications.PUT("/read-all", h.Notifications.MarkAllAsRead)
+			notifications.GET("/preferences", h.Notifications.GetNotificationPreferences)
+			notifications.PUT("/preferences", h.Notifications.UpdateNotificationPreferences)
+			notifications.PUT("/:id/read", h.Notifications.MarkAsRead)
+		}
+
+		webhooks := api.Group("/webhooks", middleware.RequireAuth())
+		{
+			webhooks.GET("", h.Webhooks.GetWebhooks)
+			webhooks.POST("", h.Webhooks.CreateWebhook)
+			webhooks.PUT("/:id", h.Webhooks.UpdateWebhook)
+			webhooks.DELETE("/:id", h.Webhooks.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", h.Webhooks.GetWebhookDeliveries)
+			webhooks.GET("/:id/deliveries/:deliveryId", h.Webhooks.GetWebhookDelivery)
+			webhooks.POST("/:id/deliveries/:deliveryId/redeliver", h.Webhooks.RedeliverWebhook)
+		}
+
+		admin := api.Group("/admin", middleware.RequireAuth(), middleware.RequireRole("admin"))
+		{
+			admin.GET("/users", handlers.AdminListUsers)
+			admin.GET("/users/:id", handlers.AdminGetUser)
+			admin.PUT("/users/:id/suspend", handlers.AdminSuspendUser)
+			admin.PUT("/users/:id/ban", handlers.AdminBanUser)
+			admin.PUT("/users/:id/reinstate", handlers.AdminReinstateUser)
+			admin.PUT("/users/:id/role", handlers.AdminChangeUserRole)
+			admin.PUT("/projects/:id/close", handlers.AdminCloseProject)
+			admin.PUT("/projects/:id/restore", handlers.AdminRestoreProject)
+			admin.PUT("/messages/:id/hide", handlers.AdminHideMessage)
+			admin.PUT("/messages/:id/unhide", handlers.AdminUnhideMessage)
+			admin.GET("/chats/:id", handlers.AdminGetChat)
+			admin.GET("/actions", handlers.AdminListActions)
+			admin.GET("/audit-logs", handlers.AdminListAuditLogs)
+		}
+	}
+
+	return r
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/payments"
+	"freelance-platform/internal/webhooks"
+)
+
+// webhookReceiver is an integrator's endpoint answering with status
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(t *testing.T, status int) *webhookReceiver {
+	// The receiver listens on loopback
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "true")
+
+	receiver := &webhookReceiver{status: status}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+type createdWebhook struct {
+	Webhook models.Webhook `json:"webhook"`
+	Secret  string         `json:"secret"`
+}
+
+type deliveryList struct {
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+}
+
+type deliveryResponse struct {
+	Delivery models.WebhookDelivery `json:"delivery"`
+}
+
+func (s *testServer) createWebhook(user models.User, url string, events ...string) createdWebhook {
+	s.t.Helper()
+
+	var created createdWebhook
+	s.as(user, http.MethodPost, "/api/webhooks", map[string]interface{}{"url": url, "events": events}).
+		expect(http.StatusCreated).decode(&created)
+	return created
+}
+
+func (s *testServer) deliverWebhooks(now time.Time) {
+	s.t.Helper()
+
+	if _, err := s.svc.Webhooks.DeliverDue(context.Background(), now); err != nil {
+		s.t.Fatalf("deliver webhooks: %v", err)
+	}
+}
+
+func (s *testServer) webhookDeliveries(user models.User, webhookID uint) []models.WebhookDelivery {
+	s.t.Helper()
+
+	var list deliveryList
+	s.as(user, http.MethodGet, fmt.Sprintf("/api/webhooks/%d/deliveries", webhookID), nil).expect(http.StatusOK).decode(&list)
+	return list.Deliveries
+}
+
+func TestWebhookManagement(t *testing.T) {
+	s := newTestServer(t)
+	client := s.createUser("client")
+	other := s.createUser("client")
+
+	s.as(client, http.MethodPost, "/api/webhooks", map[string]interface{}{"url": "ftp://example.com", "events": []string{"bid.created"}}).
+		expect(http.StatusBadRequest)
+	s.as(client, http.MethodPost, "/api/webhooks", map[string]interface{}{"url": "https://example.com/hook", "events": []string{"bid.withdrawn"}}).
+		expect(http.StatusBadRequest)
+	s.as(client, http.MethodPost, "/api/webhooks", map[string]interface{}{"url": "https://example.com/hook", "events": []string{}}).
+		expect(http.StatusBadRequest)
+
+	created := s.createWebhook(client, "https://example.com/hook", "bid.created", "bid.created")
+	if len(created.Secret) < 20 || created.Webhook.Secret != "" {
+		t.Fatalf("expected the secret beside the webhook, not in it, got %+v", created)
+	}
+	if len(created.Webhook.Events) != 1 || !created.Webhook.Active {
+		t.Errorf("expected one active subscription, got %+v", created.Webhook)
+	}
+
+	path := fmt.Sprintf("/api/webhooks/%d", created.Webhook.ID)
+	s.as(other, http.MethodPut, path, map[string]interface{}{"active": false}).expect(http.StatusNotFound)
+	s.as(other, http.MethodDelete, path, nil).expect(http.StatusNotFound)
+	s.as(other, http.MethodGet, path+"/deliveries", nil).expect(http.StatusNotFound)
+
+	var updated struct {
+		Webhook models.Webhook `json:"webhook"`
+	}
+	s.as(client, http.MethodPut, path, map[string]interface{}{"events": []string{"bid.accepted", "project.status_changed"}, "active": false}).
+		expect(http.StatusOK).decode(&updated)
+	if len(updated.Webhook.Events) != 2 || updated.Webhook.Active || updated.Webhook.URL != "https://example.com/hook" {
+		t.Errorf("expected new events, inactive and the same URL, got %+v", updated.Webhook)
+	}
+
+	var list struct {
+		Webhooks []models.Webhook `json:"webhooks"`
+	}
+	s.as(client, http.MethodGet, "/api/webhooks", nil).expect(http.StatusOK).decode(&list)
+	if len(list.Webhooks) != 1 {
+		t.Fatalf("expected the client's webhook, got %+v", list.Webhooks)
+	}
+
+	s.as(client, http.MethodDelete, path, nil).expect(http.StatusOK)
+	s.as(client, http.MethodGet, path+"/deliveries", nil).expect(http.StatusNotFound)
+}
+
+func TestWebhookSignedDelivery(t *testing.T) {
+	s := newTestServer(t)
+	receiver := newWebhookReceiver(t, http.StatusNoContent)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	created := s.createWebhook(client, receiver.URL, "bid.created")
+	// Subscribed to something else; gets nothing
+	s.createWebhook(client, receiver.URL, "project.status_changed")
+
+	s.as(freelancer, http.MethodPost, "/api/bids", bidRequest(project.ID, 20000)).expect(http.StatusCreated)
+	now := time.Now()
+	s.deliverWebhooks(now)
+
+	if len(receiver.requests) != 1 {
+		t.Fatalf("expected one delivery, got %d", len(receiver.requests))
+	}
+	req, body := receiver.requests[0], receiver.bodies[0]
+	if err := payments.VerifySignature(created.Secret, body, req.Header.Get(webhooks.HeaderSignature), now); err != nil {
+		t.Errorf("expected a valid signature: %v", err)
+	}
+	if req.Header.Get(webhooks.HeaderEvent) != "bid.created" {
+		t.Errorf("expected the event header, got %q", req.Header.Get(webhooks.HeaderEvent))
+	}
+
+	var payload struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			ProjectID    uint `json:"project_id"`
+			FreelancerID uint `json:"freelancer_id"`
+			Amount       int  `json:"amount"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID == "" || payload.ID != req.Header.Get(webhooks.HeaderID) || payload.Type != "bid.created" {
+		t.Errorf("expected the event ID and type in body and headers, got %s", body)
+	}
+	if payload.Data.ProjectID != project.ID || payload.Data.FreelancerID != freelancer.ID || payload.Data.Amount != 20000 {
+		t.Errorf("expected the bid in the payload, got %s", body)
+	}
+
+	deliveries := s.webhookDeliveries(client, created.Webhook.ID)
+	if len(deliveries) != 1 || deliveries[0].Status != "succeeded" || deliveries[0].Attempts != 1 {
+		t.Fatalf("expected one successful delivery, got %+v", deliveries)
+	}
+
+	// Nothing left to send
+	s.deliverWebhooks(now.Add(time.Hour))
+	if len(receiver.requests) != 1 {
+		t.Errorf("expected no more deliveries, got %d", len(receiver.requests))
+	}
+}
+
+func TestWebhookRetriesThenRedeliver(t *testing.T) {
+	s := newTestServer(t)
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	client := s.createUser("client")
+	freelancer := s.createUser("freelancer")
+	project := s.createProject(client)
+	bid := s.createBid(freelancer, project, 20000)
+	created := s.createWebhook(client, receiver.URL, "bid.accepted")
+
+	s.as(client, http.MethodPut, fmt.Sprintf("/api/bids/%d/accept", bid.ID), nil).expect(http.StatusOK)
+
+	now := time.Now()
+	s.deliverWebhooks(now)
+	deliveries := s.webhookDeliveries(client, created.Webhook.ID)
+	if len(deliveries) != 1 || deliveries[0].Status != "pending" || deliveries[0].Attempts != 1 {
+		t.Fatalf("expected a pending delivery after one failure, got %+v", deliveries)
+	}
+	if !deliveries[0].NextAttemptAt.After(now.Add(webhooks.Backoff(1) - time.Second)) {
+		t.Errorf("expected the retry to back off, next attempt at %v", deliveries[0].NextAttemptAt)
+	}
+
+	// Retrying before the backoff has passed sends nothing
+	s.deliverWebhooks(time.Now().Add(time.Second))
+	if len(receiver.requests) != 1 {
+		t.Fatalf("expected no early retry, got %d requests", len(receiver.requests))
+	}
+
+	// Each retry is scheduled from when the previous attempt went out
+	for attempt := 1; attempt < webhooks.MaxAttempts; attempt++ {
+		s.deliverWebhooks(time.Now().Add(webhooks.Backoff(attempt)))
+	}
+	if len(receiver.requests) != webhooks.MaxAttempts {
+		t.Fatalf("expected %d attempts, got %d", webhooks.MaxAttempts, len(receiver.requests))
+	}
+
+	deliveryPath := fmt.Sprintf("/api/webhooks/%d/deliveries/%d", created.Webhook.ID, deliveries[0].ID)
+	var dead deliveryResponse
+	s.as(client, http.MethodGet, deliveryPath, nil).expect(http.StatusOK).decode(&dead)
+	if dead.Delivery.Status != "dead" || len(dead.Delivery.AttemptLog) != webhooks.MaxAttempts {
+		t.Fatalf("expected a dead delivery with every attempt logged, got %+v", dead.Delivery)
+	}
+	if code := dead.Delivery.AttemptLog[0].StatusCode; code == nil || *code != http.StatusInternalServerError {
+		t.Errorf("expected the attempt to log the response status, got %+v", dead.Delivery.AttemptLog[0])
+	}
+	if deliveries := s.webhookDeliveries(client, created.Webhook.ID); len(deliveries) != 1 || deliveries[0].Status != "dead" {
+		t.Fatalf("expected the delivery to stay dead, got %+v", deliveries)
+	}
+
+	other := s.createUser("client")
+	s.as(other, http.MethodPost, deliveryPath+"/redeliver", nil).expect(http.StatusNotFound)
+
+	receiver.setStatus(http.StatusOK)
+	var redelivered deliveryResponse
+	s.as(client, http.MethodPost, deliveryPath+"/redeliver", nil).expect(http.StatusAccepted).decode(&redelivered)
+	if redelivered.Delivery.EventID != dead.Delivery.EventID || redelivered.Delivery.Status != "pending" {
+		t.Fatalf("expected a new pending delivery of the same event, got %+v", redelivered.Delivery)
+	}
+
+	s.deliverWebhooks(time.Now())
+	if got := receiver.requests[len(receiver.requests)-1].Header.Get(webhooks.HeaderID); got != dead.Delivery.EventID {
+		t.Errorf("expected the redelivery to keep the event ID, got %q", got)
+	}
+	var succeeded deliveryList
+	s.as(client, http.MethodGet, fmt.Sprintf("/api/webhooks/%d/deliveries?status=succeeded", created.Webhook.ID), nil).
+		expect(http.StatusOK).decode(&succeeded)
+	if len(succeeded.Deliveries) != 1 || succeeded.Deliveries[0].ID != redelivered.Delivery.ID {
+		t.Errorf("expected the redelivery to succeed, got %+v", succeeded.Deliveries)
+	}
+	s.as(client, http.MethodGet, fmt.Sprintf("/api/webhooks/%d/deliveries?status=lost", created.Webhook.ID), nil).
+		expect(http.StatusBadRequest)
+}
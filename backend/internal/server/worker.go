@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"freelance-platform/internal/handlers"
+	"freelance-platform/internal/jobs"
+	"freelance-platform/internal/outbox"
+
+	"gorm.io/gorm"
+)
+
+// The periodic jobs sweep whatever became due since their last run. Each
+// run is cheap to repeat, so a failed one just waits for the next interval.
+var (
+	expireBidsJob         = jobs.Definition[struct{}]{Name: "bids.expire", MaxAttempts: 1}
+	publishReviewsJob     = jobs.Definition[struct{}]{Name: "reviews.publish", MaxAttempts: 1}
+	notificationEmailsJob = jobs.Definition[struct{}]{Name: "notifications.email", MaxAttempts: 1}
+	webhookDeliveriesJob  = jobs.Definition[struct{}]{Name: "webhooks.deliver", MaxAttempts: 1}
+	relayOutboxJob        = jobs.Definition[struct{}]{Name: "outbox.relay", MaxAttempts: 1}
+	pruneJobsJob          = jobs.Definition[struct{}]{Name: "jobs.prune", MaxAttempts: 1}
+	pruneOutboxJob        = jobs.Definition[struct{}]{Name: "outbox.prune", MaxAttempts: 1}
+)
+
+// NewWorker returns a job worker for the background work of svc: account
+// emails, refunds and payouts, bid expiry, review publishing, notification emails and digests,
+// webhook deliveries, relaying the events left in the outbox, and pruning
+// finished jobs and published events
+func NewWorker(db *gorm.DB, svc Services, relay *outbox.Relay) *jobs.Worker {
+	w := jobs.NewWorker(db)
+	payments := handlers.NewPaymentHandler(db)
+
+	jobs.Handle(w, handlers.VerificationEmailJob, handlers.SendVerificationEmail)
+	jobs.Handle(w, handlers.PasswordResetEmailJob, handlers.SendPasswordResetEmail)
+	jobs.Handle(w, handlers.RefundPaymentJob, payments.RefundPayment)
+	jobs.Handle(w, handlers.SendPayoutJob, payments.SendPayout)
+
+	jobs.Handle(w, expireBidsJob, func(ctx context.Context, _ struct{}) error {
+		count, err := svc.Projects.ExpireStaleBids(ctx)
+		if count > 0 {
+			log.Printf("Expired %d bids", count)
+		}
+		return err
+	})
+	jobs.Every(w, expireBidsJob, time.Minute, struct{}{})
+
+	// Publishing reviews also recomputes the ratings of everyone they affect
+	jobs.Handle(w, publishReviewsJob, func(ctx context.Context, _ struct{}) error {
+		count, err := handlers.PublishDueReviews()
+		if count > 0 {
+			log.Printf("Published %d reviews", count)
+		}
+		return err
+	})
+	jobs.Every(w, publishReviewsJob, 10*time.Minute, struct{}{})
+
+	jobs.Handle(w, notificationEmailsJob, func(ctx context.Context, _ struct{}) error {
+		count, err := svc.Notifications.SendEmails(ctx, time.Now())
+		if count > 0 {
+			log.Printf("Sent %d notification emails", count)
+		}
+		return err
+	})
+	jobs.Every(w, notificationEmailsJob, time.Minute, struct{}{})
+
+	jobs.Handle(w, webhookDeliveriesJob, func(ctx context.Context, _ struct{}) error {
+		count, err := svc.Webhooks.DeliverDue(ctx, time.Now())
+		if count > 0 {
+			log.Printf("Attempted %d webhook deliveries", count)
+		}
+		return err
+	})
+	jobs.Every(w, webhookDeliveriesJob, 5*time.Second, struct{}{})
+
+	// The API publishes its events as it commits them; this catches the ones
+	// it didn't get to, say because it crashed or the broker was down
+	jobs.Handle(w, relayOutboxJob, func(ctx context.Context, _ struct{}) error {
+		count, err := relay.Flush(ctx, time.Now())
+		if count > 0 {
+			log.Printf("Relayed %d outbox events", count)
+		}
+		return err
+	})
+	jobs.Every(w, relayOutboxJob, 5*time.Second, struct{}{})
+
+	jobs.Handle(w, pruneJobsJob, func(ctx context.Context, _ struct{}) error {
+		count, err := jobs.Prune(ctx, db, time.Now().Add(-jobRetention()))
+		if count > 0 {
+			log.Printf("Pruned %d finished jobs", count)
+		}
+		return err
+	})
+	jobs.Every(w, pruneJobsJob, time.Hour, struct{}{})
+
+	jobs.Handle(w, pruneOutboxJob, func(ctx context.Context, _ struct{}) error {
+		count, err := outbox.Prune(ctx, db, time.Now().Add(-jobRetention()))
+		if count > 0 {
+			log.Printf("Pruned %d published outbox events", count)
+		}
+		return err
+	})
+	jobs.Every(w, pruneOutboxJob, time.Hour, struct{}{})
+
+	return w
+}
+
+// jobRetention is how long finished jobs, published outbox events and the
+// consumers' processed marks are kept, configured via JOB_RETENTION_DAYS (7 by
+// default)
+func jobRetention() time.Duration {
+	days := 7
+	if value := os.Getenv("JOB_RETENTION_DAYS"); value != "" {
+		if d, err := strconv.Atoi(value); err == nil && d > 0 {
+			days = d
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
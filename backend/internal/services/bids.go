@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/internal/repository"
+)
+
+var (
+	ErrBidNotFound   = errors.New("bid not found")
+	ErrBidNotOwned   = errors.New("bid not owned by user")
+	ErrBidNotPending = errors.New("bid is not pending")
+	ErrBidExpired    = errors.New("bid has expired")
+)
+
+// BidRevisionInput is the new version of a freelancer's bid
+type BidRevisionInput struct {
+	Amount   int
+	Proposal string
+	Timeline string
+}
+
+// AcceptBid accepts a pending bid on one of the client's projects, rejects the
+// competing ones, assigns the freelancer and opens a contract, all in one
+// transaction with the BidAccepted event
+func (s *ProjectService) AcceptBid(ctx context.Context, client models.User, bidID uint) (models.Bid, error) {
+	var bid models.Bid
+	err := s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		var project models.Project
+		var err error
+		bid, project, err = lockBidForOwner(ctx, tx, bidID, client.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bids.UpdateStatus(ctx, &bid, "accepted"); err != nil {
+			return err
+		}
+
+		rejectedFreelancerIDs, err := tx.Bids.RejectCompeting(ctx, bid)
+		if err != nil {
+			return err
+		}
+
+		// Assign the freelancer and start the project
+		project.FreelancerID = &bid.FreelancerID
+		project.Status = "in_progress"
+		if err := tx.Projects.Save(ctx, &project); err != nil {
+			return err
+		}
+
+		// Open a contract with a single milestone covering the whole bid; the
+		// client can split it into smaller milestones before work is submitted
+		contract := models.Contract{
+			ProjectID:    project.ID,
+			BidID:        bid.ID,
+			ClientID:     project.ClientID,
+			FreelancerID: bid.FreelancerID,
+			Amount:       bid.Amount,
+			Status:       "active",
+			Milestones: []models.Milestone{{
+				Position: 1,
+				Title:    project.Title,
+				Amount:   bid.Amount,
+				DueDate:  project.Deadline,
+				Status:   "pending",
+			}},
+		}
+		if err := tx.DB().WithContext(ctx).Create(&contract).Error; err != nil {
+			return err
+		}
+
+		// Let everyone who talked about this project know the outcome
+		chats, err := tx.Chats.ListByProject(ctx, project.ID)
+		if err != nil {
+			return err
+		}
+		for i := range chats {
+			content := "此案件已選定其他接案者，感謝您的提案。"
+			if chats[i].FreelancerID == bid.FreelancerID {
+				content = "您的提案已被發案者接受，案件正式開始進行。"
+			}
+			if err := postSystemMessage(ctx, tx, &chats[i], project.ClientID, content); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Bids.LoadDetails(ctx, &bid); err != nil {
+			return err
+		}
+		accepted := BidEventData(bid)
+		accepted.RejectedFreelancerIDs = rejectedFreelancerIDs
+		return outbox.Record(ctx, tx.DB(), events.Event{Type: events.BidAccepted, ActorID: client.ID, Data: accepted})
+	})
+	if err != nil {
+		return bid, err
+	}
+
+	outbox.Flush(ctx)
+	s.repos.Projects.LoadParties(ctx, &bid.Project)
+	return bid, nil
+}
+
+// RejectBid rejects a single pending bid on one of the client's projects
+func (s *ProjectService) RejectBid(ctx context.Context, client models.User, bidID uint) (models.Bid, error) {
+	var bid models.Bid
+	err := s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		var project models.Project
+		var err error
+		bid, project, err = lockBidForOwner(ctx, tx, bidID, client.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bids.UpdateStatus(ctx, &bid, "rejected"); err != nil {
+			return err
+		}
+
+		// Notify the freelancer if they already have a chat for this project
+		return notifyBidChat(ctx, tx, project, bid.FreelancerID, project.ClientID, "您的提案未被發案者採納。")
+	})
+	if err != nil {
+		return bid, err
+	}
+
+	s.repos.Bids.LoadDetails(ctx, &bid)
+	return bid, nil
+}
+
+// UpdateBid lets a freelancer revise a pending bid, keeping the previous version as a revision
+func (s *ProjectService) UpdateBid(ctx context.Context, freelancer models.User, bidID uint, input BidRevisionInput) (models.Bid, error) {
+	var bid models.Bid
+	err := s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		var project models.Project
+		var err error
+		bid, project, err = lockBidForFreelancer(ctx, tx, bidID, freelancer.ID)
+		if err != nil {
+			return err
+		}
+
+		if input.Amount < project.BudgetMin || input.Amount > project.BudgetMax {
+			return ErrBidOutOfBudget
+		}
+
+		return tx.Bids.Revise(ctx, &bid, input.Amount, input.Proposal, input.Timeline)
+	})
+	if err != nil {
+		return bid, err
+	}
+
+	s.repos.Bids.LoadDetails(ctx, &bid)
+	bid.Revisions, _ = s.repos.Bids.ListRevisions(ctx, bid.ID)
+	return bid, nil
+}
+
+// WithdrawBid lets a freelancer pull back one of their pending bids
+func (s *ProjectService) WithdrawBid(ctx context.Context, freelancer models.User, bidID uint) (models.Bid, error) {
+	var bid models.Bid
+	err := s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		var project models.Project
+		var err error
+		bid, project, err = lockBidForFreelancer(ctx, tx, bidID, freelancer.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bids.UpdateStatus(ctx, &bid, "withdrawn"); err != nil {
+			return err
+		}
+
+		// Tell the client if they already have a chat with this freelancer
+		return notifyBidChat(ctx, tx, project, bid.FreelancerID, freelancer.ID, "接案者已撤回此案件的提案。")
+	})
+	return bid, err
+}
+
+// BidRevisions returns the edit history of a bid to its freelancer and the project owner
+func (s *ProjectService) BidRevisions(ctx context.Context, user models.User, bidID uint) ([]models.BidRevision, error) {
+	bid := models.Bid{ID: bidID}
+	if err := s.repos.Bids.LoadDetails(ctx, &bid); err != nil {
+		return nil, notFound(err, ErrBidNotFound)
+	}
+
+	if bid.FreelancerID != user.ID && bid.Project.ClientID != user.ID {
+		return nil, ErrBidNotOwned
+	}
+
+	return s.repos.Bids.ListRevisions(ctx, bid.ID)
+}
+
+// lockBidForOwner loads a pending bid and its open project with row locks, checking project ownership
+func lockBidForOwner(ctx context.Context, tx *repository.Repositories, bidID, ownerID uint) (models.Bid, models.Project, error) {
+	bid, err := tx.Bids.FindForUpdate(ctx, bidID)
+	if err != nil {
+		return bid, models.Project{}, notFound(err, ErrBidNotFound)
+	}
+
+	project, err := tx.Projects.FindForUpdate(ctx, bid.ProjectID)
+	if err != nil {
+		return bid, project, notFound(err, ErrBidNotFound)
+	}
+
+	if project.ClientID != ownerID {
+		return bid, project, ErrBidNotOwned
+	}
+
+	if project.Status != "open" {
+		return bid, project, ErrProjectNotBidding
+	}
+
+	if bid.Status != "pending" {
+		return bid, project, ErrBidNotPending
+	}
+
+	if bidExpired(bid, time.Now()) {
+		return bid, project, ErrBidExpired
+	}
+
+	return bid, project, nil
+}
+
+// lockBidForFreelancer loads a pending bid owned by the freelancer together with its open project
+func lockBidForFreelancer(ctx context.Context, tx *repository.Repositories, bidID, freelancerID uint) (models.Bid, models.Project, error) {
+	bid, err := tx.Bids.FindForUpdate(ctx, bidID)
+	if err != nil {
+		return bid, models.Project{}, notFound(err, ErrBidNotFound)
+	}
+
+	if bid.FreelancerID != freelancerID {
+		return bid, models.Project{}, ErrBidNotOwned
+	}
+
+	if bid.Status != "pending" {
+		return bid, models.Project{}, ErrBidNotPending
+	}
+
+	if bidExpired(bid, time.Now()) {
+		return bid, models.Project{}, ErrBidExpired
+	}
+
+	project, err := tx.Projects.FindByID(ctx, bid.ProjectID)
+	if err != nil {
+		return bid, project, notFound(err, ErrBidNotFound)
+	}
+
+	if project.Status != "open" {
+		return bid, project, ErrProjectNotBidding
+	}
+
+	return bid, project, nil
+}
+
+// bidExpired reports whether a bid is past its expiry at now, even if the worker hasn't marked it expired yet
+func bidExpired(bid models.Bid, now time.Time) bool {
+	return bid.ExpiresAt != nil && !bid.ExpiresAt.After(now)
+}
+
+// notifyBidChat posts a system message into the chat between the project's
+// client and the freelancer, if they have one
+func notifyBidChat(ctx context.Context, tx *repository.Repositories, project models.Project, freelancerID, senderID uint, content string) error {
+	chat, err := tx.Chats.FindBetween(ctx, project.ID, project.ClientID, freelancerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return postSystemMessage(ctx, tx, &chat, senderID, content)
+}
+
+// postSystemMessage posts a system message into a chat and bumps its updated_at
+func postSystemMessage(ctx context.Context, tx *repository.Repositories, chat *models.Chat, senderID uint, content string) error {
+	message := models.Message{
+		ChatID:   chat.ID,
+		SenderID: senderID,
+		Content:  content,
+		Type:     "system",
+	}
+	if err := tx.Messages.Create(ctx, &message); err != nil {
+		return err
+	}
+
+	return tx.Chats.Touch(ctx, chat, message.CreatedAt)
+}
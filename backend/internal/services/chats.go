@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/internal/pagination"
+	"freelance-platform/internal/realtime"
+	"freelance-platform/internal/repository"
+)
+
+var (
+	ErrChatNotFound       = errors.New("chat not found")
+	ErrNotChatParticipant = errors.New("user is not part of the chat")
+	ErrNotClient          = errors.New("only clients can create chats")
+	ErrFreelancerNotFound = errors.New("freelancer not found")
+	ErrNotAFreelancer     = errors.New("user is not a freelancer")
+)
+
+// Publisher pushes a realtime event to the given users' open connections
+type Publisher func(userIDs []uint, eventType string, data interface{})
+
+// ChatSummary is a chat as listed for one participant
+type ChatSummary struct {
+	models.Chat
+	UnreadCount int64 `json:"unread_count"`
+}
+
+type ChatService struct {
+	repos   *repository.Repositories
+	publish Publisher
+}
+
+// NewChatService returns a chat service; a nil publisher disables realtime events
+func NewChatService(repos *repository.Repositories, publish Publisher) *ChatService {
+	if publish == nil {
+		publish = func([]uint, string, interface{}) {}
+	}
+	return &ChatService{repos: repos, publish: publish}
+}
+
+// List returns a page of the user's visible chats with their unread message counts
+func (s *ChatService) List(ctx context.Context, user models.User, page pagination.Page) ([]ChatSummary, pagination.Meta, error) {
+	chats, meta, err := s.repos.Chats.ListVisible(ctx, user, page)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	summaries := make([]ChatSummary, 0, len(chats))
+	for _, chat := range chats {
+		unreadCount, _ := s.repos.Messages.CountUnread(ctx, []uint{chat.ID}, user.ID)
+		summaries = append(summaries, ChatSummary{Chat: chat, UnreadCount: unreadCount})
+	}
+	return summaries, meta, nil
+}
+
+// Create opens a chat between a client and a freelancer about one of the
+// client's projects; an existing chat is returned with created set to false
+func (s *ChatService) Create(ctx context.Context, client models.User, projectID, freelancerID uint) (models.Chat, bool, error) {
+	if client.Role != "client" {
+		return models.Chat{}, false, ErrNotClient
+	}
+
+	project, err := s.repos.Projects.FindByID(ctx, projectID)
+	if err != nil {
+		return models.Chat{}, false, notFound(err, ErrProjectNotFound)
+	}
+	if project.ClientID != client.ID {
+		return models.Chat{}, false, ErrNotProjectOwner
+	}
+
+	freelancer, err := s.repos.Users.FindByID(ctx, freelancerID)
+	if err != nil {
+		return models.Chat{}, false, notFound(err, ErrFreelancerNotFound)
+	}
+	if freelancer.Role != "freelancer" {
+		return models.Chat{}, false, ErrNotAFreelancer
+	}
+
+	if chat, err := s.repos.Chats.FindBetween(ctx, projectID, client.ID, freelancerID); err == nil {
+		s.repos.Chats.LoadParties(ctx, &chat)
+		return chat, false, nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return models.Chat{}, false, err
+	}
+
+	chat := models.Chat{
+		ProjectID:    projectID,
+		ClientID:     client.ID,
+		FreelancerID: freelancerID,
+	}
+	if err := s.repos.Chats.Create(ctx, &chat); err != nil {
+		return chat, false, err
+	}
+
+	s.repos.Chats.LoadParties(ctx, &chat)
+	return chat, true, nil
+}
+
+// Open loads a chat the user takes part in
+func (s *ChatService) Open(ctx context.Context, user models.User, chatID uint) (models.Chat, error) {
+	chat, err := s.repos.Chats.FindByID(ctx, chatID)
+	if err != nil {
+		return chat, notFound(err, ErrChatNotFound)
+	}
+
+	if chat.ClientID != user.ID && chat.FreelancerID != user.ID {
+		return chat, ErrNotChatParticipant
+	}
+
+	return chat, nil
+}
+
+// Messages returns a page of a chat's messages, going back from the newest,
+// with those hidden by an admin redacted
+func (s *ChatService) Messages(ctx context.Context, user models.User, chatID uint, page pagination.Page) ([]models.Message, pagination.Meta, error) {
+	chat, err := s.Open(ctx, user, chatID)
+	if err != nil {
+		return nil, pagination.Meta{}, err
+	}
+
+	// A chat the user deleted stays gone until someone writes into it again
+	if (user.Role == "client" && chat.ClientHidden) ||
+		(user.Role == "freelancer" && chat.FreelancerHidden) {
+		return nil, pagination.Meta{}, ErrChatNotFound
+	}
+
+	messages, meta, err := s.repos.Messages.ListByChat(ctx, chat.ID, page)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	redactHiddenMessages(messages)
+	return messages, meta, nil
+}
+
+// Send posts a message from the user into a chat they take part in
+func (s *ChatService) Send(ctx context.Context, sender models.User, chatID uint, content, messageType string) (models.Message, error) {
+	chat, err := s.Open(ctx, sender, chatID)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	message := models.Message{
+		ChatID:   chat.ID,
+		SenderID: sender.ID,
+		Content:  content,
+		Type:     messageType,
+	}
+	if message.Type == "" {
+		message.Type = "text"
+	}
+
+	err = s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		if err := tx.Messages.Create(ctx, &message); err != nil {
+			return err
+		}
+		return s.Record(ctx, tx, &chat, sender, &message)
+	})
+	if err != nil {
+		return message, err
+	}
+
+	s.Deliver(ctx, &chat, &message)
+	return message, nil
+}
+
+// Record makes the changes that come with a new message through tx, which
+// should be the transaction that stored it: the chat reappears for a sender
+// who had hidden it, moves to the top of both lists, and MessageReceived is
+// recorded in the outbox
+func (s *ChatService) Record(ctx context.Context, tx *repository.Repositories, chat *models.Chat, sender models.User, message *models.Message) error {
+	if err := unhideForSender(ctx, tx, chat, sender); err != nil {
+		return err
+	}
+
+	if err := tx.Chats.Touch(ctx, chat, message.CreatedAt); err != nil {
+		return err
+	}
+
+	return outbox.Record(ctx, tx.DB(), events.Event{
+		Type:    events.MessageReceived,
+		ActorID: sender.ID,
+		Data: events.MessageData{
+			MessageID:   message.ID,
+			ChatID:      chat.ID,
+			ProjectID:   chat.ProjectID,
+			SenderID:    sender.ID,
+			SenderName:  sender.Name,
+			RecipientID: OtherParticipantID(*chat, sender.ID),
+			Type:        message.Type,
+			Preview:     message.Content,
+		},
+	})
+}
+
+// Deliver finishes sending a message once Record's transaction committed: the
+// message is pushed to both participants and its event is published
+func (s *ChatService) Deliver(ctx context.Context, chat *models.Chat, message *models.Message) {
+	s.repos.Messages.LoadSender(ctx, message)
+
+	s.publish(ParticipantIDs(*chat), realtime.EventMessageCreated, map[string]interface{}{"message": message})
+	if recipient, err := s.repos.Users.FindByID(ctx, OtherParticipantID(*chat, message.SenderID)); err == nil {
+		s.PublishUnreadCount(ctx, recipient)
+	}
+
+	outbox.Flush(ctx)
+}
+
+// MarkRead marks everything the other participant sent in the chat as read
+func (s *ChatService) MarkRead(ctx context.Context, reader models.User, chatID uint) error {
+	chat, err := s.Open(ctx, reader, chatID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.repos.Messages.MarkRead(ctx, chat.ID, reader.ID, now); err != nil {
+		return err
+	}
+	// The chat's message notification is settled too, so the digest doesn't repeat what was just read
+	cleared, err := s.repos.Notifications.MarkChatRead(ctx, reader.ID, events.MessageReceived, chat.ID, now)
+	if err != nil {
+		return err
+	}
+
+	// Send the read receipt to both participants and refresh the reader's badge
+	s.publish(ParticipantIDs(chat), realtime.EventMessagesRead, map[string]interface{}{
+		"chat_id":   chat.ID,
+		"reader_id": reader.ID,
+		"read_at":   now,
+	})
+	s.PublishUnreadCount(ctx, reader)
+	if cleared > 0 {
+		if count, err := s.repos.Notifications.CountUnread(ctx, reader.ID); err == nil {
+			s.publish([]uint{reader.ID}, realtime.EventNotificationCount, map[string]interface{}{"unread_count": count})
+		}
+	}
+	return nil
+}
+
+// UnreadCount counts unread messages across all chats the user can see
+func (s *ChatService) UnreadCount(ctx context.Context, user models.User) (int64, error) {
+	chatIDs, err := s.repos.Chats.VisibleIDs(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+	return s.repos.Messages.CountUnread(ctx, chatIDs, user.ID)
+}
+
+// PublishUnreadCount pushes the user's current unread badge count to their open connections
+func (s *ChatService) PublishUnreadCount(ctx context.Context, user models.User) {
+	count, err := s.UnreadCount(ctx, user)
+	if err != nil {
+		return
+	}
+	s.publish([]uint{user.ID}, realtime.EventUnreadCount, map[string]interface{}{"unread_count": count})
+}
+
+// Hide removes a chat from the user's list; once both sides have hidden it
+// the chat and its messages are deleted
+func (s *ChatService) Hide(ctx context.Context, user models.User, chatID uint) error {
+	chat, err := s.Open(ctx, user, chatID)
+	if err != nil {
+		return err
+	}
+
+	if user.Role == "client" {
+		chat.ClientHidden = true
+	} else {
+		chat.FreelancerHidden = true
+	}
+
+	return s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		if err := tx.Chats.Save(ctx, &chat); err != nil {
+			return err
+		}
+		if chat.ClientHidden && chat.FreelancerHidden {
+			return tx.Chats.Delete(ctx, &chat)
+		}
+		return nil
+	})
+}
+
+// unhideForSender makes a chat visible again for a participant who writes into it
+func unhideForSender(ctx context.Context, repos *repository.Repositories, chat *models.Chat, sender models.User) error {
+	switch {
+	case sender.Role == "client" && chat.ClientHidden:
+		chat.ClientHidden = false
+	case sender.Role == "freelancer" && chat.FreelancerHidden:
+		chat.FreelancerHidden = false
+	default:
+		return nil
+	}
+	return repos.Chats.Save(ctx, chat)
+}
+
+// redactHiddenMessages replaces the content of messages hidden by an admin with a placeholder
+func redactHiddenMessages(messages []models.Message) {
+	for i := range messages {
+		if messages[i].HiddenAt != nil {
+			messages[i].Content = "此訊息已被管理員隱藏。"
+			messages[i].FileURL = ""
+		}
+	}
+}
+
+// ParticipantIDs returns the user IDs of both sides of a chat
+func ParticipantIDs(chat models.Chat) []uint {
+	return []uint{chat.ClientID, chat.FreelancerID}
+}
+
+// OtherParticipantID returns the user on the other side of the chat from userID
+func OtherParticipantID(chat models.Chat, userID uint) uint {
+	if chat.ClientID == userID {
+		return chat.FreelancerID
+	}
+	return chat.ClientID
+}
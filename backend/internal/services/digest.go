@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/mailer"
+	"freelance-platform/internal/models"
+)
+
+// maxDigestProjects caps the matching projects listed in one digest
+const maxDigestProjects = 10
+
+// digestTypes are batched into the digest instead of sent one email each
+var digestTypes = map[string]bool{
+	events.MessageReceived:     true,
+	NotificationProjectMatched: true,
+}
+
+var englishStatusLabels = map[string]string{
+	"open":        "open",
+	"in_progress": "in progress",
+	"completed":   "completed",
+	"cancelled":   "cancelled",
+	"deleted":     "deleted",
+}
+
+// NotificationEmail is the data of the notification email template
+type NotificationEmail struct {
+	Name            string
+	Type            string
+	Title           string
+	Body            string
+	ProjectTitle    string
+	StatusLabel     string
+	ActorName       string
+	Link            string
+	PreferencesLink string
+}
+
+// DigestEmail is the data of the digest email template
+type DigestEmail struct {
+	Name            string
+	Messages        []DigestMessage
+	Projects        []DigestProject
+	PreferencesLink string
+}
+
+// DigestMessage is a chat with unread messages, quoting the latest one
+type DigestMessage struct {
+	SenderName   string
+	ProjectTitle string
+	Preview      string
+	Link         string
+}
+
+type DigestProject struct {
+	Title     string
+	BudgetMin int
+	BudgetMax int
+	Link      string
+}
+
+// DigestInterval is the least time between two digests to the same user, read
+// from NOTIFICATION_DIGEST_HOURS (24 by default)
+func DigestInterval() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("NOTIFICATION_DIGEST_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// SendEmails sends the notification emails due at now and returns how many
+// went out. Notifications go out one email each, except chat messages and
+// matching projects, which wait for the user's next digest. Nothing is sent
+// to users in their quiet hours; their email waits until the hours end.
+func (s *NotificationService) SendEmails(ctx context.Context, now time.Time) (int, error) {
+	userIDs, err := s.repos.Notifications.PendingEmailUserIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Freelancers who asked for matching projects get a digest even without pending notifications
+	matchers, err := s.repos.Preferences.UserIDsWithChannel(ctx, NotificationProjectMatched, ChannelEmail)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[uint]bool, len(userIDs))
+	for _, id := range userIDs {
+		seen[id] = true
+	}
+	for _, id := range matchers {
+		if !seen[id] {
+			userIDs = append(userIDs, id)
+		}
+	}
+
+	sent := 0
+	for _, userID := range userIDs {
+		count, err := s.emailUser(ctx, userID, now)
+		sent += count
+		if err != nil {
+			log.Printf("Failed to email notifications to user %d: %v", userID, err)
+		}
+	}
+	return sent, nil
+}
+
+// emailUser sends one user's due notification emails and digest
+func (s *NotificationService) emailUser(ctx context.Context, userID uint, now time.Time) (int, error) {
+	settings, err := s.repos.Preferences.Settings(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if inQuietHours(settings, now) {
+		return 0, nil
+	}
+
+	user, err := s.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	pending, err := s.repos.Notifications.ListPendingEmail(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var batched []models.Notification
+	for _, notification := range pending {
+		if digestTypes[notification.Type] {
+			batched = append(batched, notification)
+			continue
+		}
+
+		msg, err := mailer.Render(settings.Locale, "notification", s.notificationEmail(ctx, user, settings.Locale, notification))
+		if err != nil {
+			return sent, err
+		}
+		if err := s.send(ctx, user, msg, []uint{notification.ID}, now); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	if settings.LastDigestAt != nil && now.Sub(*settings.LastDigestAt) < DigestInterval() {
+		return sent, nil
+	}
+
+	digest, err := s.digestEmail(ctx, user, settings, batched, now)
+	if err != nil || (len(digest.Messages) == 0 && len(digest.Projects) == 0) {
+		return sent, err
+	}
+
+	msg, err := mailer.Render(settings.Locale, "digest", digest)
+	if err != nil {
+		return sent, err
+	}
+	ids := make([]uint, 0, len(batched))
+	for _, notification := range batched {
+		ids = append(ids, notification.ID)
+	}
+	if err := s.send(ctx, user, msg, ids, now); err != nil {
+		return sent, err
+	}
+	return sent + 1, s.repos.Preferences.MarkDigestSent(ctx, user.ID, now)
+}
+
+// send mails msg to the user and records that the notifications went out
+func (s *NotificationService) send(ctx context.Context, user models.User, msg mailer.Message, notificationIDs []uint, now time.Time) error {
+	msg.To = user.Email
+	if err := mailer.Send(ctx, msg); err != nil {
+		return err
+	}
+	return s.repos.Notifications.MarkEmailed(ctx, notificationIDs, now)
+}
+
+func (s *NotificationService) notificationEmail(ctx context.Context, user models.User, locale string, notification models.Notification) NotificationEmail {
+	data := NotificationEmail{
+		Name:            user.Name,
+		Type:            notification.Type,
+		Title:           notification.Title,
+		Body:            notification.Body,
+		Link:            frontendURL("/"),
+		PreferencesLink: frontendURL("/settings"),
+	}
+
+	if notification.ProjectID != nil {
+		if project, err := s.repos.Projects.FindByID(ctx, *notification.ProjectID); err == nil {
+			data.ProjectTitle = project.Title
+			data.StatusLabel = statusLabel(locale, project.Status)
+			data.Link = frontendURL(fmt.Sprintf("/projects/%d", project.ID))
+		}
+	}
+	if notification.ActorID != nil {
+		if actor, err := s.repos.Users.FindByID(ctx, *notification.ActorID); err == nil {
+			data.ActorName = actor.Name
+		}
+	}
+	return data
+}
+
+// digestEmail collects the chats with unread messages and, for freelancers who
+// asked for them, the open projects posted since the last digest that ask for
+// their skills
+func (s *NotificationService) digestEmail(ctx context.Context, user models.User, settings models.NotificationSetting, messages []models.Notification, now time.Time) (DigestEmail, error) {
+	digest := DigestEmail{Name: user.Name, PreferencesLink: frontendURL("/settings")}
+
+	names := make(map[uint]string)
+	titles := make(map[uint]string)
+	for _, notification := range messages {
+		if notification.Type != events.MessageReceived || notification.ChatID == nil {
+			continue
+		}
+
+		message := DigestMessage{
+			Preview: notification.Body,
+			Link:    frontendURL(fmt.Sprintf("/messages?chat=%d", *notification.ChatID)),
+		}
+		if id := notification.ActorID; id != nil {
+			if _, ok := names[*id]; !ok {
+				actor, _ := s.repos.Users.FindByID(ctx, *id)
+				names[*id] = actor.Name
+			}
+			message.SenderName = names[*id]
+		}
+		if id := notification.ProjectID; id != nil {
+			if _, ok := titles[*id]; !ok {
+				project, _ := s.repos.Projects.FindByID(ctx, *id)
+				titles[*id] = project.Title
+			}
+			message.ProjectTitle = titles[*id]
+		}
+		digest.Messages = append(digest.Messages, message)
+	}
+
+	if user.Role != "freelancer" {
+		return digest, nil
+	}
+	channel, err := s.channel(ctx, user.ID, NotificationProjectMatched)
+	if err != nil || channel != ChannelEmail {
+		return digest, err
+	}
+
+	since := now.Add(-DigestInterval())
+	if settings.LastDigestAt != nil {
+		since = *settings.LastDigestAt
+	}
+	projects, err := s.repos.Projects.ListMatching(ctx, user.ID, userSkills(user), since, maxDigestProjects)
+	if err != nil {
+		return digest, err
+	}
+	for _, project := range projects {
+		digest.Projects = append(digest.Projects, DigestProject{
+			Title:     project.Title,
+			BudgetMin: project.BudgetMin,
+			BudgetMax: project.BudgetMax,
+			Link:      frontendURL(fmt.Sprintf("/projects/%d", project.ID)),
+		})
+	}
+	return digest, nil
+}
+
+// userSkills reads the user's skills, stored as a JSON array
+func userSkills(user models.User) []string {
+	var skills []string
+	if err := json.Unmarshal([]byte(user.Skills), &skills); err != nil {
+		return nil
+	}
+
+	var cleaned []string
+	for _, skill := range skills {
+		if skill = strings.TrimSpace(skill); skill != "" {
+			cleaned = append(cleaned, skill)
+		}
+	}
+	return cleaned
+}
+
+func statusLabel(locale, status string) string {
+	if locale == "en" {
+		return englishStatusLabels[status]
+	}
+	return projectStatusLabels[status]
+}
+
+// frontendURL points at a frontend page
+func frontendURL(path string) string {
+	base := os.Getenv("FRONTEND_URL")
+	if base == "" {
+		base = "http://localhost:3000"
+	}
+	return strings.TrimRight(base, "/") + path
+}
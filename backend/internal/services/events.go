@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+
+	"gorm.io/gorm"
+)
+
+// RecordStatusChange records ProjectStatusChanged in the outbox through tx
+// when a change moved the project to another status
+func RecordStatusChange(ctx context.Context, tx *gorm.DB, actorID uint, before, after models.Project) error {
+	if before.Status == after.Status {
+		return nil
+	}
+
+	return outbox.Record(ctx, tx, events.Event{
+		Type:    events.ProjectStatusChanged,
+		ActorID: actorID,
+		Data: events.ProjectStatusData{
+			ProjectID:    after.ID,
+			ProjectTitle: after.Title,
+			ClientID:     after.ClientID,
+			FreelancerID: after.FreelancerID,
+			From:         before.Status,
+			To:           after.Status,
+		},
+	})
+}
+
+// BidEventData describes a bid loaded with its project and freelancer
+func BidEventData(bid models.Bid) events.BidData {
+	return events.BidData{
+		BidID:          bid.ID,
+		ProjectID:      bid.ProjectID,
+		ProjectTitle:   bid.Project.Title,
+		ClientID:       bid.Project.ClientID,
+		FreelancerID:   bid.FreelancerID,
+		FreelancerName: bid.Freelancer.Name,
+		Amount:         bid.Amount,
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"freelance-platform/internal/repository"
+)
+
+// maxCachedFacets bounds the cache; past it, expired entries are dropped and
+// if that isn't enough the cache starts over
+const maxCachedFacets = 1000
+
+// facetCache keeps recent facet counts per filter in process memory. The
+// counts feed the filter sidebar, where being a few seconds behind is fine.
+type facetCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedFacets
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+type cachedFacets struct {
+	facets    repository.ProjectFacets
+	expiresAt time.Time
+}
+
+func newFacetCache(ttl time.Duration) *facetCache {
+	return &facetCache{entries: make(map[string]cachedFacets), ttl: ttl, now: time.Now}
+}
+
+func (c *facetCache) get(key string) (repository.ProjectFacets, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return repository.ProjectFacets{}, false
+	}
+	return entry.facets, true
+}
+
+func (c *facetCache) put(key string, facets repository.ProjectFacets) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= maxCachedFacets {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCachedFacets {
+			c.entries = make(map[string]cachedFacets)
+		}
+	}
+	c.entries[key] = cachedFacets{facets: facets, expiresAt: now.Add(c.ttl)}
+}
+
+// facetKey identifies the filter a set of facet counts was computed for
+func facetKey(filter repository.ProjectFilter) string {
+	budget := func(v *int) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	}
+	return fmt.Sprintf("%t|%q|%q|%q|%s|%s|%q", filter.IncludeClosed, filter.Category, filter.Location,
+		filter.Urgency, budget(filter.MinBudget), budget(filter.MaxBudget), filter.Search)
+}
+
+// FacetCacheTTL returns how long facet counts are reused, configured via
+// PROJECT_FACETS_CACHE_SECONDS; 0 disables the cache
+func FacetCacheTTL() time.Duration {
+	seconds := 30
+	if value := os.Getenv("PROJECT_FACETS_CACHE_SECONDS"); value != "" {
+		if s, err := strconv.Atoi(value); err == nil && s >= 0 {
+			seconds = s
+		}
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// Facets counts the projects matching filter per category, location, urgency
+// and budget bucket, each facet computed without its own filter
+func (s *ProjectService) Facets(ctx context.Context, filter repository.ProjectFilter) (repository.ProjectFacets, error) {
+	key := facetKey(filter)
+	if facets, ok := s.facets.get(key); ok {
+		return facets, nil
+	}
+
+	facets, err := s.repos.Projects.Facets(ctx, filter)
+	if err != nil {
+		return facets, err
+	}
+	s.facets.put(key, facets)
+	return facets, nil
+}
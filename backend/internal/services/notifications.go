@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/internal/pagination"
+	"freelance-platform/internal/realtime"
+	"freelance-platform/internal/repository"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// Notification types besides the event types they share a name with
+const NotificationBidRejected = "bid.rejected"
+
+// previewLength is how much of a message a notification quotes, in characters
+const previewLength = 80
+
+var projectStatusLabels = map[string]string{
+	"open":        "開放中",
+	"in_progress": "進行中",
+	"completed":   "已完成",
+	"cancelled":   "已取消",
+	"deleted":     "已刪除",
+}
+
+// NotificationService turns domain events into notifications and serves the
+// notification center
+type NotificationService struct {
+	repos   *repository.Repositories
+	publish Publisher
+}
+
+// NewNotificationService returns a notification service; a nil publisher disables realtime events
+func NewNotificationService(repos *repository.Repositories, publish Publisher) *NotificationService {
+	if publish == nil {
+		publish = func([]uint, string, interface{}) {}
+	}
+	return &NotificationService{repos: repos, publish: publish}
+}
+
+// Subscribe starts creating notifications for the events published on bus
+func (s *NotificationService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(s.handle, events.BidCreated, events.BidAccepted, events.ProjectStatusChanged, events.MessageReceived)
+}
+
+// notificationConsumer is the notification center's name in processed_events
+const notificationConsumer = "notifications"
+
+// handle creates the notifications for an event in one transaction with its
+// processed mark, so a redelivered event notifies nobody twice, and pushes
+// them once committed
+func (s *NotificationService) handle(ctx context.Context, event events.Event) error {
+	var created []models.Notification
+	err := s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		first, err := outbox.MarkProcessed(ctx, tx.DB(), notificationConsumer, event.ID)
+		if err != nil || !first {
+			return err
+		}
+		created, err = s.notify(ctx, tx, event)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("notify about %s: %w", event.Type, err)
+	}
+
+	for _, notification := range created {
+		s.push(ctx, notification)
+	}
+	return nil
+}
+
+// notify stores the notifications for event through tx and returns them
+func (s *NotificationService) notify(ctx context.Context, tx *repository.Repositories, event events.Event) ([]models.Notification, error) {
+	var notifications []models.Notification
+	var err error
+
+	switch data := event.Data.(type) {
+	case events.BidData:
+		if event.Type == events.BidCreated {
+			notifications = bidCreatedNotifications(event, data)
+		} else {
+			notifications = bidAcceptedNotifications(event, data)
+		}
+	case events.ProjectStatusData:
+		notifications, err = s.projectStatusNotifications(ctx, event, data)
+	case events.MessageData:
+		return s.notifyMessage(ctx, tx, event, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var created []models.Notification
+	for _, notification := range notifications {
+		channel, err := s.channel(ctx, notification.UserID, notification.Type)
+		if err != nil {
+			return nil, err
+		}
+		if channel == ChannelNone {
+			continue
+		}
+
+		notification.SendEmail = channel == ChannelEmail
+		if err := tx.Notifications.Create(ctx, &notification); err != nil {
+			return nil, err
+		}
+		created = append(created, notification)
+	}
+	return created, nil
+}
+
+func bidCreatedNotifications(event events.Event, data events.BidData) []models.Notification {
+	return []models.Notification{{
+		UserID:    data.ClientID,
+		Type:      events.BidCreated,
+		Title:     "新的投標",
+		Body:      fmt.Sprintf("%s 對「%s」提出了 NT$ %d 的報價。", data.FreelancerName, data.ProjectTitle, data.Amount),
+		ActorID:   &event.ActorID,
+		ProjectID: &data.ProjectID,
+		BidID:     &data.BidID,
+	}}
+}
+
+func bidAcceptedNotifications(event events.Event, data events.BidData) []models.Notification {
+	notifications := []models.Notification{{
+		UserID:    data.FreelancerID,
+		Type:      events.BidAccepted,
+		Title:     "投標已被接受",
+		Body:      fmt.Sprintf("您對「%s」的報價已被接受，案件正式開始進行。", data.ProjectTitle),
+		ActorID:   &event.ActorID,
+		ProjectID: &data.ProjectID,
+		BidID:     &data.BidID,
+	}}
+	for _, freelancerID := range data.RejectedFreelancerIDs {
+		notifications = append(notifications, models.Notification{
+			UserID:    freelancerID,
+			Type:      NotificationBidRejected,
+			Title:     "投標未獲採納",
+			Body:      fmt.Sprintf("「%s」已選定其他接案者，感謝您的提案。", data.ProjectTitle),
+			ActorID:   &event.ActorID,
+			ProjectID: &data.ProjectID,
+		})
+	}
+	return notifications
+}
+
+// projectStatusNotifications tells the client, the assigned freelancer and
+// anyone still waiting on a bid, except whoever made the change
+func (s *NotificationService) projectStatusNotifications(ctx context.Context, event events.Event, data events.ProjectStatusData) ([]models.Notification, error) {
+	recipients := []uint{data.ClientID}
+	if data.FreelancerID != nil {
+		recipients = append(recipients, *data.FreelancerID)
+	}
+	bidders, err := s.repos.Bids.BidderIDs(ctx, data.ProjectID, "pending")
+	if err != nil {
+		return nil, err
+	}
+	recipients = append(recipients, bidders...)
+
+	body := fmt.Sprintf("「%s」的狀態已變更為%s。", data.ProjectTitle, projectStatusLabels[data.To])
+	if data.To == "deleted" {
+		body = fmt.Sprintf("「%s」已被發案者刪除。", data.ProjectTitle)
+	}
+
+	var notifications []models.Notification
+	seen := map[uint]bool{event.ActorID: true}
+	for _, userID := range recipients {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		notifications = append(notifications, models.Notification{
+			UserID:    userID,
+			Type:      events.ProjectStatusChanged,
+			Title:     "案件狀態更新",
+			Body:      body,
+			ActorID:   &event.ActorID,
+			ProjectID: &data.ProjectID,
+		})
+	}
+	return notifications, nil
+}
+
+// notifyMessage keeps one unread notification per chat, updated with the
+// latest message, instead of one per message
+func (s *NotificationService) notifyMessage(ctx context.Context, tx *repository.Repositories, event events.Event, data events.MessageData) ([]models.Notification, error) {
+	channel, err := s.channel(ctx, data.RecipientID, events.MessageReceived)
+	if err != nil || channel == ChannelNone {
+		return nil, err
+	}
+
+	title := fmt.Sprintf("%s 傳來新訊息", data.SenderName)
+	body := preview(data.Preview)
+	switch data.Type {
+	case "image":
+		body = "傳送了一張圖片"
+	case "file":
+		body = "傳送了一個檔案"
+	}
+
+	notification, err := tx.Notifications.FindUnreadForChat(ctx, data.RecipientID, events.MessageReceived, data.ChatID)
+	if errors.Is(err, repository.ErrNotFound) {
+		notification = models.Notification{
+			UserID:    data.RecipientID,
+			Type:      events.MessageReceived,
+			ProjectID: &data.ProjectID,
+			ChatID:    &data.ChatID,
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	notification.Title = title
+	notification.Body = body
+	notification.ActorID = &event.ActorID
+	notification.CreatedAt = event.OccurredAt
+	// A new message puts the chat back into the next digest
+	notification.SendEmail = channel == ChannelEmail
+	notification.EmailedAt = nil
+
+	if err := tx.Notifications.Save(ctx, &notification); err != nil {
+		return nil, err
+	}
+	return []models.Notification{notification}, nil
+}
+
+// push sends a new or updated notification and the new badge count to the user's open connections
+func (s *NotificationService) push(ctx context.Context, notification models.Notification) {
+	s.publish([]uint{notification.UserID}, realtime.EventNotification, map[string]interface{}{"notification": notification})
+	s.PublishUnreadCount(ctx, notification.UserID)
+}
+
+// List returns a page of the user's notifications, newest first
+func (s *NotificationService) List(ctx context.Context, user models.User, unreadOnly bool, page pagination.Page) ([]models.Notification, pagination.Meta, error) {
+	return s.repos.Notifications.ListByUser(ctx, user.ID, unreadOnly, page)
+}
+
+// MarkRead marks one of the user's notifications as read
+func (s *NotificationService) MarkRead(ctx context.Context, user models.User, id uint) (models.Notification, error) {
+	notification, err := s.repos.Notifications.MarkRead(ctx, user.ID, id, time.Now())
+	if err != nil {
+		return notification, notFound(err, ErrNotificationNotFound)
+	}
+	s.PublishUnreadCount(ctx, user.ID)
+	return notification, nil
+}
+
+// MarkAllRead marks all of the user's notifications as read and returns how many were unread
+func (s *NotificationService) MarkAllRead(ctx context.Context, user models.User) (int64, error) {
+	updated, err := s.repos.Notifications.MarkAllRead(ctx, user.ID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	s.PublishUnreadCount(ctx, user.ID)
+	return updated, nil
+}
+
+// UnreadCount is the number on the user's notification badge
+func (s *NotificationService) UnreadCount(ctx context.Context, user models.User) (int64, error) {
+	return s.repos.Notifications.CountUnread(ctx, user.ID)
+}
+
+// PublishUnreadCount pushes the user's notification badge count to their open connections
+func (s *NotificationService) PublishUnreadCount(ctx context.Context, userID uint) {
+	count, err := s.repos.Notifications.CountUnread(ctx, userID)
+	if err != nil {
+		return
+	}
+	s.publish([]uint{userID}, realtime.EventNotificationCount, map[string]interface{}{"unread_count": count})
+}
+
+// preview shortens message text for a notification
+func preview(text string) string {
+	runes := []rune(text)
+	if len(runes) <= previewLength {
+		return text
+	}
+	return string(runes[:previewLength]) + "…"
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/mailer"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/repository"
+)
+
+// Notification channels
+const (
+	ChannelInApp = "in_app" // notification center only
+	ChannelEmail = "email"  // notification center and email
+	ChannelNone  = "none"
+)
+
+// NotificationProjectMatched covers new projects asking for the freelancer's
+// skills; it only goes out in the email digest
+const NotificationProjectMatched = "project.matched"
+
+var (
+	ErrUnknownNotificationType = errors.New("unknown notification type")
+	ErrInvalidChannel          = errors.New("invalid notification channel")
+	ErrInvalidQuietHours       = errors.New("invalid quiet hours")
+	ErrUnsupportedLocale       = errors.New("unsupported locale")
+)
+
+// NotificationTypes lists the types users can pick a channel for
+var NotificationTypes = []string{
+	events.BidCreated,
+	events.BidAccepted,
+	NotificationBidRejected,
+	events.ProjectStatusChanged,
+	events.MessageReceived,
+	NotificationProjectMatched,
+}
+
+// defaultChannels applies to types the user never picked a channel for
+var defaultChannels = map[string]string{
+	events.BidCreated:           ChannelInApp,
+	events.BidAccepted:          ChannelInApp,
+	NotificationBidRejected:     ChannelInApp,
+	events.ProjectStatusChanged: ChannelInApp,
+	events.MessageReceived:      ChannelInApp,
+	NotificationProjectMatched:  ChannelNone,
+}
+
+// QuietHoursTimezone is the zone quiet hours are given in
+const QuietHoursTimezone = "Asia/Taipei"
+
+var quietHoursLocation = loadQuietHoursLocation()
+
+// loadQuietHoursLocation falls back to a fixed UTC+8 zone, which Taiwan has
+// kept without daylight saving since 1980, when tzdata is missing
+func loadQuietHoursLocation() *time.Location {
+	location, err := time.LoadLocation(QuietHoursTimezone)
+	if err != nil {
+		return time.FixedZone(QuietHoursTimezone, 8*60*60)
+	}
+	return location
+}
+
+// QuietHours is a daily window, as HH:MM in Asia/Taipei, during which no
+// email is sent; End may be on the next day
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// NotificationPreferences is everything a user can set about their notifications
+type NotificationPreferences struct {
+	// Channels has an entry for every type in NotificationTypes
+	Channels   map[string]string `json:"channels"`
+	QuietHours *QuietHours       `json:"quiet_hours"`
+	Locale     string            `json:"locale"`
+	Timezone   string            `json:"timezone"`
+}
+
+// PreferencesUpdate changes some of a user's preferences. Channels only
+// lists the types to change; a nil QuietHours leaves them alone and one with
+// an empty Start and End turns them off; an empty Locale keeps the current one.
+type PreferencesUpdate struct {
+	Channels   map[string]string
+	QuietHours *QuietHours
+	Locale     string
+}
+
+// Preferences returns the user's notification preferences, defaults filled in
+func (s *NotificationService) Preferences(ctx context.Context, user models.User) (NotificationPreferences, error) {
+	channels, err := s.repos.Preferences.Channels(ctx, user.ID)
+	if err != nil {
+		return NotificationPreferences{}, err
+	}
+	settings, err := s.repos.Preferences.Settings(ctx, user.ID)
+	if err != nil {
+		return NotificationPreferences{}, err
+	}
+
+	preferences := NotificationPreferences{
+		Channels: make(map[string]string, len(NotificationTypes)),
+		Locale:   settings.Locale,
+		Timezone: QuietHoursTimezone,
+	}
+	for _, notificationType := range NotificationTypes {
+		preferences.Channels[notificationType] = channelOrDefault(channels, notificationType)
+	}
+	if settings.QuietHoursStart != nil && settings.QuietHoursEnd != nil {
+		preferences.QuietHours = &QuietHours{
+			Start: formatClock(*settings.QuietHoursStart),
+			End:   formatClock(*settings.QuietHoursEnd),
+		}
+	}
+	if preferences.Locale == "" {
+		preferences.Locale = mailer.DefaultLocale
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences validates and stores a change to the user's preferences
+func (s *NotificationService) UpdatePreferences(ctx context.Context, user models.User, update PreferencesUpdate) (NotificationPreferences, error) {
+	for notificationType, channel := range update.Channels {
+		if _, ok := defaultChannels[notificationType]; !ok {
+			return NotificationPreferences{}, fmt.Errorf("%w: %s", ErrUnknownNotificationType, notificationType)
+		}
+		if channel != ChannelInApp && channel != ChannelEmail && channel != ChannelNone {
+			return NotificationPreferences{}, fmt.Errorf("%w: %s", ErrInvalidChannel, channel)
+		}
+		// Project matches have no notification center entry
+		if notificationType == NotificationProjectMatched && channel == ChannelInApp {
+			return NotificationPreferences{}, fmt.Errorf("%w: %s only supports email or none", ErrInvalidChannel, notificationType)
+		}
+	}
+
+	if update.Locale != "" && !mailer.SupportsLocale(update.Locale) {
+		return NotificationPreferences{}, fmt.Errorf("%w: %s", ErrUnsupportedLocale, update.Locale)
+	}
+
+	settings, err := s.repos.Preferences.Settings(ctx, user.ID)
+	if err != nil {
+		return NotificationPreferences{}, err
+	}
+	if update.Locale != "" {
+		settings.Locale = update.Locale
+	} else if settings.Locale == "" {
+		settings.Locale = mailer.DefaultLocale
+	}
+	if update.QuietHours != nil {
+		if update.QuietHours.Start == "" && update.QuietHours.End == "" {
+			settings.QuietHoursStart, settings.QuietHoursEnd = nil, nil
+		} else {
+			start, startErr := parseClock(update.QuietHours.Start)
+			end, endErr := parseClock(update.QuietHours.End)
+			if startErr != nil || endErr != nil || start == end {
+				return NotificationPreferences{}, ErrInvalidQuietHours
+			}
+			settings.QuietHoursStart, settings.QuietHoursEnd = &start, &end
+		}
+	}
+
+	err = s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		if err := tx.Preferences.SetChannels(ctx, user.ID, update.Channels); err != nil {
+			return err
+		}
+		return tx.Preferences.SaveSettings(ctx, &settings)
+	})
+	if err != nil {
+		return NotificationPreferences{}, err
+	}
+
+	return s.Preferences(ctx, user)
+}
+
+// channel returns the channel the user wants for a notification type
+func (s *NotificationService) channel(ctx context.Context, userID uint, notificationType string) (string, error) {
+	channels, err := s.repos.Preferences.Channels(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return channelOrDefault(channels, notificationType), nil
+}
+
+func channelOrDefault(channels map[string]string, notificationType string) string {
+	if channel, ok := channels[notificationType]; ok {
+		return channel
+	}
+	if channel, ok := defaultChannels[notificationType]; ok {
+		return channel
+	}
+	return ChannelInApp
+}
+
+// inQuietHours reports whether at falls in the user's quiet hours
+func inQuietHours(settings models.NotificationSetting, at time.Time) bool {
+	if settings.QuietHoursStart == nil || settings.QuietHoursEnd == nil {
+		return false
+	}
+	start, end := *settings.QuietHoursStart, *settings.QuietHoursEnd
+
+	local := at.In(quietHoursLocation)
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// The window wraps past midnight, like 22:00 to 08:00
+	return minute >= start || minute < end
+}
+
+// parseClock reads HH:MM as minutes after midnight
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,402 @@
+// Package services holds the marketplace business rules, written against the
+// repository interfaces so they can run on any connection or transaction.
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/internal/pagination"
+	"freelance-platform/internal/repository"
+	"freelance-platform/internal/search"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProjectNotFound       = errors.New("project not found")
+	ErrProjectDeleted        = errors.New("project has been deleted")
+	ErrProjectAlreadyDeleted = errors.New("project is already deleted")
+	ErrNotProjectOwner       = errors.New("project not owned by user")
+	ErrInvalidBudget         = errors.New("budget minimum must be less than maximum")
+	ErrInvalidProjectStatus  = errors.New("invalid project status")
+	ErrProjectUnderContract  = errors.New("project completes through its contract")
+	ErrNotFreelancer         = errors.New("only freelancers can place bids")
+	ErrOwnProject            = errors.New("cannot bid on own project")
+	ErrDuplicateBid          = errors.New("already bid on project")
+	ErrProjectNotBidding     = errors.New("project is not open for bidding")
+	ErrBidOutOfBudget        = errors.New("bid amount outside budget")
+	ErrInvalidBidStatus      = errors.New("invalid bid status")
+)
+
+var (
+	ValidProjectStatuses = []string{"open", "in_progress", "completed", "cancelled"}
+	ValidBidStatuses     = []string{"pending", "accepted", "rejected", "withdrawn", "expired"}
+)
+
+// snippetLength is how much of a description search results show, in characters
+const snippetLength = 120
+
+// StatusFunc applies a project status change inside tx, including its side
+// effects on contracts, escrow and user statistics
+type StatusFunc func(tx *gorm.DB, project *models.Project, status string) error
+
+// ProjectInput is the editable part of a project
+type ProjectInput struct {
+	Title        string
+	Description  string
+	BudgetMin    int
+	BudgetMax    int
+	Category     string
+	Location     string
+	Skills       string
+	Requirements string
+	Urgency      string
+}
+
+// BidInput is a freelancer's offer on a project
+type BidInput struct {
+	ProjectID uint
+	Amount    int
+	Proposal  string
+	Timeline  string
+}
+
+type ProjectService struct {
+	repos       *repository.Repositories
+	applyStatus StatusFunc
+	facets      *facetCache
+}
+
+func NewProjectService(repos *repository.Repositories, applyStatus StatusFunc) *ProjectService {
+	return &ProjectService{repos: repos, applyStatus: applyStatus, facets: newFacetCache(FacetCacheTTL())}
+}
+
+// List returns a page of projects; search results come ranked by relevance
+// and with the matching words highlighted
+func (s *ProjectService) List(ctx context.Context, filter repository.ProjectFilter, page pagination.Page) ([]models.Project, pagination.Meta, error) {
+	projects, meta, err := s.repos.Projects.List(ctx, filter, page)
+	if err != nil || filter.Search == "" {
+		return projects, meta, err
+	}
+
+	terms := search.Terms(filter.Search)
+	for i := range projects {
+		projects[i].Highlight = &models.ProjectHighlight{
+			Title:       search.Highlight(projects[i].Title, terms),
+			Description: search.Snippet(projects[i].Description, terms, snippetLength),
+		}
+	}
+	return projects, meta, nil
+}
+
+// Get returns a project with its parties and bids; deleted projects yield ErrProjectDeleted
+func (s *ProjectService) Get(ctx context.Context, id uint) (models.Project, error) {
+	project, err := s.repos.Projects.FindWithBids(ctx, id)
+	if err != nil {
+		return project, notFound(err, ErrProjectNotFound)
+	}
+
+	if project.Status == "deleted" {
+		return project, ErrProjectDeleted
+	}
+
+	return project, nil
+}
+
+func (s *ProjectService) Create(ctx context.Context, client models.User, input ProjectInput) (models.Project, error) {
+	if input.BudgetMin >= input.BudgetMax {
+		return models.Project{}, ErrInvalidBudget
+	}
+
+	project := models.Project{
+		Title:        input.Title,
+		Description:  input.Description,
+		BudgetMin:    input.BudgetMin,
+		BudgetMax:    input.BudgetMax,
+		Currency:     "TWD",
+		Category:     input.Category,
+		Location:     input.Location,
+		Skills:       input.Skills,
+		Requirements: input.Requirements,
+		Urgency:      input.Urgency,
+		ClientID:     client.ID,
+		Status:       "open",
+	}
+
+	if project.Urgency == "" {
+		project.Urgency = "一般"
+	}
+
+	if err := s.repos.Projects.Create(ctx, &project); err != nil {
+		return project, err
+	}
+
+	s.repos.Projects.LoadParties(ctx, &project)
+	return project, nil
+}
+
+func (s *ProjectService) Update(ctx context.Context, user models.User, id uint, input ProjectInput) (models.Project, error) {
+	project, err := s.ownedProject(ctx, user, id)
+	if err != nil {
+		return project, err
+	}
+
+	if input.BudgetMin >= input.BudgetMax {
+		return project, ErrInvalidBudget
+	}
+
+	project.Title = input.Title
+	project.Description = input.Description
+	project.BudgetMin = input.BudgetMin
+	project.BudgetMax = input.BudgetMax
+	project.Currency = "TWD"
+	project.Category = input.Category
+	project.Location = input.Location
+	project.Skills = input.Skills
+	project.Requirements = input.Requirements
+	if input.Urgency != "" {
+		project.Urgency = input.Urgency
+	}
+
+	if err := s.repos.Projects.Save(ctx, &project); err != nil {
+		return project, err
+	}
+
+	s.repos.Projects.LoadParties(ctx, &project)
+	return project, nil
+}
+
+// Delete soft deletes a project by marking it deleted and tells every chat
+// about it, all in one transaction with the event. Like cancelling, it ends a
+// running contract and refunds its escrow. It returns the project before and
+// after the change
+func (s *ProjectService) Delete(ctx context.Context, user models.User, id uint) (models.Project, models.Project, error) {
+	project, err := s.ownedProject(ctx, user, id)
+	if err != nil {
+		return project, project, err
+	}
+
+	if project.Status == "deleted" {
+		return project, project, ErrProjectAlreadyDeleted
+	}
+
+	before := project
+	err = s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		// Add a system message to each chat before marking the project as deleted
+		chats, err := tx.Chats.ListByProject(ctx, project.ID)
+		if err != nil {
+			return err
+		}
+		for _, chat := range chats {
+			err := tx.Messages.Create(ctx, &models.Message{
+				ChatID:   chat.ID,
+				SenderID: user.ID, // the project owner speaks for the platform here
+				Content:  "此案件已被發案者刪除。",
+				Type:     "system",
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := s.applyStatus(tx.DB(), &project, "deleted"); err != nil {
+			return err
+		}
+		return RecordStatusChange(ctx, tx.DB(), user.ID, before, project)
+	})
+	if err != nil {
+		return before, before, err
+	}
+
+	outbox.Flush(ctx)
+	return before, project, nil
+}
+
+// UpdateStatus lets the owner move a project between statuses; it returns the
+// project before and after the change
+func (s *ProjectService) UpdateStatus(ctx context.Context, user models.User, id uint, status string) (models.Project, models.Project, error) {
+	project, err := s.ownedProject(ctx, user, id)
+	if err != nil {
+		return project, project, err
+	}
+
+	if !IsValidProjectStatus(status) {
+		return project, project, ErrInvalidProjectStatus
+	}
+
+	// Projects under contract complete when their last milestone is approved
+	if status == "completed" && project.Status != "completed" {
+		hasContract, err := s.repos.Projects.HasContract(ctx, project.ID)
+		if err != nil {
+			return project, project, err
+		}
+		if hasContract {
+			return project, project, ErrProjectUnderContract
+		}
+	}
+
+	before := project
+	err = s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		if err := s.applyStatus(tx.DB(), &project, status); err != nil {
+			return err
+		}
+		return RecordStatusChange(ctx, tx.DB(), user.ID, before, project)
+	})
+	if err != nil {
+		return before, project, err
+	}
+
+	outbox.Flush(ctx)
+	s.repos.Projects.LoadParties(ctx, &project)
+	return before, project, nil
+}
+
+// PlaceBid creates a pending bid for a freelancer on an open project together with its BidCreated event
+func (s *ProjectService) PlaceBid(ctx context.Context, freelancer models.User, input BidInput) (models.Bid, error) {
+	if freelancer.Role != "freelancer" {
+		return models.Bid{}, ErrNotFreelancer
+	}
+
+	project, err := s.repos.Projects.FindByID(ctx, input.ProjectID)
+	if err != nil {
+		return models.Bid{}, notFound(err, ErrProjectNotFound)
+	}
+
+	if project.Status != "open" {
+		return models.Bid{}, ErrProjectNotBidding
+	}
+
+	if project.ClientID == freelancer.ID {
+		return models.Bid{}, ErrOwnProject
+	}
+
+	// Withdrawn or expired bids may be replaced
+	if _, err := s.repos.Bids.FindActive(ctx, project.ID, freelancer.ID); err == nil {
+		return models.Bid{}, ErrDuplicateBid
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return models.Bid{}, err
+	}
+
+	if input.Amount < project.BudgetMin || input.Amount > project.BudgetMax {
+		return models.Bid{}, ErrBidOutOfBudget
+	}
+
+	expiresAt := time.Now().Add(BidTTL())
+	bid := models.Bid{
+		ProjectID:    project.ID,
+		FreelancerID: freelancer.ID,
+		Amount:       input.Amount,
+		Proposal:     input.Proposal,
+		Timeline:     input.Timeline,
+		Status:       "pending",
+		ExpiresAt:    &expiresAt,
+	}
+
+	err = s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		if err := tx.Bids.Create(ctx, &bid); err != nil {
+			return err
+		}
+		if err := tx.Bids.LoadDetails(ctx, &bid); err != nil {
+			return err
+		}
+		return outbox.Record(ctx, tx.DB(), events.Event{
+			Type:    events.BidCreated,
+			ActorID: freelancer.ID,
+			Data:    BidEventData(bid),
+		})
+	})
+	if err != nil {
+		return bid, err
+	}
+
+	outbox.Flush(ctx)
+	return bid, nil
+}
+
+// ListBids returns a page of the bids on one of the user's projects, optionally filtered by status
+func (s *ProjectService) ListBids(ctx context.Context, user models.User, projectID uint, status string, page pagination.Page) ([]models.Bid, pagination.Meta, error) {
+	if _, err := s.ownedProject(ctx, user, projectID); err != nil {
+		return nil, pagination.Meta{}, err
+	}
+
+	if status != "" && !IsValidBidStatus(status) {
+		return nil, pagination.Meta{}, ErrInvalidBidStatus
+	}
+
+	bids, meta, err := s.repos.Bids.ListByProject(ctx, projectID, status, page)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	// The worker expires stale bids periodically; until it gets to them they show up as expired
+	now := time.Now()
+	for i := range bids {
+		if bids[i].Status == "pending" && bidExpired(bids[i], now) {
+			bids[i].Status = "expired"
+		}
+	}
+	return bids, meta, nil
+}
+
+// ExpireStaleBids marks every pending bid past its expiry time as expired
+func (s *ProjectService) ExpireStaleBids(ctx context.Context) (int64, error) {
+	return s.repos.Bids.ExpireStale(ctx, time.Now())
+}
+
+// ownedProject loads a project and checks that user is its client
+func (s *ProjectService) ownedProject(ctx context.Context, user models.User, id uint) (models.Project, error) {
+	project, err := s.repos.Projects.FindByID(ctx, id)
+	if err != nil {
+		return project, notFound(err, ErrProjectNotFound)
+	}
+
+	if project.ClientID != user.ID {
+		return project, ErrNotProjectOwner
+	}
+
+	return project, nil
+}
+
+func IsValidProjectStatus(status string) bool {
+	return contains(ValidProjectStatuses, status)
+}
+
+func IsValidBidStatus(status string) bool {
+	return contains(ValidBidStatuses, status)
+}
+
+// BidTTL returns how long a pending bid stays valid, configured via BID_EXPIRE_HOURS
+func BidTTL() time.Duration {
+	hours := 14 * 24 // default: two weeks
+	if expireHours := os.Getenv("BID_EXPIRE_HOURS"); expireHours != "" {
+		if h, err := strconv.Atoi(expireHours); err == nil && h > 0 {
+			hours = h
+		}
+	}
+
+	return time.Duration(hours) * time.Hour
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// notFound replaces repository.ErrNotFound with a domain specific error
+func notFound(err, replacement error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return replacement
+	}
+	return err
+}
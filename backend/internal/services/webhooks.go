@@ -0,0 +1,387 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"freelance-platform/internal/events"
+	"freelance-platform/internal/models"
+	"freelance-platform/internal/outbox"
+	"freelance-platform/internal/pagination"
+	"freelance-platform/internal/payments"
+	"freelance-platform/internal/repository"
+	"freelance-platform/internal/webhooks"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL       = errors.New("invalid webhook url")
+	ErrInvalidWebhookEvents    = errors.New("invalid webhook events")
+	ErrInvalidDeliveryStatus   = errors.New("invalid webhook delivery status")
+	ErrTooManyWebhooks         = errors.New("too many webhooks")
+)
+
+// WebhookEvents lists the event types a webhook can subscribe to
+var WebhookEvents = []string{events.BidCreated, events.BidAccepted, events.ProjectStatusChanged}
+
+var ValidWebhookDeliveryStatuses = []string{models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryDead}
+
+const (
+	maxWebhooksPerUser = 10
+	maxWebhookURL      = 2048
+
+	// deliveryBatch deliveries are claimed at a time and deliveryLease keeps
+	// them from other dispatchers while the batch is sent one by one, each
+	// bounded by the client timeout
+	deliveryBatch = 10
+	deliveryLease = 5 * time.Minute
+
+	// responseLogLength is how much of a response body the delivery log keeps, in bytes
+	responseLogLength = 1024
+)
+
+// WebhookInput is the editable part of a webhook. On update, a nil field is left alone.
+type WebhookInput struct {
+	URL    *string
+	Events []string
+	Active *bool
+}
+
+// WebhookPayload is the JSON body posted to a webhook
+type WebhookPayload struct {
+	ID        string      `json:"id"` // the same for redeliveries
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookService manages users' webhooks and delivers the events they subscribed to
+type WebhookService struct {
+	repos  *repository.Repositories
+	client *http.Client
+}
+
+func NewWebhookService(repos *repository.Repositories) *WebhookService {
+	return &WebhookService{repos: repos, client: webhooks.NewClient()}
+}
+
+// Subscribe starts queueing deliveries for the events published on bus
+func (s *WebhookService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(s.handle, WebhookEvents...)
+}
+
+// webhookConsumer is the webhook dispatcher's name in processed_events
+const webhookConsumer = "webhooks"
+
+// handle queues a delivery to every subscribed webhook of the client and the
+// freelancer involved, in one transaction with the event's processed mark so
+// a redelivered event is sent once. Unlike notifications, the user who caused
+// the event gets it too, since their own systems still need to hear about it.
+func (s *WebhookService) handle(ctx context.Context, event events.Event) error {
+	var recipients []uint
+	data := event.Data
+	switch payload := event.Data.(type) {
+	case events.BidData:
+		recipients = []uint{payload.ClientID, payload.FreelancerID}
+		// Who else bid is none of the integrator's business
+		payload.RejectedFreelancerIDs = nil
+		data = payload
+	case events.ProjectStatusData:
+		recipients = []uint{payload.ClientID}
+		if payload.FreelancerID != nil {
+			recipients = append(recipients, *payload.FreelancerID)
+		}
+	default:
+		return nil
+	}
+
+	// Integrators see the event's own ID, so they can skip repeats too
+	eventID := event.ID
+	if eventID == "" {
+		eventID = "evt_" + randomHex(16)
+	}
+	body, err := json.Marshal(WebhookPayload{ID: eventID, Type: event.Type, CreatedAt: event.OccurredAt, Data: data})
+	if err != nil {
+		return fmt.Errorf("encode webhook payload for %s: %w", event.Type, err)
+	}
+
+	err = s.repos.Transaction(ctx, func(tx *repository.Repositories) error {
+		first, err := outbox.MarkProcessed(ctx, tx.DB(), webhookConsumer, event.ID)
+		if err != nil || !first {
+			return err
+		}
+
+		subscribed, err := tx.Webhooks.ListSubscribed(ctx, recipients, event.Type)
+		if err != nil {
+			return err
+		}
+		for _, webhook := range subscribed {
+			delivery := models.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				EventID:       eventID,
+				EventType:     event.Type,
+				Payload:       body,
+				Status:        models.WebhookDeliveryPending,
+				NextAttemptAt: event.OccurredAt,
+			}
+			if err := tx.Webhooks.CreateDelivery(ctx, &delivery); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("queue webhooks for %s: %w", event.Type, err)
+	}
+	return nil
+}
+
+// List returns the user's webhooks
+func (s *WebhookService) List(ctx context.Context, user models.User) ([]models.Webhook, error) {
+	return s.repos.Webhooks.ListByUser(ctx, user.ID)
+}
+
+// Create registers a webhook for the user and returns it with its signing
+// secret, which is not shown again
+func (s *WebhookService) Create(ctx context.Context, user models.User, input WebhookInput) (models.Webhook, string, error) {
+	if input.URL == nil {
+		return models.Webhook{}, "", ErrInvalidWebhookURL
+	}
+	webhook := models.Webhook{
+		UserID: user.ID,
+		Secret: "whsec_" + randomHex(24),
+		Active: true,
+	}
+	if err := applyWebhookInput(&webhook, input); err != nil {
+		return models.Webhook{}, "", err
+	}
+	if len(webhook.Events) == 0 {
+		return models.Webhook{}, "", ErrInvalidWebhookEvents
+	}
+
+	count, err := s.repos.Webhooks.CountByUser(ctx, user.ID)
+	if err != nil {
+		return models.Webhook{}, "", err
+	}
+	if count >= maxWebhooksPerUser {
+		return models.Webhook{}, "", ErrTooManyWebhooks
+	}
+
+	if err := s.repos.Webhooks.Create(ctx, &webhook); err != nil {
+		return models.Webhook{}, "", err
+	}
+	return webhook, webhook.Secret, nil
+}
+
+// Update changes the URL, subscriptions or active flag of one of the user's webhooks
+func (s *WebhookService) Update(ctx context.Context, user models.User, id uint, input WebhookInput) (models.Webhook, error) {
+	webhook, err := s.find(ctx, user, id)
+	if err != nil {
+		return webhook, err
+	}
+	if input.Events != nil && len(input.Events) == 0 {
+		return webhook, ErrInvalidWebhookEvents
+	}
+	if err := applyWebhookInput(&webhook, input); err != nil {
+		return webhook, err
+	}
+	return webhook, s.repos.Webhooks.Save(ctx, &webhook)
+}
+
+// Delete removes one of the user's webhooks and its delivery log
+func (s *WebhookService) Delete(ctx context.Context, user models.User, id uint) error {
+	webhook, err := s.find(ctx, user, id)
+	if err != nil {
+		return err
+	}
+	return s.repos.Webhooks.Delete(ctx, &webhook)
+}
+
+// Deliveries returns a page of a webhook's delivery log, newest first; an empty status matches all
+func (s *WebhookService) Deliveries(ctx context.Context, user models.User, id uint, status string, page pagination.Page) ([]models.WebhookDelivery, pagination.Meta, error) {
+	if status != "" && !contains(ValidWebhookDeliveryStatuses, status) {
+		return nil, pagination.Meta{}, ErrInvalidDeliveryStatus
+	}
+	if _, err := s.find(ctx, user, id); err != nil {
+		return nil, pagination.Meta{}, err
+	}
+	return s.repos.Webhooks.ListDeliveries(ctx, id, status, page)
+}
+
+// Delivery returns one delivery of the user's webhook with every attempt made
+func (s *WebhookService) Delivery(ctx context.Context, user models.User, id, deliveryID uint) (models.WebhookDelivery, error) {
+	if _, err := s.find(ctx, user, id); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery, err := s.repos.Webhooks.FindDelivery(ctx, id, deliveryID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return delivery, ErrWebhookDeliveryNotFound
+	}
+	return delivery, err
+}
+
+// Redeliver queues the event of a delivery again, whatever became of it, as a
+// new delivery with the same event ID and payload
+func (s *WebhookService) Redeliver(ctx context.Context, user models.User, id, deliveryID uint) (models.WebhookDelivery, error) {
+	original, err := s.Delivery(ctx, user, id, deliveryID)
+	if err != nil {
+		return original, err
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+	return delivery, s.repos.Webhooks.CreateDelivery(ctx, &delivery)
+}
+
+// DeliverDue attempts the deliveries due at now and returns how many were
+// attempted. A 2xx response completes a delivery; anything else is retried
+// with exponential backoff until webhooks.MaxAttempts, after which the
+// delivery is left dead. now only selects what is due; each attempt is
+// signed and scheduled by the clock at the time it is sent.
+func (s *WebhookService) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	attempted := 0
+	for {
+		deliveries, err := s.repos.Webhooks.ClaimDue(ctx, now, deliveryLease, deliveryBatch)
+		if err != nil {
+			return attempted, err
+		}
+
+		ids := make([]uint, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.WebhookID)
+		}
+		found, err := s.repos.Webhooks.FindByIDs(ctx, ids)
+		if err != nil {
+			return attempted, err
+		}
+		byID := make(map[uint]models.Webhook, len(found))
+		for _, webhook := range found {
+			byID[webhook.ID] = webhook
+		}
+
+		for i := range deliveries {
+			webhook, ok := byID[deliveries[i].WebhookID]
+			if !ok {
+				continue
+			}
+			if err := s.attempt(ctx, webhook, &deliveries[i]); err != nil {
+				log.Printf("Failed to record webhook delivery %d: %v", deliveries[i].ID, err)
+			}
+			attempted++
+		}
+
+		if len(deliveries) < deliveryBatch {
+			return attempted, nil
+		}
+	}
+}
+
+// attempt posts a delivery once and records the outcome
+func (s *WebhookService) attempt(ctx context.Context, webhook models.Webhook, delivery *models.WebhookDelivery) error {
+	var attempt models.WebhookDeliveryAttempt
+	start := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "freelance-platform-webhooks/1.0")
+		req.Header.Set(webhooks.HeaderID, delivery.EventID)
+		req.Header.Set(webhooks.HeaderEvent, delivery.EventType)
+		// Integrators check this the same way we check payment provider webhooks
+		req.Header.Set(webhooks.HeaderSignature, payments.Sign(webhook.Secret, delivery.Payload, start))
+
+		var resp *http.Response
+		if resp, err = s.client.Do(req); err == nil {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, responseLogLength))
+			resp.Body.Close()
+			status := resp.StatusCode
+			attempt.StatusCode = &status
+			attempt.Response = string(body)
+		}
+	}
+	finished := time.Now()
+	attempt.DurationMS = finished.Sub(start).Milliseconds()
+
+	delivery.Attempts++
+	switch {
+	case err != nil:
+		attempt.Error = err.Error()
+	case *attempt.StatusCode < 200 || *attempt.StatusCode > 299:
+		attempt.Error = fmt.Sprintf("unexpected status %d", *attempt.StatusCode)
+	}
+
+	if attempt.Error == "" {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &finished
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = attempt.Error
+		if delivery.Attempts >= webhooks.MaxAttempts {
+			delivery.Status = models.WebhookDeliveryDead
+		} else {
+			delivery.NextAttemptAt = finished.Add(webhooks.Backoff(delivery.Attempts))
+		}
+	}
+	return s.repos.Webhooks.RecordAttempt(ctx, delivery, &attempt)
+}
+
+func (s *WebhookService) find(ctx context.Context, user models.User, id uint) (models.Webhook, error) {
+	webhook, err := s.repos.Webhooks.FindForUser(ctx, user.ID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return webhook, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+func applyWebhookInput(webhook *models.Webhook, input WebhookInput) error {
+	if input.URL != nil {
+		parsed, err := url.Parse(*input.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(*input.URL) > maxWebhookURL {
+			return ErrInvalidWebhookURL
+		}
+		webhook.URL = *input.URL
+	}
+
+	if input.Events != nil {
+		seen := make(map[string]bool)
+		subscribed := make([]string, 0, len(input.Events))
+		for _, eventType := range input.Events {
+			if !contains(WebhookEvents, eventType) {
+				return fmt.Errorf("%w: %s", ErrInvalidWebhookEvents, eventType)
+			}
+			if !seen[eventType] {
+				seen[eventType] = true
+				subscribed = append(subscribed, eventType)
+			}
+		}
+		webhook.Events = subscribed
+	}
+
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
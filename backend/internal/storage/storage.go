@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+// Storage persists uploaded files under opaque keys
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var DefaultStorage Storage
+
+// Connect selects the storage backend through STORAGE_DRIVER (local or s3)
+func Connect() {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "s3":
+		s, err := NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    os.Getenv("S3_USE_SSL") != "false",
+		})
+		if err != nil {
+			log.Fatal("Failed to initialize S3 storage:", err)
+		}
+		DefaultStorage = s
+	default:
+		uploadPath := os.Getenv("UPLOAD_PATH")
+		if uploadPath == "" {
+			uploadPath = "./uploads"
+		}
+		s, err := NewLocalStorage(uploadPath)
+		if err != nil {
+			log.Fatal("Failed to initialize local storage:", err)
+		}
+		DefaultStorage = s
+	}
+
+	log.Println("File storage ready")
+}
+
+// MaxFileSize returns the upload limit in bytes from MAX_FILE_SIZE, which accepts
+// plain bytes ("10485760") or a unit suffix ("10MB")
+func MaxFileSize() int64 {
+	const defaultSize = 10 << 20
+
+	value := strings.ToUpper(strings.TrimSpace(os.Getenv("MAX_FILE_SIZE")))
+	if value == "" {
+		return defaultSize
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		factor int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSuffix(value, unit.suffix)
+			multiplier = unit.factor
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || size <= 0 {
+		return defaultSize
+	}
+
+	return size * multiplier
+}
@@ -0,0 +1,92 @@
+// Package webhooks holds the transport side of outbound webhooks: the retry
+// schedule and an HTTP client that refuses to reach into the private network
+// the API runs in.
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderID        = "X-Webhook-Id" // the event ID, the same across redeliveries
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderSignature = "X-Webhook-Signature" // "t=<unix>,v1=<hex hmac>", as payments.Sign produces
+)
+
+// MaxAttempts is how many times a delivery is tried before it is left dead
+const MaxAttempts = 10
+
+const (
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+	timeout     = 10 * time.Second
+)
+
+var ErrPrivateAddress = errors.New("webhook address is not publicly routable")
+
+// Backoff is how long to wait after the given failed attempt, counted from 1:
+// 30s doubling each time, at most 6h
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := baseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+// NewClient returns the client deliveries are posted with. It doesn't follow
+// redirects and, unless WEBHOOK_ALLOW_PRIVATE_NETWORKS=true, refuses to
+// connect to loopback, private and link-local addresses, so a webhook can't
+// be pointed at internal services.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") != "true" {
+		dialer.Control = refusePrivate
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// refusePrivate runs after DNS resolution, so it also catches public names
+// that resolve to private addresses
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !publiclyRoutable(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+func publiclyRoutable(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS "audit_logs";
+DROP TABLE IF EXISTS "admin_actions";
+DROP TABLE IF EXISTS "recovery_codes";
+DROP TABLE IF EXISTS "user_tokens";
+DROP TABLE IF EXISTS "refresh_tokens";
+DROP TABLE IF EXISTS "payment_events";
+DROP TABLE IF EXISTS "payouts";
+DROP TABLE IF EXISTS "payments";
+DROP TABLE IF EXISTS "postings";
+DROP TABLE IF EXISTS "journal_entries";
+DROP TABLE IF EXISTS "ledger_accounts";
+DROP TABLE IF EXISTS "deliverables";
+DROP TABLE IF EXISTS "milestones";
+DROP TABLE IF EXISTS "contracts";
+DROP TABLE IF EXISTS "reviews";
+DROP TABLE IF EXISTS "attachments";
+DROP TABLE IF EXISTS "messages";
+DROP TABLE IF EXISTS "chats";
+DROP TABLE IF EXISTS "bid_revisions";
+DROP TABLE IF EXISTS "bids";
+DROP TABLE IF EXISTS "projects";
+DROP TABLE IF EXISTS "users";
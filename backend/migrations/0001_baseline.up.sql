@@ -0,0 +1,427 @@
+-- Baseline schema, equivalent to what GORM AutoMigrate created before SQL
+-- migrations. A database AutoMigrate set up with an older version of the
+-- models already has some of these tables, which CREATE TABLE IF NOT EXISTS
+-- leaves untouched, so the columns added to them since are added separately
+-- before any index needs them.
+
+-- Users
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "email" text NOT NULL,
+    "password" text NOT NULL,
+    "email_verified_at" timestamptz,
+    "two_factor_enabled" boolean DEFAULT false,
+    "totp_secret" text,
+    "totp_pending_secret" text,
+    "totp_last_step" bigint,
+    "name" text NOT NULL,
+    "avatar" text,
+    "bio" text,
+    "skills" text,
+    "role" text DEFAULT 'freelancer',
+    "status" text DEFAULT 'active',
+    "suspended_until" timestamptz,
+    "moderation_reason" text,
+    "rating" decimal DEFAULT 0,
+    "completed_projects" bigint DEFAULT 0,
+    "profession" text,
+    "experience" text,
+    "portfolio" text,
+    "hourly_rate" bigint,
+    "available" boolean DEFAULT true,
+    "city" text,
+    "website" text,
+    "linked_in" text,
+    "git_hub" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE "users"
+    ADD COLUMN IF NOT EXISTS "email_verified_at" timestamptz,
+    ADD COLUMN IF NOT EXISTS "two_factor_enabled" boolean DEFAULT false,
+    ADD COLUMN IF NOT EXISTS "totp_secret" text,
+    ADD COLUMN IF NOT EXISTS "totp_pending_secret" text,
+    ADD COLUMN IF NOT EXISTS "totp_last_step" bigint,
+    ADD COLUMN IF NOT EXISTS "status" text DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS "suspended_until" timestamptz,
+    ADD COLUMN IF NOT EXISTS "moderation_reason" text;
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_users_status" ON "users" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+
+-- Projects and bids
+
+CREATE TABLE IF NOT EXISTS "projects" (
+    "id" bigserial,
+    "title" text NOT NULL,
+    "description" text,
+    "budget_min" bigint NOT NULL,
+    "budget_max" bigint NOT NULL,
+    "currency" text DEFAULT 'TWD',
+    "category" text NOT NULL,
+    "location" text NOT NULL,
+    "skills" text,
+    "requirements" text,
+    "urgency" text DEFAULT '一般',
+    "status" text DEFAULT 'open',
+    "client_id" bigint NOT NULL,
+    "freelancer_id" bigint,
+    "deadline" timestamptz,
+    "completed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_projects" FOREIGN KEY ("client_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_projects_freelancer" FOREIGN KEY ("freelancer_id") REFERENCES "users"("id")
+);
+ALTER TABLE "projects" ADD COLUMN IF NOT EXISTS "completed_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_projects_deleted_at" ON "projects" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "bids" (
+    "id" bigserial,
+    "project_id" bigint NOT NULL,
+    "freelancer_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "proposal" text,
+    "timeline" text,
+    "status" text DEFAULT 'pending',
+    "expires_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_projects_bids" FOREIGN KEY ("project_id") REFERENCES "projects"("id"),
+    CONSTRAINT "fk_users_bids" FOREIGN KEY ("freelancer_id") REFERENCES "users"("id")
+);
+ALTER TABLE "bids" ADD COLUMN IF NOT EXISTS "expires_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_bids_deleted_at" ON "bids" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_bids_expires_at" ON "bids" ("expires_at");
+
+CREATE TABLE IF NOT EXISTS "bid_revisions" (
+    "id" bigserial,
+    "bid_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "proposal" text,
+    "timeline" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_bids_revisions" FOREIGN KEY ("bid_id") REFERENCES "bids"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_bid_revisions_bid_id" ON "bid_revisions" ("bid_id");
+
+-- Chat
+
+CREATE TABLE IF NOT EXISTS "chats" (
+    "id" bigserial,
+    "project_id" bigint NOT NULL,
+    "client_id" bigint NOT NULL,
+    "freelancer_id" bigint NOT NULL,
+    "client_hidden" boolean DEFAULT false,
+    "freelancer_hidden" boolean DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_chats_freelancer" FOREIGN KEY ("freelancer_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_chats_project" FOREIGN KEY ("project_id") REFERENCES "projects"("id"),
+    CONSTRAINT "fk_chats_client" FOREIGN KEY ("client_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_chats_deleted_at" ON "chats" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "messages" (
+    "id" bigserial,
+    "chat_id" bigint NOT NULL,
+    "sender_id" bigint NOT NULL,
+    "content" text NOT NULL,
+    "type" text DEFAULT 'text',
+    "file_url" text,
+    "read_at" timestamptz,
+    "hidden_at" timestamptz,
+    "hidden_by_id" bigint,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_messages_sender" FOREIGN KEY ("sender_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_chats_messages" FOREIGN KEY ("chat_id") REFERENCES "chats"("id")
+);
+ALTER TABLE "messages"
+    ADD COLUMN IF NOT EXISTS "hidden_at" timestamptz,
+    ADD COLUMN IF NOT EXISTS "hidden_by_id" bigint;
+CREATE INDEX IF NOT EXISTS "idx_messages_deleted_at" ON "messages" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "attachments" (
+    "id" bigserial,
+    "chat_id" bigint NOT NULL,
+    "message_id" bigint,
+    "uploader_id" bigint NOT NULL,
+    "storage_key" text NOT NULL,
+    "file_name" text NOT NULL,
+    "content_type" text NOT NULL,
+    "size" bigint NOT NULL,
+    "created_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_attachments_chat_id" ON "attachments" ("chat_id");
+CREATE INDEX IF NOT EXISTS "idx_attachments_deleted_at" ON "attachments" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_attachments_message_id" ON "attachments" ("message_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_attachments_storage_key" ON "attachments" ("storage_key");
+
+-- Reviews
+
+CREATE TABLE IF NOT EXISTS "reviews" (
+    "id" bigserial,
+    "project_id" bigint NOT NULL,
+    "reviewer_id" bigint NOT NULL,
+    "reviewee_id" bigint NOT NULL,
+    "communication" bigint NOT NULL,
+    "quality" bigint NOT NULL,
+    "timeliness" bigint NOT NULL,
+    "overall" decimal NOT NULL,
+    "comment" text,
+    "published_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_reviews_project" FOREIGN KEY ("project_id") REFERENCES "projects"("id"),
+    CONSTRAINT "fk_reviews_reviewer" FOREIGN KEY ("reviewer_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_reviews_published_at" ON "reviews" ("published_at");
+CREATE INDEX IF NOT EXISTS "idx_reviews_reviewee_id" ON "reviews" ("reviewee_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_review_project_reviewer" ON "reviews" ("project_id","reviewer_id");
+
+-- Contracts and milestones
+
+CREATE TABLE IF NOT EXISTS "contracts" (
+    "id" bigserial,
+    "project_id" bigint NOT NULL,
+    "bid_id" bigint NOT NULL,
+    "client_id" bigint NOT NULL,
+    "freelancer_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "status" text DEFAULT 'active',
+    "completed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_contracts_project" FOREIGN KEY ("project_id") REFERENCES "projects"("id"),
+    CONSTRAINT "fk_contracts_client" FOREIGN KEY ("client_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_contracts_freelancer" FOREIGN KEY ("freelancer_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_contracts_client_id" ON "contracts" ("client_id");
+CREATE INDEX IF NOT EXISTS "idx_contracts_deleted_at" ON "contracts" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_contracts_freelancer_id" ON "contracts" ("freelancer_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_contracts_project_id" ON "contracts" ("project_id");
+
+CREATE TABLE IF NOT EXISTS "milestones" (
+    "id" bigserial,
+    "contract_id" bigint NOT NULL,
+    "position" bigint NOT NULL,
+    "title" text NOT NULL,
+    "description" text,
+    "amount" bigint NOT NULL,
+    "due_date" timestamptz,
+    "status" text DEFAULT 'pending',
+    "escrow_status" text DEFAULT 'unfunded',
+    "approved_at" timestamptz,
+    "funded_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_contracts_milestones" FOREIGN KEY ("contract_id") REFERENCES "contracts"("id")
+);
+ALTER TABLE "milestones"
+    ADD COLUMN IF NOT EXISTS "escrow_status" text DEFAULT 'unfunded',
+    ADD COLUMN IF NOT EXISTS "funded_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_milestones_contract_id" ON "milestones" ("contract_id");
+CREATE INDEX IF NOT EXISTS "idx_milestones_deleted_at" ON "milestones" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "deliverables" (
+    "id" bigserial,
+    "milestone_id" bigint NOT NULL,
+    "note" text NOT NULL,
+    "file_url" text,
+    "status" text DEFAULT 'submitted',
+    "feedback" text,
+    "reviewed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_milestones_deliverables" FOREIGN KEY ("milestone_id") REFERENCES "milestones"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_deliverables_milestone_id" ON "deliverables" ("milestone_id");
+
+-- Ledger
+
+CREATE TABLE IF NOT EXISTS "ledger_accounts" (
+    "id" bigserial,
+    "code" text NOT NULL,
+    "name" text NOT NULL,
+    "type" text NOT NULL,
+    "owner_id" bigint,
+    "currency" text DEFAULT 'TWD',
+    "balance" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_ledger_accounts_owner_id" ON "ledger_accounts" ("owner_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_ledger_accounts_code" ON "ledger_accounts" ("code");
+
+CREATE TABLE IF NOT EXISTS "journal_entries" (
+    "id" bigserial,
+    "kind" text NOT NULL,
+    "reference" text NOT NULL,
+    "milestone_id" bigint,
+    "description" text,
+    "posted_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_journal_entries_kind" ON "journal_entries" ("kind");
+CREATE INDEX IF NOT EXISTS "idx_journal_entries_milestone_id" ON "journal_entries" ("milestone_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_journal_entries_reference" ON "journal_entries" ("reference");
+
+CREATE TABLE IF NOT EXISTS "postings" (
+    "id" bigserial,
+    "entry_id" bigint NOT NULL,
+    "account_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_journal_entries_postings" FOREIGN KEY ("entry_id") REFERENCES "journal_entries"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_postings_account_id" ON "postings" ("account_id");
+CREATE INDEX IF NOT EXISTS "idx_postings_entry_id" ON "postings" ("entry_id");
+
+-- Payments
+
+CREATE TABLE IF NOT EXISTS "payments" (
+    "id" bigserial,
+    "milestone_id" bigint NOT NULL,
+    "client_id" bigint NOT NULL,
+    "provider" text NOT NULL,
+    "provider_intent_id" text,
+    "amount" bigint NOT NULL,
+    "currency" text DEFAULT 'TWD',
+    "status" text DEFAULT 'requires_confirmation',
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_payments_client_id" ON "payments" ("client_id");
+CREATE INDEX IF NOT EXISTS "idx_payments_milestone_id" ON "payments" ("milestone_id");
+CREATE INDEX IF NOT EXISTS "idx_payments_provider_intent_id" ON "payments" ("provider_intent_id");
+
+CREATE TABLE IF NOT EXISTS "payouts" (
+    "id" bigserial,
+    "freelancer_id" bigint NOT NULL,
+    "provider" text NOT NULL,
+    "provider_payout_id" text,
+    "destination" text NOT NULL,
+    "amount" bigint NOT NULL,
+    "status" text DEFAULT 'pending',
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_payouts_freelancer_id" ON "payouts" ("freelancer_id");
+CREATE INDEX IF NOT EXISTS "idx_payouts_provider_payout_id" ON "payouts" ("provider_payout_id");
+
+CREATE TABLE IF NOT EXISTS "payment_events" (
+    "id" bigserial,
+    "provider" text NOT NULL,
+    "event_id" text NOT NULL,
+    "type" text,
+    "payload" text,
+    "processed_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payment_event" ON "payment_events" ("provider","event_id");
+
+-- Sessions and account security
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "family_id" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_at" timestamptz,
+    "replaced_by_id" bigint,
+    "user_agent" text,
+    "ip" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_user_id" ON "refresh_tokens" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_refresh_tokens_token_hash" ON "refresh_tokens" ("token_hash");
+
+CREATE TABLE IF NOT EXISTS "user_tokens" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "purpose" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_user_tokens_purpose" ON "user_tokens" ("purpose");
+CREATE INDEX IF NOT EXISTS "idx_user_tokens_user_id" ON "user_tokens" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_tokens_token_hash" ON "user_tokens" ("token_hash");
+
+CREATE TABLE IF NOT EXISTS "recovery_codes" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "code_hash" text NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_recovery_codes_code_hash" ON "recovery_codes" ("code_hash");
+CREATE INDEX IF NOT EXISTS "idx_recovery_codes_user_id" ON "recovery_codes" ("user_id");
+
+-- Moderation and audit
+
+CREATE TABLE IF NOT EXISTS "admin_actions" (
+    "id" bigserial,
+    "admin_id" bigint NOT NULL,
+    "action" text NOT NULL,
+    "target_type" text NOT NULL,
+    "target_id" bigint NOT NULL,
+    "reason" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_admin_actions_admin" FOREIGN KEY ("admin_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_admin_action_target" ON "admin_actions" ("target_type","target_id");
+CREATE INDEX IF NOT EXISTS "idx_admin_actions_action" ON "admin_actions" ("action");
+CREATE INDEX IF NOT EXISTS "idx_admin_actions_admin_id" ON "admin_actions" ("admin_id");
+CREATE INDEX IF NOT EXISTS "idx_admin_actions_created_at" ON "admin_actions" ("created_at");
+
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" bigserial,
+    "actor_id" bigint,
+    "action" text NOT NULL,
+    "target_type" text NOT NULL,
+    "target_id" bigint NOT NULL,
+    "changes" jsonb NOT NULL DEFAULT '{}',
+    "ip" text,
+    "user_agent" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_audit_logs_actor" FOREIGN KEY ("actor_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_action" ON "audit_logs" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_actor_id" ON "audit_logs" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_created_at" ON "audit_logs" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_target" ON "audit_logs" ("target_type","target_id");
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+DROP TRIGGER IF EXISTS postings_append_only ON postings;
+DROP TRIGGER IF EXISTS journal_entries_append_only ON journal_entries;
+DROP FUNCTION IF EXISTS reject_append_only_change();
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS "idx_projects_skills_trgm";
+DROP INDEX IF EXISTS "idx_projects_description_trgm";
+DROP INDEX IF EXISTS "idx_projects_title_trgm";
+DROP INDEX IF EXISTS "idx_projects_search_vector";
+
+DROP TRIGGER IF EXISTS projects_search_vector ON projects;
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "search_vector";
+
+DROP FUNCTION IF EXISTS projects_search_vector_update();
+DROP FUNCTION IF EXISTS project_search_vector(text, text, text);
+DROP FUNCTION IF EXISTS cjk_bigrams(text);
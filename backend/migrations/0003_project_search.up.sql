@@ -0,0 +1,56 @@
+-- Full-text search over projects. The default parser keeps a run of Chinese
+-- characters as one word, so CJK runs are split into overlapping bigrams
+-- before they reach to_tsvector; search terms go through the same function.
+-- pg_trgm backs the substring fallback for terms bigrams can't match, such as
+-- single characters and partial words.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public;
+
+CREATE OR REPLACE FUNCTION cjk_bigrams(input text) RETURNS text AS $$
+DECLARE
+    tokens text[] := '{}';
+    part text;
+    i int;
+BEGIN
+    FOR part IN
+        SELECT (regexp_matches(coalesce(input, ''),
+            '[\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff]+|[^\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff]+', 'g'))[1]
+    LOOP
+        IF part !~ '^[\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff]' OR char_length(part) = 1 THEN
+            tokens := tokens || part;
+        ELSE
+            FOR i IN 1 .. char_length(part) - 1 LOOP
+                tokens := tokens || substr(part, i, 2);
+            END LOOP;
+        END IF;
+    END LOOP;
+    RETURN array_to_string(tokens, ' ');
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Title matches count most, then skills, then the description
+CREATE OR REPLACE FUNCTION project_search_vector(title text, skills text, description text) RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('simple', cjk_bigrams(title)), 'A') ||
+           setweight(to_tsvector('simple', cjk_bigrams(skills)), 'B') ||
+           setweight(to_tsvector('simple', cjk_bigrams(description)), 'C');
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION projects_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := project_search_vector(NEW.title, NEW.skills, NEW.description);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE "projects" ADD COLUMN "search_vector" tsvector;
+
+UPDATE "projects" SET "search_vector" = project_search_vector("title", "skills", "description");
+
+CREATE TRIGGER projects_search_vector
+    BEFORE INSERT OR UPDATE OF title, skills, description ON projects
+    FOR EACH ROW EXECUTE FUNCTION projects_search_vector_update();
+
+CREATE INDEX IF NOT EXISTS "idx_projects_search_vector" ON "projects" USING gin ("search_vector");
+CREATE INDEX IF NOT EXISTS "idx_projects_title_trgm" ON "projects" USING gin ("title" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "idx_projects_description_trgm" ON "projects" USING gin ("description" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "idx_projects_skills_trgm" ON "projects" USING gin ("skills" gin_trgm_ops);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS "notifications" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "type" text NOT NULL,
+    "title" text NOT NULL,
+    "body" text,
+    "actor_id" bigint,
+    "project_id" bigint,
+    "bid_id" bigint,
+    "chat_id" bigint,
+    "read_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_notifications_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+-- Listing pages through a user's notifications newest first; the badge only
+-- counts unread ones
+CREATE INDEX IF NOT EXISTS "idx_notifications_user_created" ON "notifications" ("user_id", "created_at" DESC, "id" DESC);
+CREATE INDEX IF NOT EXISTS "idx_notifications_user_unread" ON "notifications" ("user_id") WHERE "read_at" IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "idx_notifications_email_pending";
+ALTER TABLE "notifications" DROP COLUMN IF EXISTS "emailed_at";
+ALTER TABLE "notifications" DROP COLUMN IF EXISTS "send_email";
+DROP TABLE IF EXISTS "notification_settings";
+DROP TABLE IF EXISTS "notification_preferences";
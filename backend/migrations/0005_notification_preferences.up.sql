@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS "notification_preferences" (
+    "user_id" bigint NOT NULL,
+    "event_type" text NOT NULL,
+    "channel" text NOT NULL,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("user_id", "event_type"),
+    CONSTRAINT "fk_notification_preferences_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "chk_notification_preferences_channel" CHECK ("channel" IN ('in_app', 'email', 'none'))
+);
+
+-- The digest job looks up everyone who asked for project matches by email
+CREATE INDEX IF NOT EXISTS "idx_notification_preferences_event_channel" ON "notification_preferences" ("event_type", "channel");
+
+CREATE TABLE IF NOT EXISTS "notification_settings" (
+    "user_id" bigint NOT NULL,
+    "locale" text NOT NULL DEFAULT 'zh-TW',
+    "quiet_hours_start" smallint,
+    "quiet_hours_end" smallint,
+    "last_digest_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("user_id"),
+    CONSTRAINT "fk_notification_settings_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+ALTER TABLE "notifications" ADD COLUMN IF NOT EXISTS "send_email" boolean NOT NULL DEFAULT false;
+ALTER TABLE "notifications" ADD COLUMN IF NOT EXISTS "emailed_at" timestamptz;
+
+-- Notifications still waiting to go out by email
+CREATE INDEX IF NOT EXISTS "idx_notifications_email_pending" ON "notifications" ("user_id", "id")
+    WHERE "send_email" AND "emailed_at" IS NULL AND "read_at" IS NULL;
@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS "webhooks" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "url" text NOT NULL,
+    "secret" text NOT NULL,
+    "events" jsonb NOT NULL DEFAULT '[]',
+    "active" boolean NOT NULL DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_webhooks_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhooks_user_id" ON "webhooks" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "id" bigserial,
+    "webhook_id" bigint NOT NULL,
+    "event_id" text NOT NULL,
+    "event_type" text NOT NULL,
+    "payload" jsonb NOT NULL,
+    "status" text NOT NULL DEFAULT 'pending',
+    "attempts" integer NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz NOT NULL,
+    "last_error" text,
+    "delivered_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_webhook_deliveries_webhook" FOREIGN KEY ("webhook_id") REFERENCES "webhooks"("id") ON DELETE CASCADE,
+    CONSTRAINT "chk_webhook_deliveries_status" CHECK ("status" IN ('pending', 'succeeded', 'dead'))
+);
+
+-- The delivery log pages through a webhook's deliveries newest first; the
+-- dispatcher only looks at pending ones that are due
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_webhook_created" ON "webhook_deliveries" ("webhook_id", "created_at" DESC, "id" DESC);
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_due" ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+
+CREATE TABLE IF NOT EXISTS "webhook_delivery_attempts" (
+    "id" bigserial,
+    "delivery_id" bigint NOT NULL,
+    "status_code" integer,
+    "response" text,
+    "error" text,
+    "duration_ms" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_webhook_delivery_attempts_delivery" FOREIGN KEY ("delivery_id") REFERENCES "webhook_deliveries"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_webhook_delivery_attempts_delivery_id" ON "webhook_delivery_attempts" ("delivery_id");
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS "jobs" (
+    "id" bigserial,
+    "type" text NOT NULL,
+    "payload" jsonb NOT NULL DEFAULT '{}',
+    "unique_key" text,
+    "status" text NOT NULL DEFAULT 'queued',
+    "attempts" integer NOT NULL DEFAULT 0,
+    "max_attempts" integer NOT NULL,
+    "run_at" timestamptz NOT NULL,
+    "locked_by" text,
+    "locked_until" timestamptz,
+    "last_error" text,
+    "finished_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "chk_jobs_status" CHECK ("status" IN ('queued', 'running', 'succeeded', 'dead'))
+);
+
+-- Workers pick queued jobs that are due and running jobs whose lock expired
+CREATE INDEX IF NOT EXISTS "idx_jobs_queued_run_at" ON "jobs" ("run_at") WHERE "status" = 'queued';
+CREATE INDEX IF NOT EXISTS "idx_jobs_running_locked_until" ON "jobs" ("locked_until") WHERE "status" = 'running';
+
+-- A unique key only blocks duplicates while the job is still to be done
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_jobs_unique_key_active" ON "jobs" ("unique_key") WHERE "status" IN ('queued', 'running');
+CREATE INDEX IF NOT EXISTS "idx_jobs_unique_key" ON "jobs" ("unique_key");
+
+-- Pruning finished jobs
+CREATE INDEX IF NOT EXISTS "idx_jobs_finished_at" ON "jobs" ("finished_at") WHERE "finished_at" IS NOT NULL;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS "outbox_events" (
+    "id" bigserial,
+    "event_id" text NOT NULL,
+    "type" text NOT NULL,
+    "actor_id" bigint,
+    "payload" jsonb NOT NULL DEFAULT '{}',
+    "occurred_at" timestamptz NOT NULL,
+    "attempts" integer NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz NOT NULL,
+    "last_error" text,
+    "published_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_outbox_events_event_id" ON "outbox_events" ("event_id");
+
+-- The relay picks unpublished events in the order they were written
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_pending" ON "outbox_events" ("next_attempt_at", "id") WHERE "published_at" IS NULL;
+
+-- Pruning published events
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_published_at" ON "outbox_events" ("published_at") WHERE "published_at" IS NOT NULL;
+
+-- Events each consumer has already handled, so a redelivered event is skipped
+CREATE TABLE IF NOT EXISTS "processed_events" (
+    "consumer" text NOT NULL,
+    "event_id" text NOT NULL,
+    "processed_at" timestamptz NOT NULL,
+    PRIMARY KEY ("consumer", "event_id")
+);
+
+CREATE INDEX IF NOT EXISTS "idx_processed_events_processed_at" ON "processed_events" ("processed_at");
@@ -0,0 +1,2 @@
+-- The backfilled accounts can't be told apart from verified ones; nothing to undo
+SELECT 1;
@@ -0,0 +1,13 @@
+-- Accounts created before email verification existed were never asked to
+-- verify; treat them as verified from the day they signed up. Accounts that
+-- were sent a verification link, or are still waiting for one, are left alone.
+UPDATE "users" SET "email_verified_at" = COALESCE("created_at", now())
+WHERE "email_verified_at" IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM "user_tokens"
+    WHERE "user_tokens"."user_id" = "users"."id" AND "user_tokens"."purpose" = 'verify_email'
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM "jobs"
+    WHERE "jobs"."unique_key" = 'email.verify:' || "users"."id"
+  );
@@ -0,0 +1,2 @@
+ALTER TABLE "users" DROP COLUMN IF EXISTS "second_factor_locked_until";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "second_factor_failures";
@@ -0,0 +1,5 @@
+-- Consecutive wrong second factors, and how long the account stays locked
+-- out of the second step once there were too many
+ALTER TABLE "users"
+    ADD COLUMN IF NOT EXISTS "second_factor_failures" integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "second_factor_locked_until" timestamptz;
@@ -0,0 +1,11 @@
+// Package migrations holds the numbered SQL migrations, embedded so the API
+// and cmd/migrate binaries carry their own schema.
+//
+// Each migration is a pair of files, NNNN_name.up.sql and NNNN_name.down.sql.
+// Create new ones with `go run ./cmd/migrate create NAME`.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -4,7 +4,9 @@ import { User, authService } from '../services/auth';
 interface AuthContextType {
   user: User | null;
   loading: boolean;
-  login: (email: string, password: string) => Promise<void>;
+  // Resolves to an MFA token when the account needs a second factor to finish logging in
+  login: (email: string, password: string) => Promise<string | null>;
+  loginTwoFactor: (mfaToken: string, code: string) => Promise<void>;
   logout: () => Promise<void>;
   updateUser: (userData: Partial<User>) => void;
   refreshUser: () => Promise<void>;
@@ -45,6 +47,15 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
 
   const login = async (email: string, password: string) => {
     const response = await authService.login({ email, password });
+    if ('mfa_required' in response) {
+      return response.mfa_token;
+    }
+    setUser(response.user);
+    return null;
+  };
+
+  const loginTwoFactor = async (mfaToken: string, code: string) => {
+    const response = await authService.loginTwoFactor(mfaToken, code);
     setUser(response.user);
   };
 
@@ -75,6 +86,7 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
     user,
     loading,
     login,
+    loginTwoFactor,
     logout,
     updateUser,
     refreshUser,
@@ -3,12 +3,14 @@ import { Link, useNavigate } from 'react-router-dom';
 import { useAuth } from '../contexts/AuthContext';
 
 const LoginPage: React.FC = () => {
-  const { login, user } = useAuth();
+  const { login, loginTwoFactor, user } = useAuth();
   const navigate = useNavigate();
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState('');
+  const [mfaToken, setMfaToken] = useState<string | null>(null);
+  const [code, setCode] = useState('');
 
   // Redirect after successful login
   useEffect(() => {
@@ -48,7 +50,7 @@ const LoginPage: React.FC = () => {
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     
-    if (!validateForm()) {
+    if (!mfaToken && !validateForm()) {
       return;
     }
 
@@ -56,7 +58,15 @@ const LoginPage: React.FC = () => {
     setError('');
 
     try {
-      await login(email.trim().toLowerCase(), password);
+      if (mfaToken) {
+        await loginTwoFactor(mfaToken, code.trim());
+      } else {
+        const pendingToken = await login(email.trim().toLowerCase(), password);
+        if (pendingToken) {
+          setMfaToken(pendingToken);
+          return;
+        }
+      }
       
       // Note: user will be updated in context after login
       // We'll redirect in useEffect when user changes
@@ -64,7 +74,13 @@ const LoginPage: React.FC = () => {
       console.error('登入錯誤:', err);
       
       // Handle different types of errors
-      if (err.response?.status === 401) {
+      if (err.response?.status === 401 && mfaToken) {
+        setError(err.response.data?.error === 'Invalid two-factor code' ? '驗證碼錯誤' : '驗證逾時，請重新登入');
+        if (err.response.data?.error !== 'Invalid two-factor code') {
+          setMfaToken(null);
+          setCode('');
+        }
+      } else if (err.response?.status === 401) {
         setError('電子信箱或密碼錯誤');
       } else if (err.response?.status === 400) {
         setError(err.response.data?.error || '登入資料無效');
@@ -99,37 +115,59 @@ const LoginPage: React.FC = () => {
         )}
         
         <form onSubmit={handleSubmit}>
-          <div className="mb-4">
-            <label htmlFor="email" className="block text-sm font-medium text-gray-700 mb-2">
-              電子信箱
-            </label>
-            <input
-              type="email"
-              id="email"
-              value={email}
-              onChange={handleInputChange(setEmail)}
-              className="input-field"
-              required
-              disabled={loading}
-              placeholder="請輸入您的電子信箱"
-            />
-          </div>
+          {mfaToken ? (
+            <div className="mb-6">
+              <label htmlFor="code" className="block text-sm font-medium text-gray-700 mb-2">
+                兩步驟驗證碼
+              </label>
+              <input
+                type="text"
+                id="code"
+                value={code}
+                onChange={handleInputChange(setCode)}
+                className="input-field"
+                required
+                autoFocus
+                autoComplete="one-time-code"
+                disabled={loading}
+                placeholder="請輸入驗證器 App 上的 6 位數驗證碼或備用碼"
+              />
+            </div>
+          ) : (
+            <>
+              <div className="mb-4">
+                <label htmlFor="email" className="block text-sm font-medium text-gray-700 mb-2">
+                  電子信箱
+                </label>
+                <input
+                  type="email"
+                  id="email"
+                  value={email}
+                  onChange={handleInputChange(setEmail)}
+                  className="input-field"
+                  required
+                  disabled={loading}
+                  placeholder="請輸入您的電子信箱"
+                />
+              </div>
           
-          <div className="mb-6">
-            <label htmlFor="password" className="block text-sm font-medium text-gray-700 mb-2">
-              密碼
-            </label>
-            <input
-              type="password"
-              id="password"
-              value={password}
-              onChange={handleInputChange(setPassword)}
-              className="input-field"
-              required
-              disabled={loading}
-              placeholder="請輸入您的密碼"
-            />
-          </div>
+              <div className="mb-6">
+                <label htmlFor="password" className="block text-sm font-medium text-gray-700 mb-2">
+                  密碼
+                </label>
+                <input
+                  type="password"
+                  id="password"
+                  value={password}
+                  onChange={handleInputChange(setPassword)}
+                  className="input-field"
+                  required
+                  disabled={loading}
+                  placeholder="請輸入您的密碼"
+                />
+              </div>
+            </>
+          )}
           
           <button 
             type="submit" 
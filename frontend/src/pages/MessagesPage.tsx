@@ -19,20 +19,27 @@ const MessagesPage: React.FC = () => {
   const [showDeleteModal, setShowDeleteModal] = useState(false);
   const [chatToDelete, setChatToDelete] = useState<Chat | null>(null);
   const [deleteLoading, setDeleteLoading] = useState(false);
+  const [chatsCursor, setChatsCursor] = useState<string | undefined>();
+  const [loadingMoreChats, setLoadingMoreChats] = useState(false);
+  const [olderMessagesCursor, setOlderMessagesCursor] = useState<string | undefined>();
+  const [loadingOlderMessages, setLoadingOlderMessages] = useState(false);
   
   const pollingIntervalRef = useRef<NodeJS.Timeout | null>(null);
   const messagesEndRef = useRef<HTMLDivElement>(null);
   const chatListPollingRef = useRef<NodeJS.Timeout | null>(null);
+  const moreChatsLoadedRef = useRef(false); // Set once the user pages past the first page of chats
+  const messagesChatIdRef = useRef<number | null>(null); // The chat whose messages are loaded
 
   // Auto-scroll to bottom function
   const scrollToBottom = () => {
     messagesEndRef.current?.scrollIntoView({ behavior: 'smooth' });
   };
 
-  // Scroll to bottom when messages change
+  // Scroll to bottom when a new message arrives, not when older ones are loaded above
+  const lastMessageId = messages.length > 0 ? messages[messages.length - 1].id : null;
   useEffect(() => {
     scrollToBottom();
-  }, [messages]);
+  }, [lastMessageId]);
 
   // Get URL parameters for direct chat initialization
   const searchParams = new URLSearchParams(location.search);
@@ -47,7 +54,18 @@ const MessagesPage: React.FC = () => {
       }
       
       const response = await authService.getChats();
-      setChats(response.chats || []); // Ensure chats is never null
+      const firstPage = response.chats || []; // Ensure chats is never null
+
+      if (moreChatsLoadedRef.current) {
+        // Keep the older pages the user loaded; chats with new activity moved into the first page
+        setChats(prev => {
+          const refreshed = new Set(firstPage.map(chat => chat.id));
+          return [...firstPage, ...prev.filter(chat => !refreshed.has(chat.id))];
+        });
+      } else {
+        setChats(firstPage);
+        setChatsCursor(response.pagination?.has_more ? response.pagination.next_cursor : undefined);
+      }
       
     } catch (error) {
       console.error('Failed to load chats:', error);
@@ -59,13 +77,47 @@ const MessagesPage: React.FC = () => {
     }
   }, []); // Remove all dependencies to prevent circular updates
 
-  const loadMessages = async (chatId: number) => {
+  const loadMoreChats = async () => {
+    if (!chatsCursor || loadingMoreChats) return;
+
+    try {
+      setLoadingMoreChats(true);
+      const response = await authService.getChats(chatsCursor);
+      const nextPage = response.chats || [];
+
+      moreChatsLoadedRef.current = true;
+      setChats(prev => [...prev, ...nextPage.filter(chat => !prev.some(loaded => loaded.id === chat.id))]);
+      setChatsCursor(response.pagination?.has_more ? response.pagination.next_cursor : undefined);
+    } catch (error) {
+      console.error('Failed to load more chats:', error);
+      showError('載入失敗，請稍後再試');
+    } finally {
+      setLoadingMoreChats(false);
+    }
+  };
+
+  // Loads the newest page of messages; firstLoad starts over for a newly selected chat
+  const loadMessages = async (chatId: number, firstLoad = false) => {
     try {
       const response = await authService.getChatMessages(chatId);
+      if (messagesChatIdRef.current !== chatId) return; // Another chat was selected meanwhile
+
+      if (firstLoad) {
+        setOlderMessagesCursor(response.pagination?.has_more ? response.pagination.next_cursor : undefined);
+      }
       
       // Update messages, but preserve optimistic messages that haven't been confirmed yet
       setMessages(prevMessages => {
         const serverMessages = response.messages || []; // Ensure messages is never null
+
+        // Keep the older messages the user loaded above the newest page
+        const oldestServerTime = Math.min(...serverMessages.map(msg => new Date(msg.created_at).getTime()));
+        const olderMessages = firstLoad ? [] : prevMessages.filter(msg =>
+          msg.chat_id === chatId &&
+          msg.id < 1000000000000 &&
+          !serverMessages.some(server => server.id === msg.id) &&
+          new Date(msg.created_at).getTime() <= oldestServerTime
+        );
         const optimisticMessages = prevMessages.filter(msg => 
           typeof msg.id === 'number' && msg.id > 1000000000000 // Optimistic messages have timestamp IDs
         );
@@ -79,15 +131,40 @@ const MessagesPage: React.FC = () => {
           )
         );
         
-        // Combine server messages with remaining optimistic messages
-        const combined = [...serverMessages, ...filteredOptimistic];
+        // Combine server messages with older pages and remaining optimistic messages
+        const combined = [...olderMessages, ...serverMessages, ...filteredOptimistic];
         
         // Sort by creation time
         return combined.sort((a, b) => new Date(a.created_at).getTime() - new Date(b.created_at).getTime());
       });
     } catch (error) {
       console.error('Failed to load messages:', error);
-      setMessages([]); // Set to empty array on error
+      if (firstLoad) {
+        setMessages([]); // Set to empty array on error
+      }
+    }
+  };
+
+  const loadOlderMessages = async () => {
+    if (!selectedChat || !olderMessagesCursor || loadingOlderMessages) return;
+
+    const chatId = selectedChat.id;
+    try {
+      setLoadingOlderMessages(true);
+      const response = await authService.getChatMessages(chatId, olderMessagesCursor);
+      if (messagesChatIdRef.current !== chatId) return;
+
+      const olderPage = response.messages || [];
+      setMessages(prev => {
+        const combined = [...olderPage.filter(older => !prev.some(msg => msg.id === older.id)), ...prev];
+        return combined.sort((a, b) => new Date(a.created_at).getTime() - new Date(b.created_at).getTime());
+      });
+      setOlderMessagesCursor(response.pagination?.has_more ? response.pagination.next_cursor : undefined);
+    } catch (error) {
+      console.error('Failed to load older messages:', error);
+      showError('載入失敗，請稍後再試');
+    } finally {
+      setLoadingOlderMessages(false);
     }
   };
 
@@ -104,9 +181,8 @@ const MessagesPage: React.FC = () => {
         // This prevents duplicates and ensures consistency
         await loadChats(false);
         
-        // Select the chat
+        // Select the chat; its messages load once it is selected
         setSelectedChat(response.chat);
-        await loadMessages(response.chat.id);
       }
     } catch (error) {
       console.error('Failed to initialize chat:', error);
@@ -170,8 +246,13 @@ const MessagesPage: React.FC = () => {
 
     // Start polling if a chat is selected
     if (selectedChat) {
-      // Load messages immediately
-      loadMessages(selectedChat.id);
+      // Load messages immediately, starting over when a different chat was selected
+      const firstLoad = messagesChatIdRef.current !== selectedChat.id;
+      if (firstLoad) {
+        messagesChatIdRef.current = selectedChat.id;
+        setOlderMessagesCursor(undefined);
+      }
+      loadMessages(selectedChat.id, firstLoad);
       
       // Mark messages as read
       markChatAsRead(selectedChat.id);
@@ -280,6 +361,7 @@ const MessagesPage: React.FC = () => {
       if (selectedChat?.id === chat.id) {
         setSelectedChat(null);
         setMessages([]);
+        messagesChatIdRef.current = null;
       }
       
       // Close modal and reset state
@@ -380,6 +462,15 @@ const MessagesPage: React.FC = () => {
                   </div>
                 );
               })}
+              {chatsCursor && (
+                <button
+                  onClick={loadMoreChats}
+                  disabled={loadingMoreChats}
+                  className="w-full p-3 text-sm text-blue-600 hover:bg-gray-50 disabled:opacity-50 disabled:cursor-not-allowed"
+                >
+                  {loadingMoreChats ? '載入中...' : '載入更多對話'}
+                </button>
+              )}
             </div>
           )}
         </div>
@@ -430,6 +521,17 @@ const MessagesPage: React.FC = () => {
 
             {/* Messages */}
             <div className="flex-1 overflow-y-auto p-4 space-y-4">
+              {olderMessagesCursor && (
+                <div className="flex justify-center">
+                  <button
+                    onClick={loadOlderMessages}
+                    disabled={loadingOlderMessages}
+                    className="text-blue-600 hover:text-blue-700 text-sm px-3 py-1 rounded-md hover:bg-blue-50 transition-colors disabled:opacity-50 disabled:cursor-not-allowed"
+                  >
+                    {loadingOlderMessages ? '載入中...' : '載入較早的訊息'}
+                  </button>
+                </div>
+              )}
               {messages.map(message => {
                 // Handle system messages differently
                 if (message.type === 'system') {
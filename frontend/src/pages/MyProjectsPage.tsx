@@ -71,12 +71,21 @@ const MyProjectsPage: React.FC = () => {
       setLoading(true);
       setError('');
 
-      // Get all projects for the current user (not just open ones)
-      const response = await authService.getProjects({ 
-        limit: 100,
-        my_projects: true
-      });
-      const myProjects = response.projects.filter(project => project.client_id === user?.id);
+      // Get all projects for the current user (not just open ones), following
+      // next_cursor until the last page so nothing past the page limit is missed
+      const allProjects: Project[] = [];
+      let cursor: string | undefined;
+      do {
+        const response = await authService.getProjects({
+          limit: 100,
+          my_projects: true,
+          cursor
+        });
+        allProjects.push(...response.projects);
+        cursor = response.pagination?.has_more ? response.pagination.next_cursor : undefined;
+      } while (cursor);
+
+      const myProjects = allProjects.filter(project => project.client_id === user?.id);
       setProjects(myProjects);
     } catch (err: any) {
       console.error('Failed to load projects:', err);
@@ -18,12 +18,44 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
+// Exchange the refresh token for a new token pair; concurrent 401s share one request
+let refreshing: Promise<string> | null = null;
+
+const refreshToken = (): Promise<string> => {
+  if (!refreshing) {
+    const token = localStorage.getItem('refresh_token');
+    refreshing = (token
+      ? axios.post(`${API_BASE_URL}/auth/refresh`, { refresh_token: token }).then((response) => {
+          localStorage.setItem('token', response.data.token);
+          localStorage.setItem('refresh_token', response.data.refresh_token);
+          return response.data.token as string;
+        })
+      : Promise.reject(new Error('No refresh token'))
+    ).finally(() => {
+      refreshing = null;
+    });
+  }
+  return refreshing;
+};
+
 // Handle auth errors
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    const original = error.config;
+    if (error.response?.status === 401 && original && !original._retry && !['/auth/login', '/auth/login/2fa', '/auth/register'].includes(original.url)) {
+      original._retry = true;
+      try {
+        const token = await refreshToken();
+        original.headers.Authorization = `Bearer ${token}`;
+        return api(original);
+      } catch {
+        // fall through to logout
+      }
+    }
     if (error.response?.status === 401) {
       localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
       window.location.href = '/login';
     }
     return Promise.reject(error);
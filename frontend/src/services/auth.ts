@@ -5,6 +5,7 @@ export interface User {
   email: string;
   name: string;
   role: string; // 'freelancer' (接案者) or 'client' (發案者)
+  email_verified_at?: string | null;
   avatar?: string;
   bio?: string; // Self introduction
   skills?: string; // JSON array of skills for freelancers
@@ -27,6 +28,14 @@ export interface User {
 export interface AuthResponse {
   user: User;
   token: string;
+  refresh_token?: string;
+}
+
+// Returned by login instead of tokens when the account has 2FA enabled
+export interface MFAChallenge {
+  mfa_required: true;
+  mfa_token: string;
+  expires_in: number;
 }
 
 export interface LoginRequest {
@@ -100,11 +109,19 @@ export interface ProjectFilters {
   min_budget?: number;
   max_budget?: number;
   urgency?: string;
-  page?: number;
+  cursor?: string;
   limit?: number;
+  total?: boolean;
   my_projects?: boolean;
 }
 
+// Lists are paged with opaque cursors: pass next_cursor back as cursor for the following page
+export interface Pagination {
+  next_cursor?: string;
+  has_more: boolean;
+  total?: number;
+}
+
 export interface CreateBidRequest {
   project_id: number;
   amount: number;
@@ -178,7 +195,7 @@ export interface SendMessageRequest {
 let currentUser: User | null = null;
 
 const projectService = {
-  async getProjects(filters: ProjectFilters = {}): Promise<{ projects: Project[] }> {
+  async getProjects(filters: ProjectFilters = {}): Promise<{ projects: Project[]; pagination: Pagination }> {
     const params = new URLSearchParams();
     
     Object.entries(filters).forEach(([key, value]) => {
@@ -228,8 +245,8 @@ const projectService = {
 };
 
 const chatService = {
-  async getChats(): Promise<{ chats: Chat[] }> {
-    const response = await api.get('/chats');
+  async getChats(cursor?: string): Promise<{ chats: Chat[]; pagination: Pagination }> {
+    const response = await api.get('/chats', { params: { cursor } });
     return response.data;
   },
 
@@ -238,8 +255,9 @@ const chatService = {
     return response.data;
   },
 
-  async getChatMessages(chatId: number): Promise<{ messages: Message[] }> {
-    const response = await api.get(`/chats/${chatId}/messages`);
+  // Starts with the newest messages; next_cursor loads older ones
+  async getChatMessages(chatId: number, cursor?: string): Promise<{ messages: Message[]; pagination: Pagination }> {
+    const response = await api.get(`/chats/${chatId}/messages`, { params: { cursor, limit: 100 } });
     return response.data;
   },
 
@@ -265,26 +283,72 @@ const chatService = {
 };
 
 const authService = {
-  async login(credentials: LoginRequest): Promise<AuthResponse> {
+  async login(credentials: LoginRequest): Promise<AuthResponse | MFAChallenge> {
     const response = await api.post('/auth/login', credentials);
-    const { user, token } = response.data;
+    if (response.data.mfa_required) {
+      return response.data as MFAChallenge;
+    }
+    const { user, token, refresh_token } = response.data;
     localStorage.setItem('token', token);
+    localStorage.setItem('refresh_token', refresh_token);
     currentUser = user;
-    return { user, token };
+    return { user, token, refresh_token };
+  },
+
+  async loginTwoFactor(mfaToken: string, code: string): Promise<AuthResponse> {
+    const response = await api.post('/auth/login/2fa', { mfa_token: mfaToken, code });
+    const { user, token, refresh_token } = response.data;
+    localStorage.setItem('token', token);
+    localStorage.setItem('refresh_token', refresh_token);
+    currentUser = user;
+    return { user, token, refresh_token };
   },
 
   async register(userData: RegisterRequest): Promise<AuthResponse> {
     const response = await api.post('/auth/register', userData);
-    const { user, token } = response.data;
+    const { user, token, refresh_token } = response.data;
     localStorage.setItem('token', token);
+    localStorage.setItem('refresh_token', refresh_token);
     currentUser = user;
-    return { user, token };
+    return { user, token, refresh_token };
   },
 
   async logout(): Promise<void> {
-    await api.post('/auth/logout');
-    localStorage.removeItem('token');
-    currentUser = null;
+    try {
+      await api.post('/auth/logout');
+    } finally {
+      localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
+      currentUser = null;
+    }
+  },
+
+  async logoutAll(): Promise<void> {
+    try {
+      await api.post('/auth/logout-all');
+    } finally {
+      localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
+      currentUser = null;
+    }
+  },
+
+  async verifyEmail(token: string): Promise<User> {
+    const response = await api.post('/auth/verify-email', { token });
+    currentUser = response.data.user;
+    return response.data.user;
+  },
+
+  async resendVerificationEmail(): Promise<void> {
+    await api.post('/auth/verify-email/resend');
+  },
+
+  async forgotPassword(email: string): Promise<void> {
+    await api.post('/auth/forgot-password', { email });
+  },
+
+  async resetPassword(token: string, password: string): Promise<void> {
+    await api.post('/auth/reset-password', { token, password });
   },
 
   async getCurrentUser(): Promise<User> {